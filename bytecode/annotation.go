@@ -0,0 +1,272 @@
+package bytecode
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// 注解的保留策略, RuntimeVisible*属性对应RetentionPolicy.RUNTIME, RuntimeInvisible*属性对应RetentionPolicy.CLASS
+type Retention uint8
+
+const (
+	RETENTION_VISIBLE Retention = iota
+	RETENTION_INVISIBLE
+)
+
+func (r Retention) String() string {
+	if r == RETENTION_INVISIBLE {
+		return "invisible"
+	}
+	return "visible"
+}
+
+func retentionOf(attributeName string) Retention {
+	if strings.HasPrefix(attributeName, "RuntimeInvisible") {
+		return RETENTION_INVISIBLE
+	}
+	return RETENTION_VISIBLE
+}
+
+// 解析后的注解, Type是内部形式的类名, 例如java/lang/annotation/Retention
+type AnnotationInfo struct {
+	Type      string
+	Elements  []AnnotationElement
+	Retention Retention
+}
+
+type AnnotationElement struct {
+	Name  string
+	Value AnnotationValue
+}
+
+// 解析后的元素值, Tag与element_value的tag相同, Value的类型由Tag决定:
+// B int8, C uint16, D float64, F float32, I int32, J int64, S int16, Z bool, s string,
+// e EnumConstant, c ClassLiteral, @ AnnotationInfo, [ []AnnotationValue
+type AnnotationValue struct {
+	Tag   byte
+	Value interface{}
+}
+
+// 枚举常量, Type是内部形式的类名
+type EnumConstant struct {
+	Type string
+	Name string
+}
+
+// 类字面量, 保存返回值描述符, 例如Ljava/lang/String;或者V
+type ClassLiteral struct {
+	Descriptor string
+}
+
+func (a *Annotation) Resolve(constantPool []ConstantPoolInfo, retention Retention) AnnotationInfo {
+	result := AnnotationInfo{Type: descriptorClass(constantUtf8(constantPool, a.TypeIndex)), Retention: retention}
+	for i := range a.ValuePairs {
+		pair := &a.ValuePairs[i]
+		result.Elements = append(result.Elements, AnnotationElement{
+			Name:  constantUtf8(constantPool, pair.ElementNameIndex),
+			Value: pair.ElementValue.Resolve(constantPool, retention),
+		})
+	}
+	return result
+}
+
+// 嵌套注解的保留策略与外层注解相同
+func (e *ElementValue) Resolve(constantPool []ConstantPoolInfo, retention Retention) AnnotationValue {
+	switch e.Tag {
+	case 'e':
+		return AnnotationValue{e.Tag, EnumConstant{
+			Type: descriptorClass(constantUtf8(constantPool, e.TypeNameIndex)),
+			Name: constantUtf8(constantPool, e.ConstNameIndex),
+		}}
+	case 'c':
+		return AnnotationValue{e.Tag, ClassLiteral{constantUtf8(constantPool, e.ClassInfoIndex)}}
+	case '@':
+		return AnnotationValue{e.Tag, e.AnnotationValue.Resolve(constantPool, retention)}
+	case '[':
+		values := make([]AnnotationValue, 0, len(e.Values))
+		for i := range e.Values {
+			values = append(values, e.Values[i].Resolve(constantPool, retention))
+		}
+		return AnnotationValue{e.Tag, values}
+	}
+	return ResolveConstantValue(constantPool, e.ConstValueIndex, e.Tag)
+}
+
+// 按element_value的tag解析常量, ConstantValue属性也可以使用, 字段描述符的第一个字符即为tag, 字符串为s
+func ResolveConstantValue(constantPool []ConstantPoolInfo, index uint16, tag byte) AnnotationValue {
+	if int(index) >= len(constantPool) {
+		return AnnotationValue{tag, nil}
+	}
+	switch c := constantPool[index].(type) {
+	case *ConstantInteger:
+		switch tag {
+		case 'B':
+			return AnnotationValue{tag, int8(c.Value)}
+		case 'C':
+			return AnnotationValue{tag, uint16(c.Value)}
+		case 'S':
+			return AnnotationValue{tag, int16(c.Value)}
+		case 'Z':
+			return AnnotationValue{tag, c.Value != 0}
+		}
+		return AnnotationValue{'I', c.Value}
+	case *ConstantLong:
+		return AnnotationValue{'J', c.Value}
+	case *ConstantFloat:
+		return AnnotationValue{'F', c.Value}
+	case *ConstantDouble:
+		return AnnotationValue{'D', c.Value}
+	case *ConstantString:
+		return AnnotationValue{'s', DecodeModifiedUtf8(constantBytes(constantPool, c.StringIndex))}
+	case *ConstantUtf8:
+		return AnnotationValue{'s', DecodeModifiedUtf8(c.Value)}
+	}
+	return AnnotationValue{tag, nil}
+}
+
+func constantBytes(constantPool []ConstantPoolInfo, index uint16) []byte {
+	if int(index) < len(constantPool) {
+		if utf8, ok := constantPool[index].(*ConstantUtf8); ok {
+			return utf8.Value
+		}
+	}
+	return nil
+}
+
+// 类名只保留简单名称, 例如@Retention(RetentionPolicy.RUNTIME)
+func (a AnnotationInfo) String() string {
+	return a.Java(simpleClassName)
+}
+
+func (v AnnotationValue) String() string {
+	return v.Java(simpleClassName)
+}
+
+func simpleClassName(name string) string {
+	return strings.ReplaceAll(name[strings.LastIndexByte(name, '/')+1:], "$", ".")
+}
+
+// 输出为Java源码, name将内部形式的类名转换为源码中的名称
+func (a AnnotationInfo) Java(name func(string) string) string {
+	result := "@" + name(a.Type)
+	if len(a.Elements) == 1 && a.Elements[0].Name == "value" {
+		return result + "(" + a.Elements[0].Value.Java(name) + ")"
+	}
+	if len(a.Elements) == 0 {
+		return result
+	}
+	elements := make([]string, 0, len(a.Elements))
+	for _, e := range a.Elements {
+		elements = append(elements, e.Name+" = "+e.Value.Java(name))
+	}
+	return result + "(" + strings.Join(elements, ", ") + ")"
+}
+
+func (v AnnotationValue) Java(name func(string) string) string {
+	switch value := v.Value.(type) {
+	case int8:
+		return fmt.Sprintf("(byte) %d", value)
+	case int16:
+		return fmt.Sprintf("(short) %d", value)
+	case uint16:
+		return javaQuote([]uint16{value}, '\'')
+	case int32:
+		return strconv.Itoa(int(value))
+	case int64:
+		return strconv.FormatInt(value, 10) + "L"
+	case float32:
+		return javaFloat(float64(value), 32)
+	case float64:
+		return javaFloat(value, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case string:
+		return javaQuote(utf16.Encode([]rune(value)), '"')
+	case EnumConstant:
+		return name(value.Type) + "." + value.Name
+	case ClassLiteral:
+		return JavaType(value.Descriptor, name) + ".class"
+	case AnnotationInfo:
+		return value.Java(name)
+	case []AnnotationValue:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			values = append(values, item.Java(name))
+		}
+		return "{" + strings.Join(values, ", ") + "}"
+	}
+	return fmt.Sprintf("<invalid %c>", v.Tag)
+}
+
+var javaPrimitiveTypes = map[string]string{
+	"B": "byte", "C": "char", "D": "double", "F": "float", "I": "int",
+	"J": "long", "S": "short", "Z": "boolean", "V": "void",
+}
+
+// 字段描述符或者V转换为Java源码中的类型, 例如[Ljava/lang/String;转换为java.lang.String[], 非法的描述符原样返回
+func JavaType(desc string, name func(string) string) string {
+	elem := strings.TrimLeft(desc, "[")
+	dims := strings.Repeat("[]", len(desc)-len(elem))
+	if primitive, ok := javaPrimitiveTypes[elem]; ok {
+		return primitive + dims
+	}
+	if strings.HasPrefix(elem, "L") && strings.HasSuffix(elem, ";") {
+		return name(elem[1:len(elem)-1]) + dims
+	}
+	return desc
+}
+
+// NaN和无穷大没有字面量, 使用常量表达式
+func javaFloat(value float64, bits int) string {
+	suffix := ""
+	if bits == 32 {
+		suffix = "f"
+	}
+	switch {
+	case math.IsNaN(value):
+		return "0.0" + suffix + " / 0.0" + suffix
+	case math.IsInf(value, 1):
+		return "1.0" + suffix + " / 0.0" + suffix
+	case math.IsInf(value, -1):
+		return "-1.0" + suffix + " / 0.0" + suffix
+	}
+	text := strconv.FormatFloat(value, 'g', -1, bits)
+	if !strings.ContainsAny(text, ".e") {
+		text += ".0"
+	}
+	return text + suffix
+}
+
+// 非ASCII字符使用\uXXXX, 控制字符使用八进制转义, 避免\u000a这样在词法分析之前就被替换的写法
+func javaQuote(units []uint16, delimiter byte) string {
+	var b strings.Builder
+	b.WriteByte(delimiter)
+	for _, unit := range units {
+		switch {
+		case unit == '\b':
+			b.WriteString(`\b`)
+		case unit == '\t':
+			b.WriteString(`\t`)
+		case unit == '\n':
+			b.WriteString(`\n`)
+		case unit == '\f':
+			b.WriteString(`\f`)
+		case unit == '\r':
+			b.WriteString(`\r`)
+		case unit == '"' || unit == '\'' || unit == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(unit))
+		case unit < 0x20 || unit == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, unit)
+		case unit < 0x80:
+			b.WriteByte(byte(unit))
+		default:
+			fmt.Fprintf(&b, `\u%04x`, unit)
+		}
+	}
+	b.WriteByte(delimiter)
+	return b.String()
+}
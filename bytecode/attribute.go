@@ -3,8 +3,13 @@ package bytecode
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"strconv"
+	"strings"
+
+	"class-file-parser/flags"
+	"class-file-parser/i18n"
 )
 
 func ParseAttribute(count int, data []byte, index int, constantPool []ConstantPoolInfo) (int, []AttributeInfo) {
@@ -67,15 +72,24 @@ func parse(data []byte, index int, constantPool []ConstantPoolInfo) (int, Attrib
 	case "Deprecated":
 		item = &Deprecated{}
 		item.parse(base, info, constantPool)
-	case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+	case "RuntimeVisibleAnnotations":
 		item = &RuntimeVisibleAnnotations{}
 		item.parse(base, info, constantPool)
-	case "RuntimeVisibleParameterAnnotations", "RuntimeInvisibleParameterAnnotations":
+	case "RuntimeInvisibleAnnotations":
+		item = &RuntimeInvisibleAnnotations{}
+		item.parse(base, info, constantPool)
+	case "RuntimeVisibleParameterAnnotations":
 		item = &RuntimeVisibleParameterAnnotations{}
 		item.parse(base, info, constantPool)
-	case "RuntimeVisibleTypeAnnotations", "RuntimeInvisibleTypeAnnotations":
+	case "RuntimeInvisibleParameterAnnotations":
+		item = &RuntimeInvisibleParameterAnnotations{}
+		item.parse(base, info, constantPool)
+	case "RuntimeVisibleTypeAnnotations":
 		item = &RuntimeVisibleTypeAnnotations{}
 		item.parse(base, info, constantPool)
+	case "RuntimeInvisibleTypeAnnotations":
+		item = &RuntimeInvisibleTypeAnnotations{}
+		item.parse(base, info, constantPool)
 	case "AnnotationDefault":
 		item = &AnnotationDefault{}
 		item.parse(base, info, constantPool)
@@ -107,7 +121,8 @@ func parse(data []byte, index int, constantPool []ConstantPoolInfo) (int, Attrib
 		item = &PermittedSubclasses{}
 		item.parse(base, info, constantPool)
 	default:
-		fmt.Printf("attribue name is %s\n", base.Name)
+		item = &UnknownAttribute{}
+		item.parse(base, info, constantPool)
 	}
 	return 6 + int(base.Length), item
 }
@@ -116,6 +131,7 @@ type AttributeInfo interface {
 	parse(base *AttributeBase, data []byte, constantPool []ConstantPoolInfo)
 	GetName() string
 	String(constantPool []ConstantPoolInfo) string
+	base() *AttributeBase
 }
 
 type AttributeBase struct {
@@ -128,6 +144,10 @@ func (a *AttributeBase) GetName() string {
 	return a.Name
 }
 
+func (a *AttributeBase) base() *AttributeBase {
+	return a
+}
+
 type ConstantValue struct {
 	AttributeBase
 	ConstantValueIndex uint16
@@ -190,9 +210,13 @@ func (c *Code) parse(base *AttributeBase, data []byte, constantPool []ConstantPo
 }
 
 func (c *Code) String(constantPool []ConstantPoolInfo) string {
-	result := fmt.Sprintf("max stack: %d, max locals: %d\n", c.MaxStack, c.MaxLocals)
+	result := i18n.T("code.max_stack", c.MaxStack, c.MaxLocals) + "\n"
 	for _, attr := range c.Attributes {
 		result += attr.GetName() + "\n"
+		if annotations, ok := visibleAttribute(attr).(*RuntimeVisibleTypeAnnotations); ok {
+			result += annotations.format(constantPool, c)
+			continue
+		}
 		result += attr.String(constantPool)
 	}
 	return result
@@ -212,6 +236,8 @@ func (v *VerificationTypeInfo) parse(data []byte, index int) int {
 	} else if v.Tag == 8 {
 		binary.Read(bytes.NewBuffer(data[index+1:index+3]), binary.BigEndian, &v.Offset)
 		index += 3
+	} else {
+		index += 1
 	}
 	return index
 }
@@ -228,7 +254,10 @@ type StackMapFrame struct {
 func (s *StackMapFrame) parse(data []byte, index int) int {
 	binary.Read(bytes.NewBuffer(data[index:index+1]), binary.BigEndian, &s.FrameType)
 	index += 1
-	if s.FrameType >= 64 && s.FrameType <= 127 {
+	if s.FrameType <= 63 {
+		s.OffsetDelta = uint16(s.FrameType)
+	} else if s.FrameType >= 64 && s.FrameType <= 127 {
+		s.OffsetDelta = uint16(s.FrameType - 64)
 		info := &VerificationTypeInfo{}
 		index = info.parse(data, index)
 		s.Stacks = append(s.Stacks, *info)
@@ -257,14 +286,14 @@ func (s *StackMapFrame) parse(data []byte, index int) int {
 		for i := 0; i < int(s.NumberOfLocals); i++ {
 			info := &VerificationTypeInfo{}
 			index = info.parse(data, index)
-			s.Stacks = append(s.Stacks, *info)
+			s.Locals = append(s.Locals, *info)
 		}
 		binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &s.NumberOfStackItems)
 		index += 2
 		for i := 0; i < int(s.NumberOfStackItems); i++ {
 			info := &VerificationTypeInfo{}
 			index = info.parse(data, index)
-			s.Locals = append(s.Locals, *info)
+			s.Stacks = append(s.Stacks, *info)
 		}
 	}
 	return index
@@ -350,10 +379,15 @@ func (i *InnerClasses) parse(base *AttributeBase, data []byte, constantPool []Co
 	}
 }
 
+func (i *InnerClassInfo) Flags() flags.InnerClassFlags {
+	return flags.InnerClassFlags(i.InnerClassAccessFlags)
+}
+
 func (i *InnerClasses) String(constantPool []ConstantPoolInfo) string {
 	result := ""
 	for _, innerClass := range i.Classes {
-		result += constantPool[innerClass.InnerClassIndex].String(constantPool) + "." + constantPool[innerClass.OuterClassIndex].String(constantPool)
+		accessFlags := innerClass.Flags()
+		result += "\n" + strings.TrimSpace(accessFlags.String()+" "+accessFlags.Kind()) + " " + ResolveConstant(constantPool, innerClass.InnerClassIndex)
 	}
 	return result
 }
@@ -421,7 +455,7 @@ type SourceDebugExtension struct {
 
 func (s *SourceDebugExtension) parse(base *AttributeBase, data []byte, constantPool []ConstantPoolInfo) {
 	s.AttributeBase = *base
-	binary.Read(bytes.NewBuffer(data), binary.BigEndian, &s.DebugExtension)
+	s.DebugExtension = data
 }
 
 func (s *SourceDebugExtension) String(constantPool []ConstantPoolInfo) string {
@@ -460,7 +494,7 @@ func (l *LineNumberTable) parse(base *AttributeBase, data []byte, constantPool [
 func (l *LineNumberTable) String(constantPool []ConstantPoolInfo) string {
 	result := ""
 	for _, line := range l.LineNumber {
-		result += fmt.Sprintf("start pc: %d, line number: %d\n", line.StartPc, line.LineNumber)
+		result += i18n.T("line_number", line.StartPc, line.LineNumber) + "\n"
 	}
 	return result
 }
@@ -503,7 +537,7 @@ func (l *LocalVariableTable) parse(base *AttributeBase, data []byte, constantPoo
 func (l *LocalVariableTable) String(constantPool []ConstantPoolInfo) string {
 	result := ""
 	for _, localVar := range l.LocalVariable {
-		result += fmt.Sprintf("start pc: %d, length: %d, name index: %d, descriptor index: %d, index: %d\n", localVar.StartPc, localVar.Length, localVar.NameIndex, localVar.DescriptorIndex, localVar.Index)
+		result += i18n.T("local_variable", localVar.StartPc, localVar.Length, localVar.NameIndex, localVar.DescriptorIndex, localVar.Index) + "\n"
 	}
 	return result
 }
@@ -546,7 +580,7 @@ func (l *LocalVariableTypeTable) parse(base *AttributeBase, data []byte, constan
 func (l *LocalVariableTypeTable) String(constantPool []ConstantPoolInfo) string {
 	result := ""
 	for _, localVar := range l.LocalVariableType {
-		result += fmt.Sprintf("start pc: %d, length: %d, name index: %d, signature index: %d, index: %d\n", localVar.StartPc, localVar.Length, localVar.NameIndex, localVar.SignatureIndex, localVar.Index)
+		result += i18n.T("local_variable_type", localVar.StartPc, localVar.Length, localVar.NameIndex, localVar.SignatureIndex, localVar.Index) + "\n"
 	}
 	return result
 }
@@ -605,7 +639,7 @@ func (e *ElementValue) parse(data []byte, index int) int {
 	binary.Read(bytes.NewBuffer(data[index:index+1]), binary.BigEndian, &e.Tag)
 	switch e.Tag {
 	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
-		binary.Read(bytes.NewBuffer(data[1:3]), binary.BigEndian, &e.ConstValueIndex)
+		binary.Read(bytes.NewBuffer(data[index+1:index+3]), binary.BigEndian, &e.ConstValueIndex)
 		index += 3
 	case 'e':
 		value := &EnumConstValue{}
@@ -613,7 +647,7 @@ func (e *ElementValue) parse(data []byte, index int) int {
 		e.EnumConstValue = *value
 		index += 5
 	case 'c':
-		binary.Read(bytes.NewBuffer(data[1:3]), binary.BigEndian, &e.ClassInfoIndex)
+		binary.Read(bytes.NewBuffer(data[index+1:index+3]), binary.BigEndian, &e.ClassInfoIndex)
 		index += 3
 	case '@':
 		ann := &Annotation{}
@@ -677,8 +711,43 @@ func (r *RuntimeVisibleAnnotations) parse(base *AttributeBase, data []byte, cons
 	}
 }
 
+// 保留策略由属性名决定, 不可见的注解属性嵌入了可见的注解属性
+func (r *RuntimeVisibleAnnotations) Retention() Retention {
+	return retentionOf(r.Name)
+}
+
+func (r *RuntimeVisibleAnnotations) Resolve(constantPool []ConstantPoolInfo) []AnnotationInfo {
+	result := make([]AnnotationInfo, 0, len(r.Annotations))
+	for i := range r.Annotations {
+		result = append(result, r.Annotations[i].Resolve(constantPool, r.Retention()))
+	}
+	return result
+}
+
 func (r *RuntimeVisibleAnnotations) String(constantPool []ConstantPoolInfo) string {
-	return fmt.Sprintf("%d runtime visible annotation\n", r.NumAnnotations)
+	result := i18n.T("annotation.count", r.NumAnnotations)
+	for _, annotation := range r.Resolve(constantPool) {
+		result += "\n" + annotation.String()
+	}
+	return result + "\n"
+}
+
+type RuntimeInvisibleAnnotations struct {
+	RuntimeVisibleAnnotations
+}
+
+// 可见和不可见的注解, 按属性的顺序
+func AnnotationsOf(attrs []AttributeInfo, constantPool []ConstantPoolInfo) []AnnotationInfo {
+	result := make([]AnnotationInfo, 0)
+	for _, attr := range attrs {
+		switch a := attr.(type) {
+		case *RuntimeVisibleAnnotations:
+			result = append(result, a.Resolve(constantPool)...)
+		case *RuntimeInvisibleAnnotations:
+			result = append(result, a.Resolve(constantPool)...)
+		}
+	}
+	return result
 }
 
 type ParameterAnnotation struct {
@@ -714,8 +783,36 @@ func (r *RuntimeVisibleParameterAnnotations) parse(base *AttributeBase, data []b
 	}
 }
 
+func (r *RuntimeVisibleParameterAnnotations) Retention() Retention {
+	return retentionOf(r.Name)
+}
+
+// 按参数的顺序, 参数个数可能少于方法描述符中的参数个数
+func (r *RuntimeVisibleParameterAnnotations) Resolve(constantPool []ConstantPoolInfo) [][]AnnotationInfo {
+	result := make([][]AnnotationInfo, 0, len(r.ParameterAnnotations))
+	for _, param := range r.ParameterAnnotations {
+		annotations := make([]AnnotationInfo, 0, len(param.Annotations))
+		for i := range param.Annotations {
+			annotations = append(annotations, param.Annotations[i].Resolve(constantPool, r.Retention()))
+		}
+		result = append(result, annotations)
+	}
+	return result
+}
+
 func (r *RuntimeVisibleParameterAnnotations) String(constantPool []ConstantPoolInfo) string {
-	return ""
+	result := ""
+	for i, annotations := range r.Resolve(constantPool) {
+		result += "\n" + i18n.T("annotation.parameter", i)
+		for _, annotation := range annotations {
+			result += " " + annotation.String()
+		}
+	}
+	return result
+}
+
+type RuntimeInvisibleParameterAnnotations struct {
+	RuntimeVisibleParameterAnnotations
 }
 
 type Table struct {
@@ -747,6 +844,7 @@ func (l *LocalVarTarget) parse(data []byte, index int) int {
 	return index
 }
 
+// target_info的各种形式合并在一个结构中, 有效的字段由TargetType决定, 参见TargetKindOf
 type TargetInfo struct {
 	TypeParameterIndex   uint8
 	SupertypeIndex       uint16
@@ -774,6 +872,7 @@ func (p *Path) parse(data []byte, index int) int {
 
 type TypePath struct {
 	PathLength uint8
+	Path       []Path
 }
 
 func (t *TypePath) parse(data []byte, index int) int {
@@ -782,6 +881,7 @@ func (t *TypePath) parse(data []byte, index int) int {
 	for i := 0; i < int(t.PathLength); i++ {
 		path := &Path{}
 		index = path.parse(data, index)
+		t.Path = append(t.Path, *path)
 	}
 	return index
 }
@@ -798,48 +898,51 @@ type TypeAnnotation struct {
 func (t *TypeAnnotation) parse(data []byte, index int) int {
 	binary.Read(bytes.NewBuffer(data[index:index+1]), binary.BigEndian, &t.TargetType)
 	index += 1
-	binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.TypeIndex)
-	index += 2
-	switch t.TargetType {
-	case 0x00, 0x01:
+	switch t.Kind() {
+	case TARGET_TYPE_PARAMETER:
 		t.TypeParameterIndex = data[index]
 		index++
-	case 0x10:
+	case TARGET_SUPERTYPE:
 		binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.SupertypeIndex)
 		index += 2
-	case 0x11, 0x12:
-		t.BoundIndex = data[index]
+	case TARGET_TYPE_PARAMETER_BOUND:
+		t.TypeParameterIndex = data[index]
 		index++
-		t.TypeArgumentIndex = data[index]
+		t.BoundIndex = data[index]
 		index++
-	//case 0x13,0x14,0x15:
-	case 0x16:
+	case TARGET_EMPTY:
+	case TARGET_FORMAL_PARAMETER:
 		t.FormalParameterIndex = data[index]
 		index++
-	case 0x17:
+	case TARGET_THROWS:
 		binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.ThrowsTypeIndex)
 		index += 2
-	case 0x40, 0x41:
+	case TARGET_LOCALVAR:
 		target := &LocalVarTarget{}
 		index = target.parse(data, index)
 		t.LocalVarTarget = *target
-	case 0x42:
+	case TARGET_CATCH:
 		binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.ExceptionTableIndex)
 		index += 2
-	case 0x44, 0x45, 0x46:
+	case TARGET_OFFSET:
 		binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.Offset)
 		index += 2
-	case 0x47, 0x48, 0x49, 0x4A, 0x4B:
+	case TARGET_TYPE_ARGUMENT:
 		binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.Offset)
 		index += 2
 		t.TypeArgumentIndex = data[index]
 		index++
+	default:
+		// 无法知道target_info的长度, 后面的注解都无法解析
+		panic(fmt.Sprintf("unknown type annotation target type 0x%02x", t.TargetType))
 	}
 
 	targetPath := &TypePath{}
 	index = targetPath.parse(data, index)
 	t.TargetPath = *targetPath
 
+	binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.TypeIndex)
+	index += 2
 	binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &t.NumElementValuePairs)
 	index += 2
 	for i := 0; i < int(t.NumElementValuePairs); i++ {
@@ -858,8 +961,8 @@ type RuntimeVisibleTypeAnnotations struct {
 
 func (r *RuntimeVisibleTypeAnnotations) parse(base *AttributeBase, data []byte, constantPool []ConstantPoolInfo) {
 	r.AttributeBase = *base
-	binary.Read(bytes.NewBuffer(data[0:1]), binary.BigEndian, &r.NumAnnotations)
-	index := 1
+	binary.Read(bytes.NewBuffer(data[0:2]), binary.BigEndian, &r.NumAnnotations)
+	index := 2
 	for i := 0; i < int(r.NumAnnotations); i++ {
 		ann := &TypeAnnotation{}
 		index = ann.parse(data, index)
@@ -867,8 +970,45 @@ func (r *RuntimeVisibleTypeAnnotations) parse(base *AttributeBase, data []byte,
 	}
 }
 
+// 类型注解去掉target_info和type_path之后与普通注解相同
+func (t *TypeAnnotation) Resolve(constantPool []ConstantPoolInfo, retention Retention) AnnotationInfo {
+	annotation := Annotation{TypeIndex: t.TypeIndex, NumElementValuePairs: t.NumElementValuePairs, ValuePairs: t.ValuePairs}
+	return annotation.Resolve(constantPool, retention)
+}
+
+func (r *RuntimeVisibleTypeAnnotations) Retention() Retention {
+	return retentionOf(r.Name)
+}
+
 func (r *RuntimeVisibleTypeAnnotations) String(constantPool []ConstantPoolInfo) string {
-	return ""
+	return r.format(constantPool, nil)
+}
+
+// Code中的类型注解由Code输出, 可以从LocalVariableTable中找到局部变量名
+func (r *RuntimeVisibleTypeAnnotations) format(constantPool []ConstantPoolInfo, code *Code) string {
+	result := i18n.T("annotation.count", r.NumAnnotations)
+	for i := range r.Annotations {
+		annotation := &r.Annotations[i]
+		result += "\n" + annotation.Resolve(constantPool, r.Retention()).String() + ": " + annotation.Location(constantPool, code)
+	}
+	return result + "\n"
+}
+
+type RuntimeInvisibleTypeAnnotations struct {
+	RuntimeVisibleTypeAnnotations
+}
+
+// 不可见的注解属性与可见的结构相同, 遍历属性的字段时统一按可见的处理
+func visibleAttribute(attr AttributeInfo) AttributeInfo {
+	switch a := attr.(type) {
+	case *RuntimeInvisibleAnnotations:
+		return &a.RuntimeVisibleAnnotations
+	case *RuntimeInvisibleParameterAnnotations:
+		return &a.RuntimeVisibleParameterAnnotations
+	case *RuntimeInvisibleTypeAnnotations:
+		return &a.RuntimeVisibleTypeAnnotations
+	}
+	return attr
 }
 
 type AnnotationDefault struct {
@@ -882,8 +1022,13 @@ func (a *AnnotationDefault) parse(base *AttributeBase, data []byte, constantPool
 	a.DefaultValue.parse(data, 0)
 }
 
+// 默认值属于注解接口本身, 按可见处理
+func (a *AnnotationDefault) Resolve(constantPool []ConstantPoolInfo) AnnotationValue {
+	return a.DefaultValue.Resolve(constantPool, RETENTION_VISIBLE)
+}
+
 func (a *AnnotationDefault) String(constantPool []ConstantPoolInfo) string {
-	return ""
+	return a.Resolve(constantPool).String()
 }
 
 type BootStrapMethod struct {
@@ -937,6 +1082,10 @@ type MethodParameter struct {
 	AccessFlags uint16
 }
 
+func (m *MethodParameter) Flags() flags.ParameterFlags {
+	return flags.ParameterFlags(m.AccessFlags)
+}
+
 func (m *MethodParameter) parse(data []byte) {
 	binary.Read(bytes.NewBuffer(data[0:2]), binary.BigEndian, &m.NameIndex)
 	binary.Read(bytes.NewBuffer(data[2:4]), binary.BigEndian, &m.AccessFlags)
@@ -945,7 +1094,7 @@ func (m *MethodParameter) parse(data []byte) {
 type MethodParameters struct {
 	AttributeBase
 	ParametersCount uint8
-	parameter       []MethodParameter
+	Parameters      []MethodParameter
 }
 
 func (m *MethodParameters) parse(base *AttributeBase, data []byte, constantPool []ConstantPoolInfo) {
@@ -955,15 +1104,18 @@ func (m *MethodParameters) parse(base *AttributeBase, data []byte, constantPool
 	for n := 0; n < int(m.ParametersCount); n++ {
 		param := &MethodParameter{}
 		param.parse(data[index:])
-		m.parameter = append(m.parameter, *param)
+		m.Parameters = append(m.Parameters, *param)
 		index += 4
 	}
 }
 
 func (m *MethodParameters) String(constantPool []ConstantPoolInfo) string {
 	result := ""
-	for _, param := range m.parameter {
-		result += constantPool[param.NameIndex].String(constantPool) + " "
+	for _, param := range m.Parameters {
+		if modifiers := param.Flags().String(); modifiers != "" {
+			result += modifiers + " "
+		}
+		result += constantUtf8(constantPool, param.NameIndex) + " "
 	}
 	return result
 }
@@ -974,6 +1126,10 @@ type Require struct {
 	RequiresVersionIndex uint16
 }
 
+func (r *Require) Flags() flags.RequiresFlags {
+	return flags.RequiresFlags(r.RequiresFlags)
+}
+
 func (r *Require) parse(data []byte, index int) {
 	binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &r.RequiresIndex)
 	binary.Read(bytes.NewBuffer(data[index+2:index+4]), binary.BigEndian, &r.RequiresFlags)
@@ -987,6 +1143,10 @@ type Export struct {
 	ExportsToIndex []uint16
 }
 
+func (e *Export) Flags() flags.ExportsFlags {
+	return flags.ExportsFlags(e.ExportsFlags)
+}
+
 func (e *Export) parse(data []byte, index int) int {
 	binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &e.ExportsIndex)
 	binary.Read(bytes.NewBuffer(data[index+2:index+4]), binary.BigEndian, &e.ExportsFlags)
@@ -1008,6 +1168,10 @@ type Open struct {
 	OpenToIndex []uint16
 }
 
+func (o *Open) Flags() flags.ExportsFlags {
+	return flags.ExportsFlags(o.OpenFlags)
+}
+
 func (o *Open) parse(data []byte, index int) int {
 	binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &o.OpenIndex)
 	binary.Read(bytes.NewBuffer(data[index+2:index+4]), binary.BigEndian, &o.OpenFlags)
@@ -1096,7 +1260,7 @@ func (m *Module) parse(base *AttributeBase, data []byte, constantPool []Constant
 		var idx uint16
 		binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &idx)
 		m.UsesIndex = append(m.UsesIndex, idx)
-		idx += 2
+		index += 2
 	}
 
 	binary.Read(bytes.NewBuffer(data[index:index+2]), binary.BigEndian, &m.ProvidesCount)
@@ -1108,8 +1272,22 @@ func (m *Module) parse(base *AttributeBase, data []byte, constantPool []Constant
 	}
 }
 
+func (m *Module) Flags() flags.ModuleFlags {
+	return flags.ModuleFlags(m.ModuleFlags)
+}
+
 func (m *Module) String(constantPool []ConstantPoolInfo) string {
-	return constantPool[m.ModuleNameIndex].String(constantPool)
+	result := strings.TrimSpace(m.Flags().String() + " module " + ResolveConstant(constantPool, m.ModuleNameIndex))
+	for _, r := range m.Requires {
+		result += "\n" + strings.Join(strings.Fields("requires "+r.Flags().String()+" "+ResolveConstant(constantPool, r.RequiresIndex)), " ")
+	}
+	for _, e := range m.Exports {
+		result += "\n" + strings.Join(strings.Fields("exports "+e.Flags().String()+" "+ResolveConstant(constantPool, e.ExportsIndex)), " ")
+	}
+	for _, o := range m.Opens {
+		result += "\n" + strings.Join(strings.Fields("opens "+o.Flags().String()+" "+ResolveConstant(constantPool, o.OpenIndex)), " ")
+	}
+	return result
 }
 
 type ModulePackages struct {
@@ -1205,7 +1383,7 @@ func (r *RecordComponent) parse(data []byte, index int, constantPool []ConstantP
 	binary.Read(bytes.NewBuffer(data[index+2:index+4]), binary.BigEndian, &r.DescriptorIndex)
 	binary.Read(bytes.NewBuffer(data[index+4:index+6]), binary.BigEndian, &r.AttributesCount)
 	index += 6
-	_, attrs := ParseAttribute(int(r.AttributesCount), data, index, constantPool)
+	index, attrs := ParseAttribute(int(r.AttributesCount), data, index, constantPool)
 	r.Attributes = attrs
 	return index
 }
@@ -1230,7 +1408,7 @@ func (r *Record) parse(base *AttributeBase, data []byte, constantPool []Constant
 func (b *Record) String(constantPool []ConstantPoolInfo) string {
 	result := ""
 	for _, component := range b.RecordComponentInfo {
-		result += " name: " + constantPool[component.NameIndex].String(constantPool)
+		result += " " + i18n.T("record.component_name") + constantPool[component.NameIndex].String(constantPool)
 	}
 	return result
 }
@@ -1260,3 +1438,56 @@ func (p *PermittedSubclasses) String(constantPool []ConstantPoolInfo) string {
 	}
 	return result
 }
+
+// 不认识的属性, 例如ScalaSig和编译器的标记, 保留原始内容以便原样写回
+type UnknownAttribute struct {
+	AttributeBase
+	Info []uint8
+}
+
+func (u *UnknownAttribute) parse(base *AttributeBase, data []byte, constantPool []ConstantPoolInfo) {
+	u.AttributeBase = *base
+	u.Info = data
+}
+
+func (u *UnknownAttribute) String(constantPool []ConstantPoolInfo) string {
+	return hex.EncodeToString(u.Info)
+}
+
+// 类文件中是否有不认识的属性, 包括成员、Code和Record组件中的属性
+func (f *ClassFile) HasUnknownAttributes() bool {
+	if hasUnknownAttributes(f.Attributes) {
+		return true
+	}
+	for i := range f.Fields {
+		if hasUnknownAttributes(f.Fields[i].Attributes) {
+			return true
+		}
+	}
+	for i := range f.Methods {
+		if hasUnknownAttributes(f.Methods[i].Attributes) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasUnknownAttributes(attrs []AttributeInfo) bool {
+	for _, attr := range attrs {
+		switch a := attr.(type) {
+		case *UnknownAttribute:
+			return true
+		case *Code:
+			if hasUnknownAttributes(a.Attributes) {
+				return true
+			}
+		case *Record:
+			for i := range a.RecordComponentInfo {
+				if hasUnknownAttributes(a.RecordComponentInfo[i].Attributes) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
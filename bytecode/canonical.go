@@ -0,0 +1,385 @@
+package bytecode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 与常量池顺序无关的文本形式, 每行一项, 用于结构化比较:
+// 常量按类型和解析后的值排序, 成员按名称和描述符排序, 属性按名称排序并且引用替换为解析后的值,
+// 字节码中的pc替换为标签, 标签按pc的顺序编号为L0、L1...
+func (f *ClassFile) Canonical() []string {
+	c := &canonical{f: f, e: &jsonEncoder{pool: f.ConstantPool}}
+	c.line(0, "class %s", f.ClassName())
+	c.line(0, "version %d.%d", f.MajorVersion, f.MinorVersion)
+	c.line(0, "flags %s", f.Flags().Javap())
+	c.line(0, "super %s", f.SuperClassName())
+	for _, name := range f.InterfaceNames() {
+		c.line(0, "interface %s", name)
+	}
+	constants := make([]string, 0, len(f.ConstantPool))
+	for i, item := range f.ConstantPool {
+		if item != nil && item.TagValue() != 0 {
+			constants = append(constants, "constant "+c.constant(uint16(i)))
+		}
+	}
+	sort.Strings(constants)
+	c.lines = append(c.lines, constants...)
+	c.attributes(0, f.Attributes)
+
+	fields := make([]string, 0, len(f.Fields))
+	byField := make(map[string]*FieldInfo)
+	for i := range f.Fields {
+		key := f.Fields[i].Name(f.ConstantPool) + ":" + f.Fields[i].Descriptor(f.ConstantPool)
+		fields, byField[key] = append(fields, key), &f.Fields[i]
+	}
+	sort.Strings(fields)
+	for _, key := range fields {
+		c.line(0, "field %s", key)
+		c.line(1, "flags %s", byField[key].Flags().Javap())
+		c.attributes(1, byField[key].Attributes)
+	}
+	methods := make([]string, 0, len(f.Methods))
+	byMethod := make(map[string]*MethodInfo)
+	for i := range f.Methods {
+		key := f.Methods[i].Name(f.ConstantPool) + f.Methods[i].Descriptor(f.ConstantPool)
+		methods, byMethod[key] = append(methods, key), &f.Methods[i]
+	}
+	sort.Strings(methods)
+	for _, key := range methods {
+		c.method(key, byMethod[key])
+	}
+	return c.lines
+}
+
+func (c *canonical) method(key string, m *MethodInfo) {
+	c.line(0, "method %s", key)
+	c.line(1, "flags %s", m.Flags().Javap())
+	c.attributes(1, m.Attributes)
+}
+
+type canonical struct {
+	f     *ClassFile
+	e     *jsonEncoder
+	lines []string
+}
+
+func (c *canonical) line(indent int, format string, args ...interface{}) {
+	c.lines = append(c.lines, strings.Repeat("  ", indent)+fmt.Sprintf(format, args...))
+}
+
+// 常量的类型和值, invokedynamic的引导方法序号替换为引导方法本身
+func (c *canonical) constant(index uint16) string {
+	pool := c.f.ConstantPool
+	if int(index) >= len(pool) || pool[index] == nil {
+		return strconv.Quote(ResolveConstant(pool, index))
+	}
+	value := c.e.value(index)
+	switch item := pool[index].(type) {
+	case *ConstantInvokeDynamic:
+		value = c.bootstrap(item.BootstrapMethodAttrIndex) + ":" + ResolveConstant(pool, item.NameAndTypeIndex)
+	case *ConstantDynamic:
+		value = c.bootstrap(item.BootstrapMethodAttrIndex) + ":" + ResolveConstant(pool, item.NameAndTypeIndex)
+	}
+	return pool[index].TagName() + " " + strconv.Quote(value)
+}
+
+func (c *canonical) bootstrap(index uint16) string {
+	for _, attr := range c.f.Attributes {
+		if methods, ok := attr.(*BootstrapMethods); ok && int(index) < len(methods.Methods) {
+			method := methods.Methods[index]
+			args := make([]string, 0, len(method.Arguments))
+			for _, arg := range method.Arguments {
+				args = append(args, c.constant(arg))
+			}
+			return ResolveConstant(c.f.ConstantPool, method.BootstrapMethodRef) + "[" + strings.Join(args, ", ") + "]"
+		}
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// 同名的属性保持原来的相对顺序
+func (c *canonical) attributes(indent int, attrs []AttributeInfo) {
+	sorted := make([]AttributeInfo, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr != nil {
+			sorted = append(sorted, attr)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+	for _, attr := range sorted {
+		if code, ok := attr.(*Code); ok {
+			if instructions, err := code.Instructions(); err == nil {
+				c.code(indent, code, instructions)
+				continue
+			}
+		}
+		c.tree(indent, attr.GetName(), plain(c.e.attribute(attr)))
+	}
+}
+
+// 属性的内容按JSON输出, 数组中的每一项单独一行, 避免一处变化导致整个属性不同
+func (c *canonical) tree(indent int, name string, tree interface{}) {
+	c.line(indent, "attribute %s", name)
+	fields, _ := tree.(map[string]interface{})
+	delete(fields, "name")
+	delete(fields, "nameIndex")
+	delete(fields, "length")
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if items, ok := fields[key].([]interface{}); ok {
+			c.line(indent+1, "%s", key)
+			for _, item := range items {
+				c.line(indent+2, "%s", marshal(item))
+			}
+			continue
+		}
+		c.line(indent+1, "%s %s", key, marshal(fields[key]))
+	}
+}
+
+func (c *canonical) code(indent int, code *Code, instructions []Instruction) {
+	labels := &labels{names: make(map[int]string)}
+	for i := range instructions {
+		for _, target := range branchTargets(&instructions[i]) {
+			labels.add(target)
+		}
+	}
+	for _, t := range code.Table {
+		labels.add(int(t.StartPc))
+		labels.add(int(t.EndPc))
+		labels.add(int(t.HandlerPc))
+	}
+	trees := make([]interface{}, 0, len(code.Attributes))
+	for _, attr := range code.Attributes {
+		tree := plain(c.e.attribute(attr))
+		if attr.GetName() == "StackMapTable" {
+			tree = labels.frames(tree)
+		}
+		trees = append(trees, labels.replace(tree))
+	}
+	labels.number()
+
+	c.line(indent, "code maxStack=%d maxLocals=%d", code.MaxStack, code.MaxLocals)
+	for i := range instructions {
+		ins := &instructions[i]
+		if name, ok := labels.names[ins.Pc]; ok {
+			c.line(indent+1, "%s:", name)
+		}
+		c.line(indent+2, "%s", c.instruction(ins, labels))
+	}
+	if name, ok := labels.names[int(code.CodeLength)]; ok {
+		c.line(indent+1, "%s:", name)
+	}
+	for _, t := range code.Table {
+		catchType := "any"
+		if t.CatchType != 0 {
+			catchType = ResolveConstant(c.f.ConstantPool, t.CatchType)
+		}
+		c.line(indent+1, "exception %s %s %s %s", labels.of(int(t.StartPc)), labels.of(int(t.EndPc)), labels.of(int(t.HandlerPc)), catchType)
+	}
+	// 属性在编号之后才能输出标签, 排序与其他属性一致
+	order := make([]int, len(code.Attributes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return code.Attributes[order[i]].GetName() < code.Attributes[order[j]].GetName()
+	})
+	for _, i := range order {
+		c.tree(indent+1, code.Attributes[i].GetName(), trees[i])
+	}
+}
+
+// ldc和ldc_w只是常量池索引的宽度不同, 统一输出为ldc
+func (c *canonical) instruction(ins *Instruction, labels *labels) string {
+	name := ins.Name()
+	if ins.Opcode == OPCODE_LDC_W {
+		name = "ldc"
+	}
+	switch ins.format() {
+	case operandLocal:
+		return fmt.Sprintf("%s %d", name, ins.Index)
+	case operandIinc:
+		return fmt.Sprintf("%s %d, %d", name, ins.Index, ins.Value)
+	case operandByte, operandShort, operandNewArray:
+		return fmt.Sprintf("%s %d", name, ins.Value)
+	case operandConstantU1, operandConstant, operandInvokeDynamic:
+		return name + " " + c.constant(ins.Index)
+	case operandInvokeInterface, operandMultiANewArray:
+		return fmt.Sprintf("%s %s, %d", name, c.constant(ins.Index), ins.Value)
+	case operandBranch, operandBranchWide:
+		return name + " " + labels.of(ins.Pc+int(ins.Branch))
+	case operandTableSwitch, operandLookupSwitch:
+		cases := make([]string, 0, len(ins.Offsets)+1)
+		for i, offset := range ins.Offsets {
+			key := ins.Low + int32(i)
+			if ins.format() == operandLookupSwitch {
+				key = ins.Keys[i]
+			}
+			cases = append(cases, fmt.Sprintf("%d: %s", key, labels.of(ins.Pc+int(offset))))
+		}
+		cases = append(cases, "default: "+labels.of(ins.Pc+int(ins.Default)))
+		return name + " {" + strings.Join(cases, ", ") + "}"
+	}
+	return name
+}
+
+func branchTargets(ins *Instruction) []int {
+	switch ins.format() {
+	case operandBranch, operandBranchWide:
+		return []int{ins.Pc + int(ins.Branch)}
+	case operandTableSwitch, operandLookupSwitch:
+		targets := []int{ins.Pc + int(ins.Default)}
+		for _, offset := range ins.Offsets {
+			targets = append(targets, ins.Pc+int(offset))
+		}
+		return targets
+	}
+	return nil
+}
+
+// pc到标签的映射, 先收集所有用到的pc, 编号之后再输出
+type labels struct {
+	names map[int]string
+}
+
+func (l *labels) add(pc int) {
+	l.names[pc] = ""
+}
+
+func (l *labels) number() {
+	pcs := make([]int, 0, len(l.names))
+	for pc := range l.names {
+		pcs = append(pcs, pc)
+	}
+	sort.Ints(pcs)
+	for i, pc := range pcs {
+		l.names[pc] = fmt.Sprintf("L%d", i)
+	}
+}
+
+func (l *labels) of(pc int) string {
+	return l.names[pc]
+}
+
+// 输出JSON时替换为标签
+type label struct {
+	pc     int
+	labels *labels
+}
+
+func (l label) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.labels.of(l.pc))
+}
+
+func (l *labels) label(pc int) label {
+	l.add(pc)
+	return label{pc, l}
+}
+
+// 栈映射帧的offset_delta换成绝对位置的标签, 因为偏移量不同而选择的extended形式视为相同的帧
+func (l *labels) frames(tree interface{}) interface{} {
+	fields, _ := tree.(map[string]interface{})
+	entries, _ := fields["entries"].([]interface{})
+	pc := -1
+	for _, entry := range entries {
+		frame, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delta, _ := frame["offsetDelta"].(json.Number).Int64()
+		pc += int(delta) + 1
+		frame["offset"] = l.label(pc)
+		if kind, ok := frame["kind"].(string); ok {
+			frame["kind"] = strings.TrimSuffix(kind, "_extended")
+		}
+		delete(frame, "offsetDelta")
+		delete(frame, "frameType")
+	}
+	return tree
+}
+
+// Code中属性的pc: startPc和length换成起止标签, 类型注解和uninitialized类型的offset换成标签
+func (l *labels) replace(tree interface{}) interface{} {
+	switch value := tree.(type) {
+	case map[string]interface{}:
+		if start, ok := value["startPc"].(json.Number); ok {
+			startPc, _ := start.Int64()
+			if length, ok := value["length"].(json.Number); ok {
+				n, _ := length.Int64()
+				value["start"], value["end"] = l.label(int(startPc)), l.label(int(startPc+n))
+				delete(value, "length")
+			} else {
+				value["start"] = l.label(int(startPc))
+			}
+			delete(value, "startPc")
+		}
+		if offset, ok := value["offset"].(json.Number); ok {
+			pc, _ := offset.Int64()
+			value["offset"] = l.label(int(pc))
+		}
+		for key, item := range value {
+			value[key] = l.replace(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = l.replace(item)
+		}
+	}
+	return tree
+}
+
+// 转换为通用的JSON值, 引用只保留解析后的值
+func plain(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return err.Error()
+	}
+	return dereference(tree)
+}
+
+func dereference(tree interface{}) interface{} {
+	switch value := tree.(type) {
+	case map[string]interface{}:
+		if _, ok := value["index"].(json.Number); ok && len(value) == 2 {
+			if s, ok := value["value"].(string); ok {
+				return s
+			}
+		}
+		for key, item := range value {
+			value[key] = dereference(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = dereference(item)
+		}
+	}
+	return tree
+}
+
+func marshal(v interface{}) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err.Error()
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
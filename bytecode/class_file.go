@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"strings"
+
+	"class-file-parser/flags"
+	"class-file-parser/i18n"
 )
 
 const MagicNumber = "CAFEBABE"
 
+// 目前已知的最高主版本号(JDK 27)
+const MAX_MAJOR_VERSION = 71
+
 const ACC_PUBLIC = 0x0001
 const ACC_FINAL = 0x0010
 const ACC_SUPER = 0x0020
@@ -37,6 +44,24 @@ type ClassFile struct {
 	Attributes        []AttributeInfo
 }
 
+// 解析类文件, 数据不完整或者格式错误时返回error而不是panic
+func ParseClassFile(data []byte) (f *ClassFile, err error) {
+	if len(data) < 10 {
+		return nil, fmt.Errorf("class file is too short: %d bytes", len(data))
+	}
+	if magic := fmt.Sprintf("%X", data[:4]); magic != MagicNumber {
+		return nil, fmt.Errorf("invalid class file, expect magic number %s, but actual is %s", MagicNumber, magic)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			f, err = nil, fmt.Errorf("malformed class file: %v", r)
+		}
+	}()
+	f = &ClassFile{}
+	f.Parser(data)
+	return f, nil
+}
+
 func (f *ClassFile) Parser(data []byte) {
 	index := 0
 	binary.Read(bytes.NewBuffer(data[index:index+4]), binary.BigEndian, &f.Magic)
@@ -102,7 +127,7 @@ func (f *ClassFile) Parser(data []byte) {
 		case 20:
 			item = &ConstantPackage{}
 		default:
-			fmt.Printf("unkown constant type, tag: %d", tag)
+			panic(fmt.Sprintf("unknown constant type, tag: %d", tag))
 		}
 		index += item.Parse(data, index)
 		f.ConstantPool[i] = item
@@ -158,41 +183,15 @@ func (f *ClassFile) Parser(data []byte) {
 
 func (f *ClassFile) String() string {
 	result := f.Version() + "\n"
-	result += fmt.Sprintf("constant number: %d\n", f.ConstantPoolCount)
+	result += i18n.T("class.constant_count", f.ConstantPoolCount) + "\n"
 	for i, item := range f.ConstantPool {
 		if item != nil {
 			result += fmt.Sprintf("const #%d = %s	%s\n", i, item.TagName(), item.String(f.ConstantPool))
 		}
 	}
 
-	if f.AccessFlags&ACC_PUBLIC != 0 {
-		result += "public "
-	}
-	if f.AccessFlags&ACC_FINAL != 0 {
-		result += "final "
-	}
-	if f.AccessFlags&ACC_SUPER != 0 {
-		result += "super "
-	}
-	if f.AccessFlags&ACC_INTERFACE != 0 {
-		result += "interface "
-	}
-	if f.AccessFlags&ACC_ABSTRACT != 0 {
-		result += "abstract "
-	}
-	if f.AccessFlags&ACC_SYNTHETIC != 0 {
-		result += "synthetic "
-	}
-	if f.AccessFlags&ACC_ANNOTATION != 0 {
-		result += "@ "
-	}
-	if f.AccessFlags&ACC_ENUM != 0 {
-		result += "enum "
-	}
-	if f.AccessFlags&ACC_MODULE != 0 {
-		result += "module "
-	}
-
+	accessFlags := f.Flags()
+	result += strings.TrimSpace(accessFlags.String() + " " + accessFlags.Kind())
 	result += "\n"
 
 	thisClassName := f.getClassName(f.ThisClass)
@@ -210,19 +209,19 @@ func (f *ClassFile) String() string {
 		}
 	}
 	result += "\n"
-	result += fmt.Sprintf("字段个数: %d\n", f.FieldsCount)
+	result += i18n.T("class.field_count", f.FieldsCount) + "\n"
 	for _, field := range f.Fields {
 		result += field.String(f.ConstantPool) + "\n"
 	}
 
 	result += "\n"
-	result += fmt.Sprintf("方法个数: %d\n", f.MethodsCount)
+	result += i18n.T("class.method_count", f.MethodsCount) + "\n"
 	for _, method := range f.Methods {
 		result += method.String(f.ConstantPool) + "\n"
 	}
 
 	result += "\n"
-	result += fmt.Sprintf("属性个数: %d\n", f.AttributesCount)
+	result += i18n.T("attribute_count", f.AttributesCount) + "\n"
 	for _, attr := range f.Attributes {
 		if attr != nil {
 			result += attr.GetName() + ": " + attr.String(f.ConstantPool) + "\n"
@@ -231,35 +230,54 @@ func (f *ClassFile) String() string {
 	return result
 }
 
+func (f *ClassFile) Flags() flags.ClassFlags {
+	return flags.ClassFlags(f.AccessFlags)
+}
+
+func (f *ClassFile) ClassName() string {
+	return f.getClassName(f.ThisClass)
+}
+
+// java/lang/Object和module-info没有父类, 返回空字符串
+func (f *ClassFile) SuperClassName() string {
+	if f.SuperClass == 0 {
+		return ""
+	}
+	return f.getClassName(f.SuperClass)
+}
+
+func (f *ClassFile) InterfaceNames() []string {
+	names := make([]string, 0, len(f.Interfaces))
+	for _, index := range f.Interfaces {
+		names = append(names, f.getClassName(index))
+	}
+	return names
+}
+
 func (f *ClassFile) getClassName(index uint16) (className string) {
+	if int(index) >= len(f.ConstantPool) {
+		return ""
+	}
 	item := f.ConstantPool[index]
 	constClazz, ok := item.(*ConstantClass)
 	if ok {
-		item = f.ConstantPool[constClazz.NameIndex]
-		constUtf8, ok := item.(*ConstantUtf8)
-		if ok {
-			className = string(constUtf8.Value)
-		}
+		className = constantUtf8(f.ConstantPool, constClazz.NameIndex)
 	}
 	return className
 }
 
 func (f *ClassFile) Version() string {
 	if f.MajorVersion == 45 {
-		return fmt.Sprintf("JDK Version 1.0.2 or 1.1, %d.%d", f.MajorVersion, f.MinorVersion)
-	} else if f.MajorVersion > 52 {
-		if f.MajorVersion >= 56 && f.MinorVersion != 0 && f.MinorVersion != 65535 {
-			return "Unknow JDK Version"
-		}
-		jdkVersion := f.MajorVersion - 44
-		if jdkVersion == 8 || jdkVersion == 11 || jdkVersion == 17 {
-			return fmt.Sprintf("JDK Version %d (LTS), %d.%d", jdkVersion, f.MajorVersion, f.MinorVersion)
-		} else {
-			return fmt.Sprintf("JDK Version %d, %d.%d", jdkVersion, f.MajorVersion, f.MinorVersion)
-		}
-	} else if f.MajorVersion > 45 && f.MinorVersion == 0 {
-		jdkVersion := f.MajorVersion - 44
-		return fmt.Sprintf("JDK Version 1.%d (LTS), %d.%d", jdkVersion, f.MajorVersion, f.MinorVersion)
+		return i18n.T("version.jdk", i18n.T("version.jdk_1_0"), f.MajorVersion, f.MinorVersion)
+	}
+	name := ReleaseName(f.MajorVersion)
+	switch {
+	case name == "" || f.MajorVersion >= 56 && f.MinorVersion != 0 && !f.IsPreview():
+		return i18n.T("version.unknown")
+	case f.IsPreview():
+		return i18n.T("version.jdk_preview", name, f.MajorVersion, f.MinorVersion)
+	case IsLTS(f.MajorVersion):
+		return i18n.T("version.jdk_lts", name, f.MajorVersion, f.MinorVersion)
 	}
-	return "Unknow JDK Version"
+	return i18n.T("version.jdk", name, f.MajorVersion, f.MinorVersion)
 }
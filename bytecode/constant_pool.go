@@ -4,8 +4,54 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"unicode/utf16"
 )
 
+const CONSTANT_Utf8 = 1
+const CONSTANT_Integer = 3
+const CONSTANT_Float = 4
+const CONSTANT_Long = 5
+const CONSTANT_Double = 6
+const CONSTANT_Class = 7
+const CONSTANT_String = 8
+const CONSTANT_Fieldref = 9
+const CONSTANT_Methodref = 10
+const CONSTANT_InterfaceMethodref = 11
+const CONSTANT_NameAndType = 12
+const CONSTANT_MethodHandle = 15
+const CONSTANT_MethodType = 16
+const CONSTANT_Dynamic = 17
+const CONSTANT_InvokeDynamic = 18
+const CONSTANT_Module = 19
+const CONSTANT_Package = 20
+
+var constantTagNames = map[uint8]string{
+	CONSTANT_Utf8:               "Utf8",
+	CONSTANT_Integer:            "Integer",
+	CONSTANT_Float:              "Float",
+	CONSTANT_Long:               "Long",
+	CONSTANT_Double:             "Double",
+	CONSTANT_Class:              "Class",
+	CONSTANT_String:             "String",
+	CONSTANT_Fieldref:           "Fieldref",
+	CONSTANT_Methodref:          "Methodref",
+	CONSTANT_InterfaceMethodref: "InterfaceMethodref",
+	CONSTANT_NameAndType:        "NameAndType",
+	CONSTANT_MethodHandle:       "MethodHandle",
+	CONSTANT_MethodType:         "MethodType",
+	CONSTANT_Dynamic:            "Dynamic",
+	CONSTANT_InvokeDynamic:      "InvokeDynamic",
+	CONSTANT_Module:             "Module",
+	CONSTANT_Package:            "Package",
+}
+
+func ConstantTagName(tag uint8) string {
+	if name, ok := constantTagNames[tag]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", tag)
+}
+
 type ConstantPoolInfo interface {
 	TagValue() uint8
 
@@ -441,3 +487,109 @@ func (c *ConstantPackage) Parse(data []byte, index int) int {
 	binary.Read(bytes.NewBuffer(data[index+1:index+3]), binary.BigEndian, &c.NameIndex)
 	return 3
 }
+
+var referenceKindNames = []string{"", "REF_getField", "REF_getStatic", "REF_putField", "REF_putStatic",
+	"REF_invokeVirtual", "REF_invokeStatic", "REF_invokeSpecial", "REF_newInvokeSpecial", "REF_invokeInterface"}
+
+func ReferenceKindName(kind uint8) string {
+	if kind == 0 || int(kind) >= len(referenceKindNames) {
+		return fmt.Sprintf("REF_unknown(%d)", kind)
+	}
+	return referenceKindNames[kind]
+}
+
+// 将modified UTF-8解码为字符串, 补充平面的字符在class文件中以两个代理项分别编码, 非法字节替换为U+FFFD
+func DecodeModifiedUtf8(value []byte) string {
+	units := make([]uint16, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+		case c&0xE0 == 0xC0 && i+1 < len(value):
+			units = append(units, uint16(c&0x1F)<<6|uint16(value[i+1]&0x3F))
+			i++
+		case c&0xF0 == 0xE0 && i+2 < len(value):
+			units = append(units, uint16(c&0x0F)<<12|uint16(value[i+1]&0x3F)<<6|uint16(value[i+2]&0x3F))
+			i += 2
+		default:
+			units = append(units, 0xFFFD)
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// 索引非法或者不是Utf8时返回空字符串
+func constantUtf8(constantPool []ConstantPoolInfo, index uint16) string {
+	if int(index) < len(constantPool) {
+		if utf8, ok := constantPool[index].(*ConstantUtf8); ok {
+			return string(utf8.Value)
+		}
+	}
+	return ""
+}
+
+// 索引越界时返回nil
+func ConstantAt(constantPool []ConstantPoolInfo, index uint16) ConstantPoolInfo {
+	if int(index) < len(constantPool) {
+		return constantPool[index]
+	}
+	return nil
+}
+
+// 解码后的Utf8常量, 索引非法或者不是Utf8时返回空字符串
+func Utf8At(constantPool []ConstantPoolInfo, index uint16) string {
+	if utf8, ok := ConstantAt(constantPool, index).(*ConstantUtf8); ok {
+		return DecodeModifiedUtf8(utf8.Value)
+	}
+	return ""
+}
+
+// Class常量的类名, 索引非法或者不是Class时返回空字符串
+func ClassNameAt(constantPool []ConstantPoolInfo, index uint16) string {
+	if c, ok := ConstantAt(constantPool, index).(*ConstantClass); ok {
+		return Utf8At(constantPool, c.NameIndex)
+	}
+	return ""
+}
+
+// 解析常量的最终值, 索引非法时不会panic
+func ResolveConstant(constantPool []ConstantPoolInfo, index uint16) string {
+	return resolve(constantPool, index, 0)
+}
+
+func resolve(constantPool []ConstantPoolInfo, index uint16, depth int) string {
+	if index == 0 || int(index) >= len(constantPool) || constantPool[index] == nil || depth > 4 {
+		return fmt.Sprintf("<invalid #%d>", index)
+	}
+	depth++
+	switch c := constantPool[index].(type) {
+	case *ConstantClass:
+		return resolve(constantPool, c.NameIndex, depth)
+	case *ConstantString:
+		return resolve(constantPool, c.StringIndex, depth)
+	case *ConstantFieldref:
+		return resolve(constantPool, c.ClassIndex, depth) + "." + resolve(constantPool, c.NameAndTypeIndex, depth)
+	case *ConstantMethodref:
+		return resolve(constantPool, c.ClassIndex, depth) + "." + resolve(constantPool, c.NameAndTypeIndex, depth)
+	case *ConstantInterfaceMethodref:
+		return resolve(constantPool, c.ClassIndex, depth) + "." + resolve(constantPool, c.NameAndTypeIndex, depth)
+	case *ConstantNameAndType:
+		return resolve(constantPool, c.NameIndex, depth) + ":" + resolve(constantPool, c.DescriptorIndex, depth)
+	case *ConstantMethodHandle:
+		return ReferenceKindName(c.ReferenceKind) + " " + resolve(constantPool, c.ReferenceIndex, depth)
+	case *ConstantMethodType:
+		return resolve(constantPool, c.DescriptorIndex, depth)
+	case *ConstantDynamic:
+		return fmt.Sprintf("#%d:", c.BootstrapMethodAttrIndex) + resolve(constantPool, c.NameAndTypeIndex, depth)
+	case *ConstantInvokeDynamic:
+		return fmt.Sprintf("#%d:", c.BootstrapMethodAttrIndex) + resolve(constantPool, c.NameAndTypeIndex, depth)
+	case *ConstantModule:
+		return resolve(constantPool, c.NameIndex, depth)
+	case *ConstantPackage:
+		return resolve(constantPool, c.NameIndex, depth)
+	case *ConstantPlaceHolder:
+		return fmt.Sprintf("<invalid #%d>", index)
+	}
+	return constantPool[index].String(constantPool)
+}
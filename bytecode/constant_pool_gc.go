@@ -0,0 +1,184 @@
+package bytecode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errUnknownAttributes = errors.New("class file has unknown attributes that may refer to the constant pool")
+
+// 计算常量池中每一项是否被类文件引用, 常量之间的引用会被传递
+func (f *ClassFile) ReachableConstants() ([]bool, error) {
+	reachable := make([]bool, len(f.ConstantPool))
+	edges := make(map[uint16][]uint16)
+	roots := make([]uint16, 0)
+	err := f.WalkConstantPoolRefs(func(ref *ConstantPoolRef) {
+		index := *ref.Index
+		if index == 0 || int(index) >= len(f.ConstantPool) {
+			return
+		}
+		if ref.From == 0 {
+			roots = append(roots, index)
+		} else {
+			edges[ref.From] = append(edges[ref.From], index)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for len(roots) > 0 {
+		index := roots[len(roots)-1]
+		roots = roots[:len(roots)-1]
+		if reachable[index] {
+			continue
+		}
+		reachable[index] = true
+		roots = append(roots, edges[index]...)
+	}
+	return reachable, nil
+}
+
+// 删除没有被引用的常量并重新编号, 返回删除的常量个数.
+// 不认识的属性中可能有常量池索引, 无法改写, 这时返回错误, 可以改用RemoveUnusedConstantsFrom
+func (f *ClassFile) RemoveUnusedConstants() (int, error) {
+	if f.HasUnknownAttributes() {
+		return 0, errUnknownAttributes
+	}
+	return f.removeUnusedConstants(1)
+}
+
+// 只删除索引不小于from的未使用常量, 之前的常量保持原来的索引, 用于只追加过常量的类文件.
+// 不认识的属性只能引用from之前的常量, 所以可以有不认识的属性
+func (f *ClassFile) RemoveUnusedConstantsFrom(from int) (int, error) {
+	if from < 1 {
+		from = 1
+	}
+	return f.removeUnusedConstants(from)
+}
+
+func (f *ClassFile) removeUnusedConstants(from int) (int, error) {
+	reachable, err := f.ReachableConstants()
+	if err != nil {
+		return 0, err
+	}
+	order := make([]uint16, 0, len(f.ConstantPool))
+	removed := 0
+	for i := 1; i < len(f.ConstantPool); i++ {
+		if f.ConstantPool[i] == nil {
+			continue
+		}
+		if i < from || reachable[i] {
+			order = append(order, uint16(i))
+		} else {
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, f.renumber(order)
+}
+
+// 按order中旧索引的顺序重建常量池, 不在order中的常量会被删除, 所有引用同步改写.
+// 任何引用指向被删除的常量、或者字节码无法重新排布时返回错误且不修改类文件; 有不认识的属性时也返回错误
+func (f *ClassFile) RenumberConstantPool(order []uint16) error {
+	if f.HasUnknownAttributes() {
+		return errUnknownAttributes
+	}
+	return f.renumber(order)
+}
+
+func (f *ClassFile) renumber(order []uint16) error {
+	mapping := make([]uint16, len(f.ConstantPool))
+	pool := []ConstantPoolInfo{&ConstantPlaceHolder{}}
+	for _, old := range order {
+		if old == 0 || int(old) >= len(f.ConstantPool) || f.ConstantPool[old] == nil {
+			return fmt.Errorf("constant #%d does not exist", old)
+		}
+		if mapping[old] != 0 {
+			return fmt.Errorf("constant #%d appears more than once", old)
+		}
+		if len(pool) > math.MaxUint16-1 {
+			return fmt.Errorf("constant pool exceeds %d entries", math.MaxUint16)
+		}
+		mapping[old] = uint16(len(pool))
+		pool = append(pool, f.ConstantPool[old])
+		switch f.ConstantPool[old].(type) {
+		case *ConstantLong, *ConstantDouble:
+			pool = append(pool, nil)
+		}
+	}
+	if len(pool) > math.MaxUint16 {
+		return fmt.Errorf("constant pool exceeds %d entries", math.MaxUint16)
+	}
+
+	// 先检查一遍: 指令中的引用改写的是指令序列的副本, 用来确认ldc加宽后字节码仍然可以重新排布
+	var missing error
+	errs := f.walk(&refWalker{constantPool: f.ConstantPool, dryRun: true, visit: func(ref *ConstantPoolRef) {
+		index := *ref.Index
+		if missing != nil || index == 0 || (ref.From != 0 && mapping[ref.From] == 0) {
+			return
+		}
+		if int(index) >= len(mapping) || mapping[index] == 0 {
+			missing = ConstantPoolRefError{Location: ref.Location, Index: index, Message: "refers to a removed or invalid constant"}
+		} else if ref.inCode {
+			*ref.Index = mapping[index]
+		}
+	}})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	if missing != nil {
+		return missing
+	}
+
+	err := f.WalkConstantPoolRefs(func(ref *ConstantPoolRef) {
+		if *ref.Index != 0 && (ref.From == 0 || mapping[ref.From] != 0) {
+			*ref.Index = mapping[*ref.Index]
+		}
+	})
+	if err != nil {
+		return err
+	}
+	f.ConstantPool = pool
+	f.ConstantPoolCount = uint16(len(pool))
+	return nil
+}
+
+// 检查所有常量池引用, 报告越界、指向不可用位置以及类型不符的索引
+func (f *ClassFile) CheckConstantPoolRefs() []ConstantPoolRefError {
+	errs := make([]ConstantPoolRefError, 0)
+	walkErrs := f.walkConstantPoolRefs(func(ref *ConstantPoolRef) {
+		index := *ref.Index
+		report := func(message string) {
+			errs = append(errs, ConstantPoolRefError{Location: ref.Location, Index: index, Message: message})
+		}
+		if index == 0 {
+			if !ref.Optional {
+				report("is zero")
+			}
+			return
+		}
+		if int(index) >= len(f.ConstantPool) {
+			report(fmt.Sprintf("is out of range, constant pool count is %d", len(f.ConstantPool)))
+			return
+		}
+		item := f.ConstantPool[index]
+		if item == nil || item.TagValue() == 0 {
+			report("refers to an unusable slot")
+			return
+		}
+		if !ref.accepts(item.TagValue()) {
+			expected := ""
+			for i, tag := range ref.Tags {
+				if i > 0 {
+					expected += "|"
+				}
+				expected += ConstantTagName(tag)
+			}
+			report(fmt.Sprintf("refers to %s, expected %s", item.TagName(), expected))
+		}
+	})
+	return append(errs, walkErrs...)
+}
@@ -0,0 +1,135 @@
+package bytecode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func parseTestClass(t *testing.T, name string) (*ClassFile, []byte) {
+	t.Helper()
+	data := loadTestClasses(t)[name]
+	f, err := ParseClassFile(data)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return f, data
+}
+
+func addConstant(f *ClassFile, item ConstantPoolInfo) uint16 {
+	f.ConstantPool = append(f.ConstantPool, item)
+	f.ConstantPoolCount = uint16(len(f.ConstantPool))
+	return uint16(len(f.ConstantPool) - 1)
+}
+
+func addUtf8(f *ClassFile, value string) uint16 {
+	return addConstant(f, &ConstantUtf8{Tag: CONSTANT_Utf8, Length: uint16(len(value)), Value: []byte(value)})
+}
+
+func classBytes(t *testing.T, f *ClassFile) []byte {
+	t.Helper()
+	data, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRemoveUnusedConstants(t *testing.T) {
+	f, _ := parseTestClass(t, "Box.class")
+	addUtf8(f, "unused")
+	removed, err := f.RemoveUnusedConstants()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed == 0 {
+		t.Fatal("expect the appended constant to be removed")
+	}
+	g, err := ParseClassFile(classBytes(t, f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := g.CheckConstantPoolRefs(); len(errs) > 0 {
+		t.Errorf("broken references after removing constants: %v", errs)
+	}
+	if g.ClassName() != "com/acme/Box" {
+		t.Errorf("class name is %s", g.ClassName())
+	}
+}
+
+// 不认识的属性中的索引无法改写, 重新编号会悄悄破坏类文件, 所以要返回错误
+func TestRenumberRejectsUnknownAttributes(t *testing.T) {
+	f, data := parseTestClass(t, "Lambdas.class")
+	order := make([]uint16, 0, len(f.ConstantPool))
+	for i := len(f.ConstantPool) - 1; i > 0; i-- {
+		if f.ConstantPool[i] != nil {
+			order = append(order, uint16(i))
+		}
+	}
+	if err := f.RenumberConstantPool(order); err == nil {
+		t.Error("expect an error for a class with unknown attributes")
+	}
+	if _, err := f.RemoveUnusedConstants(); err == nil {
+		t.Error("expect an error for a class with unknown attributes")
+	}
+	if !bytes.Equal(classBytes(t, f), data) {
+		t.Error("class file is modified")
+	}
+}
+
+func TestRemoveUnusedConstantsFrom(t *testing.T) {
+	f, data := parseTestClass(t, "Lambdas.class")
+	original := len(f.ConstantPool)
+	addUtf8(f, "unused")
+	removed, err := f.RemoveUnusedConstantsFrom(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d constants, expect 1", removed)
+	}
+	if !bytes.Equal(classBytes(t, f), data) {
+		t.Error("constants before from are changed")
+	}
+}
+
+// ldc加宽为ldc_w之后goto的偏移量溢出, 重新编号失败时类文件不能只改了一半
+func TestRenumberLeavesClassOnFailure(t *testing.T) {
+	f, _ := parseTestClass(t, "Box.class")
+	str := addConstant(f, &ConstantString{Tag: CONSTANT_String, StringIndex: addUtf8(f, "far")})
+	code := []byte{OPCODE_GOTO, 0x7f, 0xff, OPCODE_LDC, byte(str)}
+	for len(code) < 0x7fff {
+		code = append(code, OPCODE_NOP)
+	}
+	code = append(code, OPCODE_RETURN)
+	if str > 0xff {
+		t.Fatalf("string constant #%d does not fit ldc", str)
+	}
+	f.Methods = append(f.Methods, MethodInfo{AccessFlags: METHOD_ACC_STATIC, NameIndex: addUtf8(f, "far"), DescriptorIndex: addUtf8(f, "()V"),
+		Attributes: []AttributeInfo{&Code{AttributeBase: AttributeBase{NameIndex: addUtf8(f, "Code"), Name: "Code"},
+			MaxStack: 1, CodeLength: uint32(len(code)), Code: code}}})
+	f.MethodsCount = uint16(len(f.Methods))
+	for i := 0; i < 300; i++ {
+		addUtf8(f, "filler")
+	}
+	before := classBytes(t, f)
+
+	// 原来的常量放到填充的Utf8之后, 字符串常量放在最后
+	order := make([]uint16, 0, len(f.ConstantPool))
+	for i := len(f.ConstantPool) - 300; i < len(f.ConstantPool); i++ {
+		order = append(order, uint16(i))
+	}
+	for i := 1; i < len(f.ConstantPool)-300; i++ {
+		if f.ConstantPool[i] != nil && uint16(i) != str {
+			order = append(order, uint16(i))
+		}
+	}
+	order = append(order, str)
+	err := f.RenumberConstantPool(order)
+	if err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Fatalf("expect the branch offset to overflow, got %v", err)
+	}
+	if !bytes.Equal(classBytes(t, f), before) {
+		t.Error("class file is modified after a failed renumbering")
+	}
+}
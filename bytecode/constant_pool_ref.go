@@ -0,0 +1,401 @@
+package bytecode
+
+import (
+	"fmt"
+	"math"
+)
+
+var loadableTags = []uint8{CONSTANT_Integer, CONSTANT_Float, CONSTANT_Long, CONSTANT_Double, CONSTANT_Class,
+	CONSTANT_String, CONSTANT_MethodHandle, CONSTANT_MethodType, CONSTANT_Dynamic}
+
+// 类文件中对常量池的一处引用
+type ConstantPoolRef struct {
+	Location string
+	Index    *uint16
+	Tags     []uint8 //允许引用的常量类型, 为空时不限制
+	Optional bool    //是否允许为0
+	From     uint16  //引用来自常量池中的哪一项, 0表示来自常量池之外
+	inCode   bool    //指令中的引用, 指向指令序列的副本
+}
+
+func (r *ConstantPoolRef) accepts(tag uint8) bool {
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+type ConstantPoolRefError struct {
+	Location string
+	Index    uint16
+	Message  string
+}
+
+func (e ConstantPoolRefError) Error() string {
+	if e.Index == 0 && e.Message != "is zero" {
+		return fmt.Sprintf("%s: %s", e.Location, e.Message)
+	}
+	return fmt.Sprintf("%s: #%d %s", e.Location, e.Index, e.Message)
+}
+
+type refWalker struct {
+	constantPool []ConstantPoolInfo
+	visit        func(ref *ConstantPoolRef)
+	errs         []ConstantPoolRefError //无法解析的字节码, 其中的引用会被跳过
+	dryRun       bool                   //只检查改写后的字节码能否重新排布, 不修改Code
+}
+
+func (w *refWalker) ref(location string, index *uint16, tags ...uint8) {
+	w.visit(&ConstantPoolRef{Location: location, Index: index, Tags: tags})
+}
+
+func (w *refWalker) optional(location string, index *uint16, tags ...uint8) {
+	w.visit(&ConstantPoolRef{Location: location, Index: index, Tags: tags, Optional: true})
+}
+
+func (w *refWalker) name(index uint16) string {
+	if int(index) < len(w.constantPool) {
+		if utf8, ok := w.constantPool[index].(*ConstantUtf8); ok {
+			return string(utf8.Value)
+		}
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// 遍历类文件中所有的常量池索引, visit中可以修改索引的值.
+// 字节码中的ldc在索引超过255时会改为ldc_w, 并重新计算所有偏移量
+func (f *ClassFile) WalkConstantPoolRefs(visit func(ref *ConstantPoolRef)) error {
+	errs := f.walkConstantPoolRefs(visit)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+func (f *ClassFile) walkConstantPoolRefs(visit func(ref *ConstantPoolRef)) []ConstantPoolRefError {
+	return f.walk(&refWalker{constantPool: f.ConstantPool, visit: visit})
+}
+
+func (f *ClassFile) walk(w *refWalker) []ConstantPoolRefError {
+	for i, item := range f.ConstantPool {
+		if item != nil {
+			w.constant(uint16(i), item)
+		}
+	}
+	w.ref("this_class", &f.ThisClass, CONSTANT_Class)
+	w.optional("super_class", &f.SuperClass, CONSTANT_Class)
+	for i := range f.Interfaces {
+		w.ref(fmt.Sprintf("interfaces[%d]", i), &f.Interfaces[i], CONSTANT_Class)
+	}
+	for i := range f.Fields {
+		field := &f.Fields[i]
+		location := "field " + w.name(field.NameIndex)
+		w.ref(location+" name_index", &field.NameIndex, CONSTANT_Utf8)
+		w.ref(location+" descriptor_index", &field.DescriptorIndex, CONSTANT_Utf8)
+		w.attributes(location, field.Attributes)
+	}
+	for i := range f.Methods {
+		method := &f.Methods[i]
+		location := "method " + w.name(method.NameIndex) + w.name(method.DescriptorIndex)
+		w.ref(location+" name_index", &method.NameIndex, CONSTANT_Utf8)
+		w.ref(location+" descriptor_index", &method.DescriptorIndex, CONSTANT_Utf8)
+		w.attributes(location, method.Attributes)
+	}
+	w.attributes("class", f.Attributes)
+	return w.errs
+}
+
+func (w *refWalker) constant(index uint16, item ConstantPoolInfo) {
+	location := fmt.Sprintf("constant #%d %s", index, item.TagName())
+	ref := func(field string, value *uint16, tags ...uint8) {
+		w.visit(&ConstantPoolRef{Location: location + "." + field, Index: value, Tags: tags, From: index})
+	}
+	switch c := item.(type) {
+	case *ConstantClass:
+		ref("name_index", &c.NameIndex, CONSTANT_Utf8)
+	case *ConstantString:
+		ref("string_index", &c.StringIndex, CONSTANT_Utf8)
+	case *ConstantFieldref:
+		ref("class_index", &c.ClassIndex, CONSTANT_Class)
+		ref("name_and_type_index", &c.NameAndTypeIndex, CONSTANT_NameAndType)
+	case *ConstantMethodref:
+		ref("class_index", &c.ClassIndex, CONSTANT_Class)
+		ref("name_and_type_index", &c.NameAndTypeIndex, CONSTANT_NameAndType)
+	case *ConstantInterfaceMethodref:
+		ref("class_index", &c.ClassIndex, CONSTANT_Class)
+		ref("name_and_type_index", &c.NameAndTypeIndex, CONSTANT_NameAndType)
+	case *ConstantNameAndType:
+		ref("name_index", &c.NameIndex, CONSTANT_Utf8)
+		ref("descriptor_index", &c.DescriptorIndex, CONSTANT_Utf8)
+	case *ConstantMethodHandle:
+		ref("reference_index", &c.ReferenceIndex, CONSTANT_Fieldref, CONSTANT_Methodref, CONSTANT_InterfaceMethodref)
+	case *ConstantMethodType:
+		ref("descriptor_index", &c.DescriptorIndex, CONSTANT_Utf8)
+	case *ConstantDynamic:
+		ref("name_and_type_index", &c.NameAndTypeIndex, CONSTANT_NameAndType)
+	case *ConstantInvokeDynamic:
+		ref("name_and_type_index", &c.NameAndTypeIndex, CONSTANT_NameAndType)
+	case *ConstantModule:
+		ref("name_index", &c.NameIndex, CONSTANT_Utf8)
+	case *ConstantPackage:
+		ref("name_index", &c.NameIndex, CONSTANT_Utf8)
+	}
+}
+
+func (w *refWalker) attributes(owner string, attrs []AttributeInfo) {
+	for _, attr := range attrs {
+		if attr == nil {
+			continue
+		}
+		location := owner + " " + attr.GetName()
+		w.ref(location+" attribute_name_index", &attr.base().NameIndex, CONSTANT_Utf8)
+		switch a := visibleAttribute(attr).(type) {
+		case *ConstantValue:
+			w.ref(location, &a.ConstantValueIndex, CONSTANT_Integer, CONSTANT_Float, CONSTANT_Long, CONSTANT_Double, CONSTANT_String)
+		case *Code:
+			for i := range a.Table {
+				w.optional(fmt.Sprintf("%s exception_table[%d].catch_type", location, i), &a.Table[i].CatchType, CONSTANT_Class)
+			}
+			w.code(location, a)
+			w.attributes(location, a.Attributes)
+		case *StackMapTable:
+			for i := range a.Entries {
+				frame := &a.Entries[i]
+				for n := range frame.Locals {
+					if frame.Locals[n].Tag == 7 {
+						w.ref(fmt.Sprintf("%s frame[%d].locals[%d]", location, i, n), &frame.Locals[n].CpoolIndex, CONSTANT_Class)
+					}
+				}
+				for n := range frame.Stacks {
+					if frame.Stacks[n].Tag == 7 {
+						w.ref(fmt.Sprintf("%s frame[%d].stack[%d]", location, i, n), &frame.Stacks[n].CpoolIndex, CONSTANT_Class)
+					}
+				}
+			}
+		case *Exceptions:
+			for i := range a.ExceptionIndexTable {
+				w.ref(fmt.Sprintf("%s[%d]", location, i), &a.ExceptionIndexTable[i], CONSTANT_Class)
+			}
+		case *InnerClasses:
+			for i := range a.Classes {
+				c := &a.Classes[i]
+				prefix := fmt.Sprintf("%s[%d]", location, i)
+				w.ref(prefix+".inner_class_info_index", &c.InnerClassIndex, CONSTANT_Class)
+				w.optional(prefix+".outer_class_info_index", &c.OuterClassIndex, CONSTANT_Class)
+				w.optional(prefix+".inner_name_index", &c.InnerNameIndex, CONSTANT_Utf8)
+			}
+		case *EnclosingMethod:
+			w.ref(location+".class_index", &a.ClassIndex, CONSTANT_Class)
+			w.optional(location+".method_index", &a.MethodIndex, CONSTANT_NameAndType)
+		case *Signature:
+			w.ref(location, &a.SignatureIndex, CONSTANT_Utf8)
+		case *SourceFile:
+			w.ref(location, &a.SourceFileIndex, CONSTANT_Utf8)
+		case *LocalVariableTable:
+			for i := range a.LocalVariable {
+				v := &a.LocalVariable[i]
+				w.ref(fmt.Sprintf("%s[%d].name_index", location, i), &v.NameIndex, CONSTANT_Utf8)
+				w.ref(fmt.Sprintf("%s[%d].descriptor_index", location, i), &v.DescriptorIndex, CONSTANT_Utf8)
+			}
+		case *LocalVariableTypeTable:
+			for i := range a.LocalVariableType {
+				v := &a.LocalVariableType[i]
+				w.ref(fmt.Sprintf("%s[%d].name_index", location, i), &v.NameIndex, CONSTANT_Utf8)
+				w.ref(fmt.Sprintf("%s[%d].signature_index", location, i), &v.SignatureIndex, CONSTANT_Utf8)
+			}
+		case *RuntimeVisibleAnnotations:
+			for i := range a.Annotations {
+				w.annotation(fmt.Sprintf("%s[%d]", location, i), &a.Annotations[i])
+			}
+		case *RuntimeVisibleParameterAnnotations:
+			for i := range a.ParameterAnnotations {
+				for n := range a.ParameterAnnotations[i].Annotations {
+					w.annotation(fmt.Sprintf("%s[%d][%d]", location, i, n), &a.ParameterAnnotations[i].Annotations[n])
+				}
+			}
+		case *RuntimeVisibleTypeAnnotations:
+			for i := range a.Annotations {
+				ann := &a.Annotations[i]
+				prefix := fmt.Sprintf("%s[%d]", location, i)
+				w.ref(prefix+".type_index", &ann.TypeIndex, CONSTANT_Utf8)
+				for n := range ann.ValuePairs {
+					w.elementValuePair(prefix, &ann.ValuePairs[n])
+				}
+			}
+		case *AnnotationDefault:
+			w.elementValue(location, &a.DefaultValue)
+		case *BootstrapMethods:
+			for i := range a.Methods {
+				method := &a.Methods[i]
+				prefix := fmt.Sprintf("%s[%d]", location, i)
+				w.ref(prefix+".bootstrap_method_ref", &method.BootstrapMethodRef, CONSTANT_MethodHandle)
+				for n := range method.Arguments {
+					w.ref(fmt.Sprintf("%s.bootstrap_arguments[%d]", prefix, n), &method.Arguments[n], loadableTags...)
+				}
+			}
+		case *MethodParameters:
+			for i := range a.Parameters {
+				w.optional(fmt.Sprintf("%s[%d].name_index", location, i), &a.Parameters[i].NameIndex, CONSTANT_Utf8)
+			}
+		case *Module:
+			w.module(location, a)
+		case *ModulePackages:
+			for i := range a.PackageIndex {
+				w.ref(fmt.Sprintf("%s[%d]", location, i), &a.PackageIndex[i], CONSTANT_Package)
+			}
+		case *ModuleMainClass:
+			w.ref(location, &a.MainClassIndex, CONSTANT_Class)
+		case *NestHost:
+			w.ref(location, &a.HostClassIndex, CONSTANT_Class)
+		case *NestMembers:
+			for i := range a.Classes {
+				w.ref(fmt.Sprintf("%s[%d]", location, i), &a.Classes[i], CONSTANT_Class)
+			}
+		case *PermittedSubclasses:
+			for i := range a.Classes {
+				w.ref(fmt.Sprintf("%s[%d]", location, i), &a.Classes[i], CONSTANT_Class)
+			}
+		case *Record:
+			for i := range a.RecordComponentInfo {
+				component := &a.RecordComponentInfo[i]
+				prefix := fmt.Sprintf("%s component %s", location, w.name(component.NameIndex))
+				w.ref(prefix+" name_index", &component.NameIndex, CONSTANT_Utf8)
+				w.ref(prefix+" descriptor_index", &component.DescriptorIndex, CONSTANT_Utf8)
+				w.attributes(prefix, component.Attributes)
+			}
+		}
+	}
+}
+
+func (w *refWalker) code(location string, c *Code) {
+	instructions, err := c.Instructions()
+	if err != nil {
+		w.errs = append(w.errs, ConstantPoolRefError{Location: location, Message: err.Error()})
+		return
+	}
+	changed := false
+	for i := range instructions {
+		ins := &instructions[i]
+		if !ins.HasConstantPoolIndex() {
+			continue
+		}
+		var tags []uint8
+		switch ins.Opcode {
+		case OPCODE_LDC, OPCODE_LDC_W:
+			tags = []uint8{CONSTANT_Integer, CONSTANT_Float, CONSTANT_String, CONSTANT_Class,
+				CONSTANT_MethodHandle, CONSTANT_MethodType, CONSTANT_Dynamic}
+		case OPCODE_LDC2_W:
+			tags = []uint8{CONSTANT_Long, CONSTANT_Double, CONSTANT_Dynamic}
+		case OPCODE_GETSTATIC, OPCODE_PUTSTATIC, OPCODE_GETFIELD, OPCODE_PUTFIELD:
+			tags = []uint8{CONSTANT_Fieldref}
+		case OPCODE_INVOKEVIRTUAL:
+			tags = []uint8{CONSTANT_Methodref}
+		case OPCODE_INVOKESPECIAL, OPCODE_INVOKESTATIC:
+			tags = []uint8{CONSTANT_Methodref, CONSTANT_InterfaceMethodref}
+		case OPCODE_INVOKEINTERFACE:
+			tags = []uint8{CONSTANT_InterfaceMethodref}
+		case OPCODE_INVOKEDYNAMIC:
+			tags = []uint8{CONSTANT_InvokeDynamic}
+		default:
+			tags = []uint8{CONSTANT_Class}
+		}
+		original := ins.Index
+		w.visit(&ConstantPoolRef{Location: fmt.Sprintf("%s pc %d %s", location, ins.Pc, ins.Name()), Index: &ins.Index, Tags: tags, inCode: true})
+		if ins.Index != original {
+			changed = true
+			if ins.Opcode == OPCODE_LDC && ins.Index > math.MaxUint8 {
+				ins.Opcode = OPCODE_LDC_W
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+	if w.dryRun {
+		if _, _, err := c.relocateInstructions(instructions); err != nil {
+			w.errs = append(w.errs, ConstantPoolRefError{Location: location, Message: err.Error()})
+		}
+		return
+	}
+	if err := c.SetInstructions(instructions); err != nil {
+		w.errs = append(w.errs, ConstantPoolRefError{Location: location, Message: err.Error()})
+	}
+}
+
+func (w *refWalker) annotation(location string, a *Annotation) {
+	w.ref(location+".type_index", &a.TypeIndex, CONSTANT_Utf8)
+	for i := range a.ValuePairs {
+		w.elementValuePair(location, &a.ValuePairs[i])
+	}
+}
+
+func (w *refWalker) elementValuePair(location string, pair *ElementValuePairs) {
+	location += "." + w.name(pair.ElementNameIndex)
+	w.ref(location+" element_name_index", &pair.ElementNameIndex, CONSTANT_Utf8)
+	w.elementValue(location, &pair.ElementValue)
+}
+
+func (w *refWalker) elementValue(location string, e *ElementValue) {
+	switch e.Tag {
+	case 'B', 'C', 'I', 'S', 'Z':
+		w.ref(location+" const_value_index", &e.ConstValueIndex, CONSTANT_Integer)
+	case 'D':
+		w.ref(location+" const_value_index", &e.ConstValueIndex, CONSTANT_Double)
+	case 'F':
+		w.ref(location+" const_value_index", &e.ConstValueIndex, CONSTANT_Float)
+	case 'J':
+		w.ref(location+" const_value_index", &e.ConstValueIndex, CONSTANT_Long)
+	case 's':
+		w.ref(location+" const_value_index", &e.ConstValueIndex, CONSTANT_Utf8)
+	case 'e':
+		w.ref(location+" type_name_index", &e.TypeNameIndex, CONSTANT_Utf8)
+		w.ref(location+" const_name_index", &e.ConstNameIndex, CONSTANT_Utf8)
+	case 'c':
+		w.ref(location+" class_info_index", &e.ClassInfoIndex, CONSTANT_Utf8)
+	case '@':
+		w.annotation(location, &e.AnnotationValue)
+	case '[':
+		for i := range e.Values {
+			w.elementValue(fmt.Sprintf("%s[%d]", location, i), &e.Values[i])
+		}
+	}
+}
+
+func (w *refWalker) module(location string, m *Module) {
+	w.ref(location+".module_name_index", &m.ModuleNameIndex, CONSTANT_Module)
+	w.optional(location+".module_version_index", &m.ModuleVersionIndex, CONSTANT_Utf8)
+	for i := range m.Requires {
+		r := &m.Requires[i]
+		w.ref(fmt.Sprintf("%s.requires[%d]", location, i), &r.RequiresIndex, CONSTANT_Module)
+		w.optional(fmt.Sprintf("%s.requires[%d].version", location, i), &r.RequiresVersionIndex, CONSTANT_Utf8)
+	}
+	for i := range m.Exports {
+		e := &m.Exports[i]
+		w.ref(fmt.Sprintf("%s.exports[%d]", location, i), &e.ExportsIndex, CONSTANT_Package)
+		for n := range e.ExportsToIndex {
+			w.ref(fmt.Sprintf("%s.exports[%d].to[%d]", location, i, n), &e.ExportsToIndex[n], CONSTANT_Module)
+		}
+	}
+	for i := range m.Opens {
+		o := &m.Opens[i]
+		w.ref(fmt.Sprintf("%s.opens[%d]", location, i), &o.OpenIndex, CONSTANT_Package)
+		for n := range o.OpenToIndex {
+			w.ref(fmt.Sprintf("%s.opens[%d].to[%d]", location, i, n), &o.OpenToIndex[n], CONSTANT_Module)
+		}
+	}
+	for i := range m.UsesIndex {
+		w.ref(fmt.Sprintf("%s.uses[%d]", location, i), &m.UsesIndex[i], CONSTANT_Class)
+	}
+	for i := range m.Provides {
+		p := &m.Provides[i]
+		w.ref(fmt.Sprintf("%s.provides[%d]", location, i), &p.ProvidesIndex, CONSTANT_Class)
+		for n := range p.ProvidesWithIndex {
+			w.ref(fmt.Sprintf("%s.provides[%d].with[%d]", location, i, n), &p.ProvidesWithIndex[n], CONSTANT_Class)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package bytecode
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 索引越界、指向空位或者类型不对时都不能panic
+func TestPoolAccessors(t *testing.T) {
+	f, _ := parseTestClass(t, "Box.class")
+	pool := f.ConstantPool
+	if name := ClassNameAt(pool, f.ThisClass); name != "com/acme/Box" {
+		t.Errorf("class name is %q", name)
+	}
+	for _, index := range []uint16{0, uint16(len(pool)), 0xffff} {
+		if Utf8At(pool, index) != "" || ClassNameAt(pool, index) != "" {
+			t.Errorf("index #%d should not resolve", index)
+		}
+	}
+	if ConstantAt(pool, uint16(len(pool))) != nil {
+		t.Error("index out of range should return nil")
+	}
+	utf8 := addUtf8(f, "caf\xc3\xa9")
+	if got := Utf8At(f.ConstantPool, utf8); got != "café" {
+		t.Errorf("utf8 is %q", got)
+	}
+	if ClassNameAt(f.ConstantPool, utf8) != "" {
+		t.Error("a Utf8 constant is not a class")
+	}
+}
+
+func TestDescriptorClasses(t *testing.T) {
+	tests := map[string][]string{
+		"Ljava/lang/String;": {"java/lang/String"},
+		"[[Ljava/util/List;": {"java/util/List"},
+		"I":                  {},
+		"(ILjava/lang/Object;[Ljava/io/File;)Ljava/net/URI;": {"java/lang/Object", "java/io/File", "java/net/URI"},
+		"(L": nil,
+	}
+	for desc, want := range tests {
+		if got := DescriptorClasses(desc); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", desc, got, want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package bytecode
+
+import (
+	"fmt"
+)
+
+type ConstantPoolError struct {
+	Index   uint16
+	Tag     string
+	Message string
+}
+
+func (e ConstantPoolError) Error() string {
+	return fmt.Sprintf("#%d %s: %s", e.Index, e.Tag, e.Message)
+}
+
+type poolValidator struct {
+	f    *ClassFile
+	errs []ConstantPoolError
+}
+
+func (v *poolValidator) report(index uint16, format string, args ...interface{}) {
+	tag := ""
+	if int(index) < len(v.f.ConstantPool) && v.f.ConstantPool[index] != nil {
+		tag = v.f.ConstantPool[index].TagName()
+	}
+	v.errs = append(v.errs, ConstantPoolError{Index: index, Tag: tag, Message: fmt.Sprintf(format, args...)})
+}
+
+// 引用的常量满足类型要求时返回该常量
+func (v *poolValidator) get(from uint16, field string, index uint16, tag uint8) ConstantPoolInfo {
+	pool := v.f.ConstantPool
+	if index == 0 || int(index) >= len(pool) {
+		v.report(from, "%s #%d is out of range", field, index)
+		return nil
+	}
+	if pool[index] == nil || pool[index].TagValue() == 0 {
+		v.report(from, "%s #%d refers to an unusable slot", field, index)
+		return nil
+	}
+	if tag != 0 && pool[index].TagValue() != tag {
+		v.report(from, "%s #%d refers to %s, expected %s", field, index, pool[index].TagName(), ConstantTagName(tag))
+		return nil
+	}
+	return pool[index]
+}
+
+func (v *poolValidator) utf8(from uint16, field string, index uint16) (string, bool) {
+	if item, ok := v.get(from, field, index, CONSTANT_Utf8).(*ConstantUtf8); ok {
+		return string(item.Value), true
+	}
+	return "", false
+}
+
+func (v *poolValidator) nameAndType(from uint16, index uint16) (string, string, bool) {
+	item, ok := v.get(from, "name_and_type_index", index, CONSTANT_NameAndType).(*ConstantNameAndType)
+	if !ok {
+		return "", "", false
+	}
+	name, ok := v.utf8(index, "name_index", item.NameIndex)
+	if !ok {
+		return "", "", false
+	}
+	desc, ok := v.utf8(index, "descriptor_index", item.DescriptorIndex)
+	return name, desc, ok
+}
+
+// 按照JVMS 4.4检查常量池的静态约束, 返回所有违规的常量
+func (f *ClassFile) Validate() []ConstantPoolError {
+	v := &poolValidator{f: f}
+	pool := f.ConstantPool
+	if int(f.ConstantPoolCount) != len(pool) {
+		v.report(0, "constant_pool_count is %d, but there are %d slots", f.ConstantPoolCount, len(pool))
+	}
+	bootstrapCount := -1
+	for _, attr := range f.Attributes {
+		if b, ok := attr.(*BootstrapMethods); ok {
+			bootstrapCount = len(b.Methods)
+		}
+	}
+
+	for i := 1; i < len(pool); i++ {
+		index := uint16(i)
+		item := pool[i]
+		if item == nil || item.TagValue() == 0 {
+			v.report(index, "slot is empty but does not follow a Long or Double")
+			continue
+		}
+		switch item.TagValue() {
+		case CONSTANT_MethodHandle, CONSTANT_MethodType, CONSTANT_InvokeDynamic:
+			if f.MajorVersion < 51 {
+				v.report(index, "requires class file version 51.0, but is %d.%d", f.MajorVersion, f.MinorVersion)
+			}
+		case CONSTANT_Dynamic:
+			if f.MajorVersion < 55 {
+				v.report(index, "requires class file version 55.0, but is %d.%d", f.MajorVersion, f.MinorVersion)
+			}
+		case CONSTANT_Module, CONSTANT_Package:
+			if f.MajorVersion < 53 || f.AccessFlags&ACC_MODULE == 0 {
+				v.report(index, "is only allowed in module-info of class file version 53.0 or above")
+			}
+		}
+
+		switch c := item.(type) {
+		case *ConstantUtf8:
+			if int(c.Length) != len(c.Value) {
+				v.report(index, "length is %d, but there are %d bytes", c.Length, len(c.Value))
+			}
+			if !IsModifiedUtf8(c.Value) {
+				v.report(index, "is not valid modified UTF-8")
+			}
+		case *ConstantLong, *ConstantDouble:
+			if i+1 >= len(pool) {
+				v.report(index, "is the last entry, the following slot is missing")
+			} else if pool[i+1] != nil {
+				v.report(index, "must be followed by an unusable slot, but #%d is %s", i+1, pool[i+1].TagName())
+			}
+			i++
+		case *ConstantClass:
+			if name, ok := v.utf8(index, "name_index", c.NameIndex); ok && !IsClassName(name) {
+				v.report(index, "invalid class name %q", name)
+			}
+		case *ConstantString:
+			v.utf8(index, "string_index", c.StringIndex)
+		case *ConstantFieldref:
+			v.get(index, "class_index", c.ClassIndex, CONSTANT_Class)
+			if name, desc, ok := v.nameAndType(index, c.NameAndTypeIndex); ok {
+				if !IsUnqualifiedName(name) {
+					v.report(index, "invalid field name %q", name)
+				}
+				if !IsFieldDescriptor(desc) {
+					v.report(index, "invalid field descriptor %q", desc)
+				}
+			}
+		case *ConstantMethodref:
+			v.get(index, "class_index", c.ClassIndex, CONSTANT_Class)
+			v.methodRef(index, c.NameAndTypeIndex)
+		case *ConstantInterfaceMethodref:
+			v.get(index, "class_index", c.ClassIndex, CONSTANT_Class)
+			if name, _ := v.methodRef(index, c.NameAndTypeIndex); name == "<init>" {
+				v.report(index, "interface method cannot be <init>")
+			}
+		case *ConstantNameAndType:
+			name, nameOk := v.utf8(index, "name_index", c.NameIndex)
+			desc, descOk := v.utf8(index, "descriptor_index", c.DescriptorIndex)
+			if nameOk && !IsMethodName(name) {
+				v.report(index, "invalid name %q", name)
+			}
+			if descOk && !IsFieldDescriptor(desc) && !IsMethodDescriptor(desc) {
+				v.report(index, "invalid descriptor %q", desc)
+			}
+		case *ConstantMethodHandle:
+			v.methodHandle(index, c)
+		case *ConstantMethodType:
+			if desc, ok := v.utf8(index, "descriptor_index", c.DescriptorIndex); ok && !IsMethodDescriptor(desc) {
+				v.report(index, "invalid method descriptor %q", desc)
+			}
+		case *ConstantDynamic:
+			v.bootstrap(index, c.BootstrapMethodAttrIndex, bootstrapCount)
+			if _, desc, ok := v.nameAndType(index, c.NameAndTypeIndex); ok && !IsFieldDescriptor(desc) {
+				v.report(index, "dynamic constant must have a field descriptor, but is %q", desc)
+			}
+		case *ConstantInvokeDynamic:
+			v.bootstrap(index, c.BootstrapMethodAttrIndex, bootstrapCount)
+			if name, desc, ok := v.nameAndType(index, c.NameAndTypeIndex); ok {
+				if !IsMethodDescriptor(desc) {
+					v.report(index, "invokedynamic must have a method descriptor, but is %q", desc)
+				}
+				if name == "<init>" || name == "<clinit>" {
+					v.report(index, "invokedynamic cannot be named %s", name)
+				}
+			}
+		case *ConstantModule:
+			if name, ok := v.utf8(index, "name_index", c.NameIndex); ok && !IsModuleName(name) {
+				v.report(index, "invalid module name %q", name)
+			}
+		case *ConstantPackage:
+			if name, ok := v.utf8(index, "name_index", c.NameIndex); ok && !IsBinaryName(name) {
+				v.report(index, "invalid package name %q", name)
+			}
+		}
+	}
+	return v.errs
+}
+
+func (v *poolValidator) methodRef(index uint16, nameAndTypeIndex uint16) (string, string) {
+	name, desc, ok := v.nameAndType(index, nameAndTypeIndex)
+	if !ok {
+		return "", ""
+	}
+	if name == "<clinit>" || !IsMethodName(name) {
+		v.report(index, "invalid method name %q", name)
+	}
+	_, ret, err := ParseMethodDescriptor(desc)
+	if err != nil {
+		v.report(index, "invalid method descriptor %q: %s", desc, err.Error())
+	} else if name == "<init>" && ret != "V" {
+		v.report(index, "<init> must return void, but descriptor is %q", desc)
+	}
+	return name, desc
+}
+
+func (v *poolValidator) bootstrap(index uint16, bootstrapIndex uint16, count int) {
+	if count < 0 {
+		v.report(index, "class file has no BootstrapMethods attribute")
+	} else if int(bootstrapIndex) >= count {
+		v.report(index, "bootstrap_method_attr_index %d is out of range, there are %d bootstrap methods", bootstrapIndex, count)
+	}
+}
+
+func (v *poolValidator) methodHandle(index uint16, c *ConstantMethodHandle) {
+	pool := v.f.ConstantPool
+	var expected []uint8
+	switch c.ReferenceKind {
+	case 1, 2, 3, 4:
+		expected = []uint8{CONSTANT_Fieldref}
+	case 5, 8:
+		expected = []uint8{CONSTANT_Methodref}
+	case 6, 7:
+		expected = []uint8{CONSTANT_Methodref}
+		if v.f.MajorVersion >= 52 {
+			expected = append(expected, CONSTANT_InterfaceMethodref)
+		}
+	case 9:
+		expected = []uint8{CONSTANT_InterfaceMethodref}
+	default:
+		v.report(index, "reference_kind %d is not in 1..9", c.ReferenceKind)
+		return
+	}
+	ref := v.get(index, "reference_index", c.ReferenceIndex, 0)
+	if ref == nil {
+		return
+	}
+	matched := false
+	names := ""
+	for i, tag := range expected {
+		matched = matched || ref.TagValue() == tag
+		if i > 0 {
+			names += " or "
+		}
+		names += ConstantTagName(tag)
+	}
+	if !matched {
+		v.report(index, "%s must refer to %s, but #%d is %s", ReferenceKindName(c.ReferenceKind),
+			names, c.ReferenceIndex, ref.TagName())
+		return
+	}
+	if c.ReferenceKind < 5 {
+		return
+	}
+	var nameAndTypeIndex uint16
+	switch r := ref.(type) {
+	case *ConstantMethodref:
+		nameAndTypeIndex = r.NameAndTypeIndex
+	case *ConstantInterfaceMethodref:
+		nameAndTypeIndex = r.NameAndTypeIndex
+	}
+	if int(nameAndTypeIndex) >= len(pool) {
+		return
+	}
+	// name_index不是Utf8时已经在NameAndType中报告
+	if nt, ok := pool[nameAndTypeIndex].(*ConstantNameAndType); ok {
+		name := constantUtf8(pool, nt.NameIndex)
+		if name == "" {
+			return
+		}
+		if c.ReferenceKind == 8 && name != "<init>" {
+			v.report(index, "REF_newInvokeSpecial must refer to <init>, but refers to %s", name)
+		} else if c.ReferenceKind != 8 && (name == "<init>" || name == "<clinit>") {
+			v.report(index, "%s cannot refer to %s", ReferenceKindName(c.ReferenceKind), name)
+		}
+	}
+}
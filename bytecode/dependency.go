@@ -0,0 +1,76 @@
+package bytecode
+
+import (
+	"sort"
+	"strings"
+)
+
+// 描述符中的类型为对象或者对象数组时返回类名
+func descriptorClass(desc string) string {
+	desc = strings.TrimLeft(desc, "[")
+	if strings.HasPrefix(desc, "L") && strings.HasSuffix(desc, ";") {
+		return desc[1 : len(desc)-1]
+	}
+	return ""
+}
+
+// 字段或者方法描述符中的对象类型, 数组为元素类型, 描述符非法时返回nil
+func DescriptorClasses(desc string) []string {
+	types := make([]string, 0)
+	if strings.HasPrefix(desc, "(") {
+		params, ret, err := ParseMethodDescriptor(desc)
+		if err != nil {
+			return nil
+		}
+		types = append(params, ret)
+	} else if IsFieldDescriptor(desc) {
+		types = append(types, desc)
+	}
+	classes := make([]string, 0)
+	for _, t := range types {
+		if name := descriptorClass(t); name != "" {
+			classes = append(classes, name)
+		}
+	}
+	return classes
+}
+
+// 类文件通过常量池和描述符引用到的其他类, 按名称排序, 不包括类本身
+func (f *ClassFile) ReferencedClasses() []string {
+	pool := f.ConstantPool
+	seen := make(map[string]bool)
+	add := func(names ...string) {
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+	for _, item := range pool {
+		switch c := item.(type) {
+		case *ConstantClass:
+			name := constantUtf8(pool, c.NameIndex)
+			if strings.HasPrefix(name, "[") {
+				name = descriptorClass(name)
+			}
+			if name != "" {
+				add(name)
+			}
+		case *ConstantNameAndType:
+			add(DescriptorClasses(constantUtf8(pool, c.DescriptorIndex))...)
+		case *ConstantMethodType:
+			add(DescriptorClasses(constantUtf8(pool, c.DescriptorIndex))...)
+		}
+	}
+	for i := range f.Fields {
+		add(DescriptorClasses(f.Fields[i].Descriptor(pool))...)
+	}
+	for i := range f.Methods {
+		add(DescriptorClasses(f.Methods[i].Descriptor(pool))...)
+	}
+	delete(seen, f.ClassName())
+	classes := make([]string, 0, len(seen))
+	for name := range seen {
+		classes = append(classes, name)
+	}
+	sort.Strings(classes)
+	return classes
+}
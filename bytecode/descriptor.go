@@ -0,0 +1,162 @@
+package bytecode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 读取描述符开头的一个字段类型, 返回该类型和剩余部分
+func nextFieldType(desc string) (string, string, error) {
+	dims := 0
+	for dims < len(desc) && desc[dims] == '[' {
+		dims++
+	}
+	if dims > 255 {
+		return "", "", fmt.Errorf("array type %q has more than 255 dimensions", desc)
+	}
+	if dims == len(desc) {
+		return "", "", fmt.Errorf("missing field type in %q", desc)
+	}
+	switch desc[dims] {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z':
+		return desc[:dims+1], desc[dims+1:], nil
+	case 'L':
+		end := strings.IndexByte(desc[dims:], ';')
+		if end < 0 {
+			return "", "", fmt.Errorf("missing ';' in %q", desc)
+		}
+		end += dims
+		if !IsBinaryName(desc[dims+1 : end]) {
+			return "", "", fmt.Errorf("invalid class name %q", desc[dims+1:end])
+		}
+		return desc[:end+1], desc[end+1:], nil
+	}
+	return "", "", fmt.Errorf("invalid field type %q", desc)
+}
+
+func IsFieldDescriptor(desc string) bool {
+	_, rest, err := nextFieldType(desc)
+	return err == nil && rest == ""
+}
+
+// 解析方法描述符, 返回参数类型和返回值类型
+func ParseMethodDescriptor(desc string) ([]string, string, error) {
+	if !strings.HasPrefix(desc, "(") {
+		return nil, "", fmt.Errorf("method descriptor %q must start with '('", desc)
+	}
+	rest := desc[1:]
+	params := make([]string, 0)
+	slots := 0
+	for rest != "" && rest[0] != ')' {
+		var param string
+		var err error
+		param, rest, err = nextFieldType(rest)
+		if err != nil {
+			return nil, "", err
+		}
+		params = append(params, param)
+		slots++
+		if param == "J" || param == "D" {
+			slots++
+		}
+	}
+	if rest == "" {
+		return nil, "", fmt.Errorf("method descriptor %q misses ')'", desc)
+	}
+	if slots > 255 {
+		return nil, "", fmt.Errorf("method descriptor %q has more than 255 parameter slots", desc)
+	}
+	rest = rest[1:]
+	if rest == "V" {
+		return params, rest, nil
+	}
+	ret, rest, err := nextFieldType(rest)
+	if err != nil {
+		return nil, "", err
+	}
+	if rest != "" {
+		return nil, "", fmt.Errorf("unexpected %q after return type", rest)
+	}
+	return params, ret, nil
+}
+
+func IsMethodDescriptor(desc string) bool {
+	_, _, err := ParseMethodDescriptor(desc)
+	return err == nil
+}
+
+// 字段名、局部变量名等非限定名, 不能包含 . ; [ /
+func IsUnqualifiedName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, ".;[/")
+}
+
+// 方法名在非限定名的基础上不能包含 < >, <init>和<clinit>除外
+func IsMethodName(name string) bool {
+	if name == "<init>" || name == "<clinit>" {
+		return true
+	}
+	return IsUnqualifiedName(name) && !strings.ContainsAny(name, "<>")
+}
+
+// 内部形式的二进制名称, 例如java/lang/Object
+func IsBinaryName(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if !IsUnqualifiedName(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// CONSTANT_Class_info中的名称可以是二进制名称或者数组类型的描述符
+func IsClassName(name string) bool {
+	if strings.HasPrefix(name, "[") {
+		return IsFieldDescriptor(name)
+	}
+	return IsBinaryName(name)
+}
+
+func IsModuleName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c < 0x20:
+			return false
+		case c == '\\':
+			if i+1 == len(name) || !strings.ContainsRune(`\:@`, rune(name[i+1])) {
+				return false
+			}
+			i++
+		case c == ':' || c == '@':
+			return false
+		}
+	}
+	return true
+}
+
+// 检查是否是合法的modified UTF-8编码
+func IsModifiedUtf8(value []byte) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == 0 || c >= 0xF0:
+			return false
+		case c < 0x80:
+		case c&0xE0 == 0xC0:
+			if i+1 >= len(value) || value[i+1]&0xC0 != 0x80 {
+				return false
+			}
+			i++
+		case c&0xF0 == 0xE0:
+			if i+2 >= len(value) || value[i+1]&0xC0 != 0x80 || value[i+2]&0xC0 != 0x80 {
+				return false
+			}
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
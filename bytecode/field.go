@@ -3,7 +3,9 @@ package bytecode
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
+
+	"class-file-parser/flags"
+	"class-file-parser/i18n"
 )
 
 const Field_ACC_PUBLIC = 0x0001
@@ -38,27 +40,11 @@ func (f *FieldInfo) Parse(data []byte, index int, constantPool []ConstantPoolInf
 
 func (f *FieldInfo) String(constantPool []ConstantPoolInfo) string {
 	result := ""
-	if Field_ACC_PRIVATE&f.AccessFlags != 0 {
-		result += "private "
-	} else if Field_ACC_PROTECTED&f.AccessFlags != 0 {
-		result += "protected "
-	} else if Field_ACC_PUBLIC&f.AccessFlags != 0 {
-		result += "public "
-	}
-	if Field_ACC_STATIC&f.AccessFlags != 0 {
-		result += "static "
-	}
-	if Field_ACC_FINAL&f.AccessFlags != 0 {
-		result += "final "
-	}
-	if Field_ACC_VOLATILE&f.AccessFlags != 0 {
-		result += "volatile "
-	}
-	if Field_ACC_TRANSIENT&f.AccessFlags != 0 {
-		result += "transient "
+	if modifiers := f.Flags().String(); modifiers != "" {
+		result += modifiers + " "
 	}
 	result += constantPool[f.DescriptorIndex].String(constantPool) + " " + constantPool[f.NameIndex].String(constantPool)
-	result += fmt.Sprintf("\n属性个数: %d\n", f.AttributesCount)
+	result += "\n" + i18n.T("attribute_count", f.AttributesCount) + "\n"
 	for _, attr := range f.Attributes {
 		if attr != nil {
 			result += attr.GetName() + ": " + attr.String(constantPool) + "\n"
@@ -66,3 +52,15 @@ func (f *FieldInfo) String(constantPool []ConstantPoolInfo) string {
 	}
 	return result
 }
+
+func (f *FieldInfo) Flags() flags.FieldFlags {
+	return flags.FieldFlags(f.AccessFlags)
+}
+
+func (f *FieldInfo) Name(constantPool []ConstantPoolInfo) string {
+	return constantUtf8(constantPool, f.NameIndex)
+}
+
+func (f *FieldInfo) Descriptor(constantPool []ConstantPoolInfo) string {
+	return constantUtf8(constantPool, f.DescriptorIndex)
+}
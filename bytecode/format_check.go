@@ -0,0 +1,374 @@
+package bytecode
+
+import (
+	"fmt"
+)
+
+type Severity int
+
+const (
+	SEVERITY_INFO Severity = iota
+	SEVERITY_WARNING
+	SEVERITY_ERROR
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SEVERITY_INFO:
+		return "info"
+	case SEVERITY_WARNING:
+		return "warning"
+	}
+	return "error"
+}
+
+type Finding struct {
+	Severity Severity
+	Rule     string
+	Location string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s [%s] %s: %s", f.Severity, f.Rule, f.Location, f.Message)
+}
+
+const CLASS_ACC_MASK = ACC_PUBLIC | ACC_FINAL | ACC_SUPER | ACC_INTERFACE | ACC_ABSTRACT | ACC_SYNTHETIC | ACC_ANNOTATION | ACC_ENUM | ACC_MODULE
+const FIELD_ACC_MASK = Field_ACC_PUBLIC | Field_ACC_PRIVATE | Field_ACC_PROTECTED | Field_ACC_STATIC | Field_ACC_FINAL | Field_ACC_VOLATILE | Field_ACC_TRANSIENT | Field_ACC_SYNTHETIC | Field_ACC_ENUM
+const METHOD_ACC_MASK = METHOD_ACC_PUBLIC | METHOD_ACC_PRIVATE | METHOD_ACC_PROTECTED | METHOD_ACC_STATIC | METHOD_ACC_FINAL | METHOD_ACC_SYNCHRONIZED | METHOD_ACC_BRIDGE | METHOD_ACC_VARARGS | METHOD_ACC_NATIVE | METHOD_ACC_ABSTRACT | METHOD_ACC_STRICT | METHOD_ACC_SYNTHETIC
+
+const (
+	ownerClass = iota
+	ownerField
+	ownerMethod
+	ownerCode
+	ownerRecordComponent
+)
+
+// 属性允许出现的位置以及最低的类文件版本, 参见JVMS 4.7表4.7-A和4.7-C
+var attributeRules = map[string]struct {
+	owners   []int
+	major    uint16
+	multiple bool
+}{
+	"ConstantValue":                        {[]int{ownerField}, 45, false},
+	"Code":                                 {[]int{ownerMethod}, 45, false},
+	"StackMapTable":                        {[]int{ownerCode}, 50, false},
+	"Exceptions":                           {[]int{ownerMethod}, 45, false},
+	"InnerClasses":                         {[]int{ownerClass}, 45, false},
+	"EnclosingMethod":                      {[]int{ownerClass}, 49, false},
+	"Synthetic":                            {[]int{ownerClass, ownerField, ownerMethod}, 45, false},
+	"Signature":                            {[]int{ownerClass, ownerField, ownerMethod, ownerRecordComponent}, 49, false},
+	"SourceFile":                           {[]int{ownerClass}, 45, false},
+	"SourceDebugExtension":                 {[]int{ownerClass}, 49, false},
+	"LineNumberTable":                      {[]int{ownerCode}, 45, true},
+	"LocalVariableTable":                   {[]int{ownerCode}, 45, true},
+	"LocalVariableTypeTable":               {[]int{ownerCode}, 49, true},
+	"Deprecated":                           {[]int{ownerClass, ownerField, ownerMethod}, 45, false},
+	"RuntimeVisibleAnnotations":            {[]int{ownerClass, ownerField, ownerMethod, ownerRecordComponent}, 49, false},
+	"RuntimeInvisibleAnnotations":          {[]int{ownerClass, ownerField, ownerMethod, ownerRecordComponent}, 49, false},
+	"RuntimeVisibleParameterAnnotations":   {[]int{ownerMethod}, 49, false},
+	"RuntimeInvisibleParameterAnnotations": {[]int{ownerMethod}, 49, false},
+	"RuntimeVisibleTypeAnnotations":        {[]int{ownerClass, ownerField, ownerMethod, ownerCode, ownerRecordComponent}, 52, false},
+	"RuntimeInvisibleTypeAnnotations":      {[]int{ownerClass, ownerField, ownerMethod, ownerCode, ownerRecordComponent}, 52, false},
+	"AnnotationDefault":                    {[]int{ownerMethod}, 49, false},
+	"BootstrapMethods":                     {[]int{ownerClass}, 51, false},
+	"MethodParameters":                     {[]int{ownerMethod}, 52, false},
+	"Module":                               {[]int{ownerClass}, 53, false},
+	"ModulePackages":                       {[]int{ownerClass}, 53, false},
+	"ModuleMainClass":                      {[]int{ownerClass}, 53, false},
+	"NestHost":                             {[]int{ownerClass}, 55, false},
+	"NestMembers":                          {[]int{ownerClass}, 55, false},
+	"Record":                               {[]int{ownerClass}, 60, false},
+	"PermittedSubclasses":                  {[]int{ownerClass}, 61, false},
+}
+
+var ownerNames = []string{"class", "field", "method", "Code attribute", "record component"}
+
+type formatChecker struct {
+	f        *ClassFile
+	findings []Finding
+}
+
+func (c *formatChecker) add(severity Severity, rule, location, format string, args ...interface{}) {
+	c.findings = append(c.findings, Finding{Severity: severity, Rule: rule, Location: location, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *formatChecker) name(index uint16) string {
+	return constantUtf8(c.f.ConstantPool, index)
+}
+
+// 按照JVMS 4.1、4.5、4.6和4.7检查类文件的结构约束
+func (f *ClassFile) CheckFormat() []Finding {
+	c := &formatChecker{f: f}
+	c.classFlags()
+	c.fields()
+	c.methods()
+	c.attributes("class", ownerClass, f.Attributes)
+	c.classAttributes()
+	return c.findings
+}
+
+func (c *formatChecker) classFlags() {
+	f := c.f
+	flags := f.AccessFlags
+	if f.MajorVersion < 45 || f.MajorVersion > MAX_MAJOR_VERSION {
+		c.add(SEVERITY_ERROR, "class-version", "class", "unsupported class file version %d.%d", f.MajorVersion, f.MinorVersion)
+	} else if f.MajorVersion >= 56 && f.MinorVersion != 0 && f.MinorVersion != 65535 {
+		c.add(SEVERITY_ERROR, "class-version", "class", "minor version of class file version %d must be 0 or 65535, but is %d", f.MajorVersion, f.MinorVersion)
+	}
+	if flags&^uint16(CLASS_ACC_MASK) != 0 {
+		c.add(SEVERITY_WARNING, "access-flags", "class", "undefined access flags 0x%04X", flags&^uint16(CLASS_ACC_MASK))
+	}
+	thisClass := f.getClassName(f.ThisClass)
+	if flags&ACC_MODULE != 0 {
+		if f.MajorVersion < 53 {
+			c.add(SEVERITY_ERROR, "access-flags", "class", "ACC_MODULE requires class file version 53.0")
+		}
+		if flags != ACC_MODULE {
+			c.add(SEVERITY_ERROR, "access-flags", "class", "no other flag may be set together with ACC_MODULE")
+		}
+		if thisClass != "module-info" {
+			c.add(SEVERITY_ERROR, "module", "class", "module class must be named module-info, but is %s", thisClass)
+		}
+		if f.SuperClass != 0 || len(f.Interfaces) > 0 || len(f.Fields) > 0 || len(f.Methods) > 0 {
+			c.add(SEVERITY_ERROR, "module", "class", "module-info must not have a super class, interfaces, fields or methods")
+		}
+		return
+	}
+	if flags&ACC_INTERFACE != 0 {
+		if flags&ACC_ABSTRACT == 0 {
+			c.add(SEVERITY_ERROR, "access-flags", "class", "interface must be ACC_ABSTRACT")
+		}
+		if flags&(ACC_FINAL|ACC_SUPER|ACC_ENUM) != 0 {
+			c.add(SEVERITY_ERROR, "access-flags", "class", "interface must not be ACC_FINAL, ACC_SUPER or ACC_ENUM")
+		}
+		if f.getClassName(f.SuperClass) != "java/lang/Object" {
+			c.add(SEVERITY_ERROR, "super-class", "class", "super class of an interface must be java/lang/Object")
+		}
+	} else {
+		if flags&ACC_ANNOTATION != 0 {
+			c.add(SEVERITY_ERROR, "access-flags", "class", "ACC_ANNOTATION requires ACC_INTERFACE")
+		}
+		if flags&ACC_FINAL != 0 && flags&ACC_ABSTRACT != 0 {
+			c.add(SEVERITY_ERROR, "access-flags", "class", "class cannot be both ACC_FINAL and ACC_ABSTRACT")
+		}
+	}
+	if f.SuperClass == 0 && thisClass != "java/lang/Object" {
+		c.add(SEVERITY_ERROR, "super-class", "class", "only java/lang/Object may have no super class")
+	}
+}
+
+func visibilityCount(flags uint16) int {
+	count := 0
+	for _, flag := range []uint16{METHOD_ACC_PUBLIC, METHOD_ACC_PRIVATE, METHOD_ACC_PROTECTED} {
+		if flags&flag != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func (c *formatChecker) fields() {
+	isInterface := c.f.AccessFlags&ACC_INTERFACE != 0
+	seen := make(map[string]bool)
+	for _, field := range c.f.Fields {
+		name, desc := c.name(field.NameIndex), c.name(field.DescriptorIndex)
+		location := "field " + name + ":" + desc
+		flags := field.AccessFlags
+		if !IsUnqualifiedName(name) {
+			c.add(SEVERITY_ERROR, "member-name", location, "invalid field name %q", name)
+		}
+		if !IsFieldDescriptor(desc) {
+			c.add(SEVERITY_ERROR, "member-descriptor", location, "invalid field descriptor %q", desc)
+		}
+		if seen[name+":"+desc] {
+			c.add(SEVERITY_ERROR, "duplicate-member", location, "duplicate field")
+		}
+		seen[name+":"+desc] = true
+		if flags&^uint16(FIELD_ACC_MASK) != 0 {
+			c.add(SEVERITY_WARNING, "access-flags", location, "undefined access flags 0x%04X", flags&^uint16(FIELD_ACC_MASK))
+		}
+		if visibilityCount(flags) > 1 {
+			c.add(SEVERITY_ERROR, "access-flags", location, "at most one of ACC_PUBLIC, ACC_PRIVATE and ACC_PROTECTED may be set")
+		}
+		if flags&Field_ACC_FINAL != 0 && flags&Field_ACC_VOLATILE != 0 {
+			c.add(SEVERITY_ERROR, "access-flags", location, "field cannot be both ACC_FINAL and ACC_VOLATILE")
+		}
+		if isInterface {
+			required := uint16(Field_ACC_PUBLIC | Field_ACC_STATIC | Field_ACC_FINAL)
+			if flags&required != required || flags&^(required|Field_ACC_SYNTHETIC) != 0 {
+				c.add(SEVERITY_ERROR, "access-flags", location, "interface field must be exactly public static final, optionally synthetic")
+			}
+		}
+		c.attributes(location, ownerField, field.Attributes)
+		for _, attr := range field.Attributes {
+			if _, ok := attr.(*ConstantValue); ok && flags&Field_ACC_STATIC == 0 {
+				c.add(SEVERITY_WARNING, "attribute-placement", location, "ConstantValue on a non-static field is ignored")
+			}
+		}
+	}
+}
+
+func (c *formatChecker) methods() {
+	f := c.f
+	isInterface := f.AccessFlags&ACC_INTERFACE != 0
+	seen := make(map[string]bool)
+	for _, method := range f.Methods {
+		name, desc := c.name(method.NameIndex), c.name(method.DescriptorIndex)
+		location := "method " + name + desc
+		flags := method.AccessFlags
+		if !IsMethodName(name) {
+			c.add(SEVERITY_ERROR, "member-name", location, "invalid method name %q", name)
+		}
+		_, ret, err := ParseMethodDescriptor(desc)
+		if err != nil {
+			c.add(SEVERITY_ERROR, "member-descriptor", location, "invalid method descriptor: %s", err.Error())
+		}
+		if seen[name+desc] {
+			c.add(SEVERITY_ERROR, "duplicate-member", location, "duplicate method")
+		}
+		seen[name+desc] = true
+		if flags&^uint16(METHOD_ACC_MASK) != 0 {
+			c.add(SEVERITY_WARNING, "access-flags", location, "undefined access flags 0x%04X", flags&^uint16(METHOD_ACC_MASK))
+		}
+		if visibilityCount(flags) > 1 {
+			c.add(SEVERITY_ERROR, "access-flags", location, "at most one of ACC_PUBLIC, ACC_PRIVATE and ACC_PROTECTED may be set")
+		}
+
+		switch name {
+		case "<clinit>":
+			if desc != "()V" {
+				c.add(SEVERITY_ERROR, "initializer", location, "<clinit> must have descriptor ()V")
+			}
+			if f.MajorVersion >= 51 && flags&METHOD_ACC_STATIC == 0 {
+				c.add(SEVERITY_ERROR, "initializer", location, "<clinit> must be ACC_STATIC since class file version 51.0")
+			}
+		case "<init>":
+			if isInterface {
+				c.add(SEVERITY_ERROR, "initializer", location, "interface cannot declare <init>")
+			}
+			if err == nil && ret != "V" {
+				c.add(SEVERITY_ERROR, "initializer", location, "<init> must return void")
+			}
+			allowed := uint16(METHOD_ACC_PUBLIC | METHOD_ACC_PRIVATE | METHOD_ACC_PROTECTED | METHOD_ACC_VARARGS | METHOD_ACC_STRICT | METHOD_ACC_SYNTHETIC)
+			if flags&^allowed != 0 {
+				c.add(SEVERITY_ERROR, "initializer", location, "<init> may only be public, private, protected, varargs, strict or synthetic")
+			}
+		default:
+			c.methodFlags(location, flags, isInterface)
+		}
+
+		c.attributes(location, ownerMethod, method.Attributes)
+		hasCode := false
+		for _, attr := range method.Attributes {
+			if _, ok := attr.(*Code); ok {
+				hasCode = true
+			}
+		}
+		needsCode := flags&(METHOD_ACC_ABSTRACT|METHOD_ACC_NATIVE) == 0
+		if name == "<clinit>" && f.MajorVersion >= 51 {
+			needsCode = true
+		}
+		if needsCode && !hasCode {
+			c.add(SEVERITY_ERROR, "attribute-placement", location, "method must have a Code attribute")
+		} else if !needsCode && hasCode {
+			c.add(SEVERITY_ERROR, "attribute-placement", location, "abstract or native method must not have a Code attribute")
+		}
+	}
+}
+
+func (c *formatChecker) methodFlags(location string, flags uint16, isInterface bool) {
+	major := c.f.MajorVersion
+	if isInterface {
+		if major < 52 {
+			if flags&(METHOD_ACC_PUBLIC|METHOD_ACC_ABSTRACT) != METHOD_ACC_PUBLIC|METHOD_ACC_ABSTRACT {
+				c.add(SEVERITY_ERROR, "access-flags", location, "interface method must be public abstract before class file version 52.0")
+			}
+		} else {
+			if flags&(METHOD_ACC_PROTECTED|METHOD_ACC_FINAL|METHOD_ACC_SYNCHRONIZED|METHOD_ACC_NATIVE) != 0 {
+				c.add(SEVERITY_ERROR, "access-flags", location, "interface method must not be protected, final, synchronized or native")
+			}
+			if visibilityCount(flags&(METHOD_ACC_PUBLIC|METHOD_ACC_PRIVATE)) != 1 {
+				c.add(SEVERITY_ERROR, "access-flags", location, "interface method must be exactly one of public and private")
+			}
+		}
+	}
+	if flags&METHOD_ACC_ABSTRACT != 0 {
+		forbidden := uint16(METHOD_ACC_PRIVATE | METHOD_ACC_STATIC | METHOD_ACC_FINAL | METHOD_ACC_SYNCHRONIZED | METHOD_ACC_NATIVE)
+		if major >= 46 && major < 61 {
+			forbidden |= METHOD_ACC_STRICT
+		}
+		if flags&forbidden != 0 {
+			c.add(SEVERITY_ERROR, "access-flags", location, "abstract method must not be private, static, final, synchronized, native or strict")
+		}
+	}
+}
+
+func (c *formatChecker) attributes(location string, owner int, attrs []AttributeInfo) {
+	major := c.f.MajorVersion
+	count := make(map[string]int)
+	for _, attr := range attrs {
+		if attr == nil {
+			continue
+		}
+		name := attr.GetName()
+		count[name]++
+		rule, ok := attributeRules[name]
+		if !ok {
+			continue
+		}
+		allowed := false
+		for _, o := range rule.owners {
+			allowed = allowed || o == owner
+		}
+		if !allowed {
+			c.add(SEVERITY_ERROR, "attribute-placement", location, "%s attribute is not allowed on a %s", name, ownerNames[owner])
+		}
+		if major < rule.major {
+			c.add(SEVERITY_WARNING, "attribute-version", location, "%s attribute requires class file version %d.0 and is ignored in %d.%d", name, rule.major, major, c.f.MinorVersion)
+		}
+		if count[name] == 2 && !rule.multiple {
+			c.add(SEVERITY_ERROR, "duplicate-attribute", location, "more than one %s attribute", name)
+		}
+		switch a := attr.(type) {
+		case *Code:
+			c.attributes(location+" Code", ownerCode, a.Attributes)
+		case *Record:
+			for _, component := range a.RecordComponentInfo {
+				c.attributes(location+" component "+c.name(component.NameIndex), ownerRecordComponent, component.Attributes)
+			}
+		}
+	}
+}
+
+func (c *formatChecker) classAttributes() {
+	f := c.f
+	var nestHost, nestMembers, record, permitted AttributeInfo
+	for _, attr := range f.Attributes {
+		switch attr.(type) {
+		case *NestHost:
+			nestHost = attr
+		case *NestMembers:
+			nestMembers = attr
+		case *Record:
+			record = attr
+		case *PermittedSubclasses:
+			permitted = attr
+		}
+	}
+	if nestHost != nil && nestMembers != nil {
+		c.add(SEVERITY_ERROR, "nest", "class", "class cannot have both NestHost and NestMembers attributes")
+	}
+	if record != nil {
+		if f.AccessFlags&ACC_FINAL == 0 {
+			c.add(SEVERITY_WARNING, "record", "class", "record class should be final")
+		}
+		if superClass := f.getClassName(f.SuperClass); superClass != "java/lang/Record" {
+			c.add(SEVERITY_WARNING, "record", "class", "record class should extend java/lang/Record, but extends %s", superClass)
+		}
+	}
+	if permitted != nil && f.AccessFlags&ACC_FINAL != 0 {
+		c.add(SEVERITY_ERROR, "sealed", "class", "final class cannot have a PermittedSubclasses attribute")
+	}
+}
@@ -0,0 +1,889 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+type operandFormat int
+
+const (
+	operandNone operandFormat = iota
+	operandLocal
+	operandByte
+	operandShort
+	operandConstantU1
+	operandConstant
+	operandInvokeInterface
+	operandInvokeDynamic
+	operandMultiANewArray
+	operandIinc
+	operandNewArray
+	operandBranch
+	operandBranchWide
+	operandTableSwitch
+	operandLookupSwitch
+	operandWide
+)
+
+type opcode struct {
+	name   string
+	format operandFormat
+}
+
+const (
+	OPCODE_NOP             = 0x00
+	OPCODE_ACONST_NULL     = 0x01
+	OPCODE_ICONST_M1       = 0x02
+	OPCODE_ICONST_0        = 0x03
+	OPCODE_ICONST_1        = 0x04
+	OPCODE_ICONST_2        = 0x05
+	OPCODE_ICONST_3        = 0x06
+	OPCODE_ICONST_4        = 0x07
+	OPCODE_ICONST_5        = 0x08
+	OPCODE_LCONST_0        = 0x09
+	OPCODE_LCONST_1        = 0x0A
+	OPCODE_FCONST_0        = 0x0B
+	OPCODE_FCONST_1        = 0x0C
+	OPCODE_FCONST_2        = 0x0D
+	OPCODE_DCONST_0        = 0x0E
+	OPCODE_DCONST_1        = 0x0F
+	OPCODE_BIPUSH          = 0x10
+	OPCODE_SIPUSH          = 0x11
+	OPCODE_LDC             = 0x12
+	OPCODE_LDC_W           = 0x13
+	OPCODE_LDC2_W          = 0x14
+	OPCODE_ILOAD           = 0x15
+	OPCODE_LLOAD           = 0x16
+	OPCODE_FLOAD           = 0x17
+	OPCODE_DLOAD           = 0x18
+	OPCODE_ALOAD           = 0x19
+	OPCODE_ILOAD_0         = 0x1A
+	OPCODE_ILOAD_1         = 0x1B
+	OPCODE_ILOAD_2         = 0x1C
+	OPCODE_ILOAD_3         = 0x1D
+	OPCODE_LLOAD_0         = 0x1E
+	OPCODE_LLOAD_1         = 0x1F
+	OPCODE_LLOAD_2         = 0x20
+	OPCODE_LLOAD_3         = 0x21
+	OPCODE_FLOAD_0         = 0x22
+	OPCODE_FLOAD_1         = 0x23
+	OPCODE_FLOAD_2         = 0x24
+	OPCODE_FLOAD_3         = 0x25
+	OPCODE_DLOAD_0         = 0x26
+	OPCODE_DLOAD_1         = 0x27
+	OPCODE_DLOAD_2         = 0x28
+	OPCODE_DLOAD_3         = 0x29
+	OPCODE_ALOAD_0         = 0x2A
+	OPCODE_ALOAD_1         = 0x2B
+	OPCODE_ALOAD_2         = 0x2C
+	OPCODE_ALOAD_3         = 0x2D
+	OPCODE_IALOAD          = 0x2E
+	OPCODE_LALOAD          = 0x2F
+	OPCODE_FALOAD          = 0x30
+	OPCODE_DALOAD          = 0x31
+	OPCODE_AALOAD          = 0x32
+	OPCODE_BALOAD          = 0x33
+	OPCODE_CALOAD          = 0x34
+	OPCODE_SALOAD          = 0x35
+	OPCODE_ISTORE          = 0x36
+	OPCODE_LSTORE          = 0x37
+	OPCODE_FSTORE          = 0x38
+	OPCODE_DSTORE          = 0x39
+	OPCODE_ASTORE          = 0x3A
+	OPCODE_ISTORE_0        = 0x3B
+	OPCODE_ISTORE_1        = 0x3C
+	OPCODE_ISTORE_2        = 0x3D
+	OPCODE_ISTORE_3        = 0x3E
+	OPCODE_LSTORE_0        = 0x3F
+	OPCODE_LSTORE_1        = 0x40
+	OPCODE_LSTORE_2        = 0x41
+	OPCODE_LSTORE_3        = 0x42
+	OPCODE_FSTORE_0        = 0x43
+	OPCODE_FSTORE_1        = 0x44
+	OPCODE_FSTORE_2        = 0x45
+	OPCODE_FSTORE_3        = 0x46
+	OPCODE_DSTORE_0        = 0x47
+	OPCODE_DSTORE_1        = 0x48
+	OPCODE_DSTORE_2        = 0x49
+	OPCODE_DSTORE_3        = 0x4A
+	OPCODE_ASTORE_0        = 0x4B
+	OPCODE_ASTORE_1        = 0x4C
+	OPCODE_ASTORE_2        = 0x4D
+	OPCODE_ASTORE_3        = 0x4E
+	OPCODE_IASTORE         = 0x4F
+	OPCODE_LASTORE         = 0x50
+	OPCODE_FASTORE         = 0x51
+	OPCODE_DASTORE         = 0x52
+	OPCODE_AASTORE         = 0x53
+	OPCODE_BASTORE         = 0x54
+	OPCODE_CASTORE         = 0x55
+	OPCODE_SASTORE         = 0x56
+	OPCODE_POP             = 0x57
+	OPCODE_POP2            = 0x58
+	OPCODE_DUP             = 0x59
+	OPCODE_DUP_X1          = 0x5A
+	OPCODE_DUP_X2          = 0x5B
+	OPCODE_DUP2            = 0x5C
+	OPCODE_DUP2_X1         = 0x5D
+	OPCODE_DUP2_X2         = 0x5E
+	OPCODE_SWAP            = 0x5F
+	OPCODE_IADD            = 0x60
+	OPCODE_LADD            = 0x61
+	OPCODE_FADD            = 0x62
+	OPCODE_DADD            = 0x63
+	OPCODE_ISUB            = 0x64
+	OPCODE_LSUB            = 0x65
+	OPCODE_FSUB            = 0x66
+	OPCODE_DSUB            = 0x67
+	OPCODE_IMUL            = 0x68
+	OPCODE_LMUL            = 0x69
+	OPCODE_FMUL            = 0x6A
+	OPCODE_DMUL            = 0x6B
+	OPCODE_IDIV            = 0x6C
+	OPCODE_LDIV            = 0x6D
+	OPCODE_FDIV            = 0x6E
+	OPCODE_DDIV            = 0x6F
+	OPCODE_IREM            = 0x70
+	OPCODE_LREM            = 0x71
+	OPCODE_FREM            = 0x72
+	OPCODE_DREM            = 0x73
+	OPCODE_INEG            = 0x74
+	OPCODE_LNEG            = 0x75
+	OPCODE_FNEG            = 0x76
+	OPCODE_DNEG            = 0x77
+	OPCODE_ISHL            = 0x78
+	OPCODE_LSHL            = 0x79
+	OPCODE_ISHR            = 0x7A
+	OPCODE_LSHR            = 0x7B
+	OPCODE_IUSHR           = 0x7C
+	OPCODE_LUSHR           = 0x7D
+	OPCODE_IAND            = 0x7E
+	OPCODE_LAND            = 0x7F
+	OPCODE_IOR             = 0x80
+	OPCODE_LOR             = 0x81
+	OPCODE_IXOR            = 0x82
+	OPCODE_LXOR            = 0x83
+	OPCODE_IINC            = 0x84
+	OPCODE_I2L             = 0x85
+	OPCODE_I2F             = 0x86
+	OPCODE_I2D             = 0x87
+	OPCODE_L2I             = 0x88
+	OPCODE_L2F             = 0x89
+	OPCODE_L2D             = 0x8A
+	OPCODE_F2I             = 0x8B
+	OPCODE_F2L             = 0x8C
+	OPCODE_F2D             = 0x8D
+	OPCODE_D2I             = 0x8E
+	OPCODE_D2L             = 0x8F
+	OPCODE_D2F             = 0x90
+	OPCODE_I2B             = 0x91
+	OPCODE_I2C             = 0x92
+	OPCODE_I2S             = 0x93
+	OPCODE_LCMP            = 0x94
+	OPCODE_FCMPL           = 0x95
+	OPCODE_FCMPG           = 0x96
+	OPCODE_DCMPL           = 0x97
+	OPCODE_DCMPG           = 0x98
+	OPCODE_IFEQ            = 0x99
+	OPCODE_IFNE            = 0x9A
+	OPCODE_IFLT            = 0x9B
+	OPCODE_IFGE            = 0x9C
+	OPCODE_IFGT            = 0x9D
+	OPCODE_IFLE            = 0x9E
+	OPCODE_IF_ICMPEQ       = 0x9F
+	OPCODE_IF_ICMPNE       = 0xA0
+	OPCODE_IF_ICMPLT       = 0xA1
+	OPCODE_IF_ICMPGE       = 0xA2
+	OPCODE_IF_ICMPGT       = 0xA3
+	OPCODE_IF_ICMPLE       = 0xA4
+	OPCODE_IF_ACMPEQ       = 0xA5
+	OPCODE_IF_ACMPNE       = 0xA6
+	OPCODE_GOTO            = 0xA7
+	OPCODE_JSR             = 0xA8
+	OPCODE_RET             = 0xA9
+	OPCODE_TABLESWITCH     = 0xAA
+	OPCODE_LOOKUPSWITCH    = 0xAB
+	OPCODE_IRETURN         = 0xAC
+	OPCODE_LRETURN         = 0xAD
+	OPCODE_FRETURN         = 0xAE
+	OPCODE_DRETURN         = 0xAF
+	OPCODE_ARETURN         = 0xB0
+	OPCODE_RETURN          = 0xB1
+	OPCODE_GETSTATIC       = 0xB2
+	OPCODE_PUTSTATIC       = 0xB3
+	OPCODE_GETFIELD        = 0xB4
+	OPCODE_PUTFIELD        = 0xB5
+	OPCODE_INVOKEVIRTUAL   = 0xB6
+	OPCODE_INVOKESPECIAL   = 0xB7
+	OPCODE_INVOKESTATIC    = 0xB8
+	OPCODE_INVOKEINTERFACE = 0xB9
+	OPCODE_INVOKEDYNAMIC   = 0xBA
+	OPCODE_NEW             = 0xBB
+	OPCODE_NEWARRAY        = 0xBC
+	OPCODE_ANEWARRAY       = 0xBD
+	OPCODE_ARRAYLENGTH     = 0xBE
+	OPCODE_ATHROW          = 0xBF
+	OPCODE_CHECKCAST       = 0xC0
+	OPCODE_INSTANCEOF      = 0xC1
+	OPCODE_MONITORENTER    = 0xC2
+	OPCODE_MONITOREXIT     = 0xC3
+	OPCODE_WIDE            = 0xC4
+	OPCODE_MULTIANEWARRAY  = 0xC5
+	OPCODE_IFNULL          = 0xC6
+	OPCODE_IFNONNULL       = 0xC7
+	OPCODE_GOTO_W          = 0xC8
+	OPCODE_JSR_W           = 0xC9
+	OPCODE_BREAKPOINT      = 0xCA
+	OPCODE_IMPDEP1         = 0xFE
+	OPCODE_IMPDEP2         = 0xFF
+)
+
+var opcodes = map[uint8]opcode{
+	OPCODE_NOP:             {"nop", operandNone},
+	OPCODE_ACONST_NULL:     {"aconst_null", operandNone},
+	OPCODE_ICONST_M1:       {"iconst_m1", operandNone},
+	OPCODE_ICONST_0:        {"iconst_0", operandNone},
+	OPCODE_ICONST_1:        {"iconst_1", operandNone},
+	OPCODE_ICONST_2:        {"iconst_2", operandNone},
+	OPCODE_ICONST_3:        {"iconst_3", operandNone},
+	OPCODE_ICONST_4:        {"iconst_4", operandNone},
+	OPCODE_ICONST_5:        {"iconst_5", operandNone},
+	OPCODE_LCONST_0:        {"lconst_0", operandNone},
+	OPCODE_LCONST_1:        {"lconst_1", operandNone},
+	OPCODE_FCONST_0:        {"fconst_0", operandNone},
+	OPCODE_FCONST_1:        {"fconst_1", operandNone},
+	OPCODE_FCONST_2:        {"fconst_2", operandNone},
+	OPCODE_DCONST_0:        {"dconst_0", operandNone},
+	OPCODE_DCONST_1:        {"dconst_1", operandNone},
+	OPCODE_BIPUSH:          {"bipush", operandByte},
+	OPCODE_SIPUSH:          {"sipush", operandShort},
+	OPCODE_LDC:             {"ldc", operandConstantU1},
+	OPCODE_LDC_W:           {"ldc_w", operandConstant},
+	OPCODE_LDC2_W:          {"ldc2_w", operandConstant},
+	OPCODE_ILOAD:           {"iload", operandLocal},
+	OPCODE_LLOAD:           {"lload", operandLocal},
+	OPCODE_FLOAD:           {"fload", operandLocal},
+	OPCODE_DLOAD:           {"dload", operandLocal},
+	OPCODE_ALOAD:           {"aload", operandLocal},
+	OPCODE_ILOAD_0:         {"iload_0", operandNone},
+	OPCODE_ILOAD_1:         {"iload_1", operandNone},
+	OPCODE_ILOAD_2:         {"iload_2", operandNone},
+	OPCODE_ILOAD_3:         {"iload_3", operandNone},
+	OPCODE_LLOAD_0:         {"lload_0", operandNone},
+	OPCODE_LLOAD_1:         {"lload_1", operandNone},
+	OPCODE_LLOAD_2:         {"lload_2", operandNone},
+	OPCODE_LLOAD_3:         {"lload_3", operandNone},
+	OPCODE_FLOAD_0:         {"fload_0", operandNone},
+	OPCODE_FLOAD_1:         {"fload_1", operandNone},
+	OPCODE_FLOAD_2:         {"fload_2", operandNone},
+	OPCODE_FLOAD_3:         {"fload_3", operandNone},
+	OPCODE_DLOAD_0:         {"dload_0", operandNone},
+	OPCODE_DLOAD_1:         {"dload_1", operandNone},
+	OPCODE_DLOAD_2:         {"dload_2", operandNone},
+	OPCODE_DLOAD_3:         {"dload_3", operandNone},
+	OPCODE_ALOAD_0:         {"aload_0", operandNone},
+	OPCODE_ALOAD_1:         {"aload_1", operandNone},
+	OPCODE_ALOAD_2:         {"aload_2", operandNone},
+	OPCODE_ALOAD_3:         {"aload_3", operandNone},
+	OPCODE_IALOAD:          {"iaload", operandNone},
+	OPCODE_LALOAD:          {"laload", operandNone},
+	OPCODE_FALOAD:          {"faload", operandNone},
+	OPCODE_DALOAD:          {"daload", operandNone},
+	OPCODE_AALOAD:          {"aaload", operandNone},
+	OPCODE_BALOAD:          {"baload", operandNone},
+	OPCODE_CALOAD:          {"caload", operandNone},
+	OPCODE_SALOAD:          {"saload", operandNone},
+	OPCODE_ISTORE:          {"istore", operandLocal},
+	OPCODE_LSTORE:          {"lstore", operandLocal},
+	OPCODE_FSTORE:          {"fstore", operandLocal},
+	OPCODE_DSTORE:          {"dstore", operandLocal},
+	OPCODE_ASTORE:          {"astore", operandLocal},
+	OPCODE_ISTORE_0:        {"istore_0", operandNone},
+	OPCODE_ISTORE_1:        {"istore_1", operandNone},
+	OPCODE_ISTORE_2:        {"istore_2", operandNone},
+	OPCODE_ISTORE_3:        {"istore_3", operandNone},
+	OPCODE_LSTORE_0:        {"lstore_0", operandNone},
+	OPCODE_LSTORE_1:        {"lstore_1", operandNone},
+	OPCODE_LSTORE_2:        {"lstore_2", operandNone},
+	OPCODE_LSTORE_3:        {"lstore_3", operandNone},
+	OPCODE_FSTORE_0:        {"fstore_0", operandNone},
+	OPCODE_FSTORE_1:        {"fstore_1", operandNone},
+	OPCODE_FSTORE_2:        {"fstore_2", operandNone},
+	OPCODE_FSTORE_3:        {"fstore_3", operandNone},
+	OPCODE_DSTORE_0:        {"dstore_0", operandNone},
+	OPCODE_DSTORE_1:        {"dstore_1", operandNone},
+	OPCODE_DSTORE_2:        {"dstore_2", operandNone},
+	OPCODE_DSTORE_3:        {"dstore_3", operandNone},
+	OPCODE_ASTORE_0:        {"astore_0", operandNone},
+	OPCODE_ASTORE_1:        {"astore_1", operandNone},
+	OPCODE_ASTORE_2:        {"astore_2", operandNone},
+	OPCODE_ASTORE_3:        {"astore_3", operandNone},
+	OPCODE_IASTORE:         {"iastore", operandNone},
+	OPCODE_LASTORE:         {"lastore", operandNone},
+	OPCODE_FASTORE:         {"fastore", operandNone},
+	OPCODE_DASTORE:         {"dastore", operandNone},
+	OPCODE_AASTORE:         {"aastore", operandNone},
+	OPCODE_BASTORE:         {"bastore", operandNone},
+	OPCODE_CASTORE:         {"castore", operandNone},
+	OPCODE_SASTORE:         {"sastore", operandNone},
+	OPCODE_POP:             {"pop", operandNone},
+	OPCODE_POP2:            {"pop2", operandNone},
+	OPCODE_DUP:             {"dup", operandNone},
+	OPCODE_DUP_X1:          {"dup_x1", operandNone},
+	OPCODE_DUP_X2:          {"dup_x2", operandNone},
+	OPCODE_DUP2:            {"dup2", operandNone},
+	OPCODE_DUP2_X1:         {"dup2_x1", operandNone},
+	OPCODE_DUP2_X2:         {"dup2_x2", operandNone},
+	OPCODE_SWAP:            {"swap", operandNone},
+	OPCODE_IADD:            {"iadd", operandNone},
+	OPCODE_LADD:            {"ladd", operandNone},
+	OPCODE_FADD:            {"fadd", operandNone},
+	OPCODE_DADD:            {"dadd", operandNone},
+	OPCODE_ISUB:            {"isub", operandNone},
+	OPCODE_LSUB:            {"lsub", operandNone},
+	OPCODE_FSUB:            {"fsub", operandNone},
+	OPCODE_DSUB:            {"dsub", operandNone},
+	OPCODE_IMUL:            {"imul", operandNone},
+	OPCODE_LMUL:            {"lmul", operandNone},
+	OPCODE_FMUL:            {"fmul", operandNone},
+	OPCODE_DMUL:            {"dmul", operandNone},
+	OPCODE_IDIV:            {"idiv", operandNone},
+	OPCODE_LDIV:            {"ldiv", operandNone},
+	OPCODE_FDIV:            {"fdiv", operandNone},
+	OPCODE_DDIV:            {"ddiv", operandNone},
+	OPCODE_IREM:            {"irem", operandNone},
+	OPCODE_LREM:            {"lrem", operandNone},
+	OPCODE_FREM:            {"frem", operandNone},
+	OPCODE_DREM:            {"drem", operandNone},
+	OPCODE_INEG:            {"ineg", operandNone},
+	OPCODE_LNEG:            {"lneg", operandNone},
+	OPCODE_FNEG:            {"fneg", operandNone},
+	OPCODE_DNEG:            {"dneg", operandNone},
+	OPCODE_ISHL:            {"ishl", operandNone},
+	OPCODE_LSHL:            {"lshl", operandNone},
+	OPCODE_ISHR:            {"ishr", operandNone},
+	OPCODE_LSHR:            {"lshr", operandNone},
+	OPCODE_IUSHR:           {"iushr", operandNone},
+	OPCODE_LUSHR:           {"lushr", operandNone},
+	OPCODE_IAND:            {"iand", operandNone},
+	OPCODE_LAND:            {"land", operandNone},
+	OPCODE_IOR:             {"ior", operandNone},
+	OPCODE_LOR:             {"lor", operandNone},
+	OPCODE_IXOR:            {"ixor", operandNone},
+	OPCODE_LXOR:            {"lxor", operandNone},
+	OPCODE_IINC:            {"iinc", operandIinc},
+	OPCODE_I2L:             {"i2l", operandNone},
+	OPCODE_I2F:             {"i2f", operandNone},
+	OPCODE_I2D:             {"i2d", operandNone},
+	OPCODE_L2I:             {"l2i", operandNone},
+	OPCODE_L2F:             {"l2f", operandNone},
+	OPCODE_L2D:             {"l2d", operandNone},
+	OPCODE_F2I:             {"f2i", operandNone},
+	OPCODE_F2L:             {"f2l", operandNone},
+	OPCODE_F2D:             {"f2d", operandNone},
+	OPCODE_D2I:             {"d2i", operandNone},
+	OPCODE_D2L:             {"d2l", operandNone},
+	OPCODE_D2F:             {"d2f", operandNone},
+	OPCODE_I2B:             {"i2b", operandNone},
+	OPCODE_I2C:             {"i2c", operandNone},
+	OPCODE_I2S:             {"i2s", operandNone},
+	OPCODE_LCMP:            {"lcmp", operandNone},
+	OPCODE_FCMPL:           {"fcmpl", operandNone},
+	OPCODE_FCMPG:           {"fcmpg", operandNone},
+	OPCODE_DCMPL:           {"dcmpl", operandNone},
+	OPCODE_DCMPG:           {"dcmpg", operandNone},
+	OPCODE_IFEQ:            {"ifeq", operandBranch},
+	OPCODE_IFNE:            {"ifne", operandBranch},
+	OPCODE_IFLT:            {"iflt", operandBranch},
+	OPCODE_IFGE:            {"ifge", operandBranch},
+	OPCODE_IFGT:            {"ifgt", operandBranch},
+	OPCODE_IFLE:            {"ifle", operandBranch},
+	OPCODE_IF_ICMPEQ:       {"if_icmpeq", operandBranch},
+	OPCODE_IF_ICMPNE:       {"if_icmpne", operandBranch},
+	OPCODE_IF_ICMPLT:       {"if_icmplt", operandBranch},
+	OPCODE_IF_ICMPGE:       {"if_icmpge", operandBranch},
+	OPCODE_IF_ICMPGT:       {"if_icmpgt", operandBranch},
+	OPCODE_IF_ICMPLE:       {"if_icmple", operandBranch},
+	OPCODE_IF_ACMPEQ:       {"if_acmpeq", operandBranch},
+	OPCODE_IF_ACMPNE:       {"if_acmpne", operandBranch},
+	OPCODE_GOTO:            {"goto", operandBranch},
+	OPCODE_JSR:             {"jsr", operandBranch},
+	OPCODE_RET:             {"ret", operandLocal},
+	OPCODE_TABLESWITCH:     {"tableswitch", operandTableSwitch},
+	OPCODE_LOOKUPSWITCH:    {"lookupswitch", operandLookupSwitch},
+	OPCODE_IRETURN:         {"ireturn", operandNone},
+	OPCODE_LRETURN:         {"lreturn", operandNone},
+	OPCODE_FRETURN:         {"freturn", operandNone},
+	OPCODE_DRETURN:         {"dreturn", operandNone},
+	OPCODE_ARETURN:         {"areturn", operandNone},
+	OPCODE_RETURN:          {"return", operandNone},
+	OPCODE_GETSTATIC:       {"getstatic", operandConstant},
+	OPCODE_PUTSTATIC:       {"putstatic", operandConstant},
+	OPCODE_GETFIELD:        {"getfield", operandConstant},
+	OPCODE_PUTFIELD:        {"putfield", operandConstant},
+	OPCODE_INVOKEVIRTUAL:   {"invokevirtual", operandConstant},
+	OPCODE_INVOKESPECIAL:   {"invokespecial", operandConstant},
+	OPCODE_INVOKESTATIC:    {"invokestatic", operandConstant},
+	OPCODE_INVOKEINTERFACE: {"invokeinterface", operandInvokeInterface},
+	OPCODE_INVOKEDYNAMIC:   {"invokedynamic", operandInvokeDynamic},
+	OPCODE_NEW:             {"new", operandConstant},
+	OPCODE_NEWARRAY:        {"newarray", operandNewArray},
+	OPCODE_ANEWARRAY:       {"anewarray", operandConstant},
+	OPCODE_ARRAYLENGTH:     {"arraylength", operandNone},
+	OPCODE_ATHROW:          {"athrow", operandNone},
+	OPCODE_CHECKCAST:       {"checkcast", operandConstant},
+	OPCODE_INSTANCEOF:      {"instanceof", operandConstant},
+	OPCODE_MONITORENTER:    {"monitorenter", operandNone},
+	OPCODE_MONITOREXIT:     {"monitorexit", operandNone},
+	OPCODE_WIDE:            {"wide", operandWide},
+	OPCODE_MULTIANEWARRAY:  {"multianewarray", operandMultiANewArray},
+	OPCODE_IFNULL:          {"ifnull", operandBranch},
+	OPCODE_IFNONNULL:       {"ifnonnull", operandBranch},
+	OPCODE_GOTO_W:          {"goto_w", operandBranchWide},
+	OPCODE_JSR_W:           {"jsr_w", operandBranchWide},
+	OPCODE_BREAKPOINT:      {"breakpoint", operandNone},
+	OPCODE_IMPDEP1:         {"impdep1", operandNone},
+	OPCODE_IMPDEP2:         {"impdep2", operandNone},
+}
+
+type Instruction struct {
+	Pc      int
+	Opcode  uint8
+	Wide    bool
+	Index   uint16 //常量池索引或局部变量索引
+	Value   int32  //bipush/sipush/iinc的常量, newarray的类型, multianewarray的维度, invokeinterface的count
+	Branch  int32  //相对于Pc的跳转偏移量
+	Default int32
+	Low     int32
+	High    int32
+	Keys    []int32
+	Offsets []int32
+}
+
+func (i *Instruction) Name() string {
+	if op, ok := opcodes[i.Opcode]; ok {
+		return op.name
+	}
+	return fmt.Sprintf("unknown_0x%02x", i.Opcode)
+}
+
+func (i *Instruction) format() operandFormat {
+	return opcodes[i.Opcode].format
+}
+
+// 指令是否携带常量池索引
+func (i *Instruction) HasConstantPoolIndex() bool {
+	switch i.format() {
+	case operandConstantU1, operandConstant, operandInvokeInterface, operandInvokeDynamic, operandMultiANewArray:
+		return true
+	}
+	return false
+}
+
+func (i *Instruction) IsBranch() bool {
+	switch i.format() {
+	case operandBranch, operandBranchWide, operandTableSwitch, operandLookupSwitch:
+		return true
+	}
+	return false
+}
+
+// 指令在Pc处编码后的字节数, tableswitch和lookupswitch的填充字节与Pc有关
+func (i *Instruction) Len() int {
+	switch i.format() {
+	case operandLocal:
+		if i.Wide {
+			return 4
+		}
+		return 2
+	case operandByte, operandConstantU1, operandNewArray:
+		return 2
+	case operandShort, operandConstant, operandBranch:
+		return 3
+	case operandMultiANewArray:
+		return 4
+	case operandInvokeInterface, operandInvokeDynamic, operandBranchWide:
+		return 5
+	case operandIinc:
+		if i.Wide {
+			return 6
+		}
+		return 3
+	case operandTableSwitch:
+		return 1 + switchPadding(i.Pc) + 12 + 4*len(i.Offsets)
+	case operandLookupSwitch:
+		return 1 + switchPadding(i.Pc) + 8 + 8*len(i.Offsets)
+	}
+	return 1
+}
+
+func switchPadding(pc int) int {
+	return (4 - (pc+1)%4) % 4
+}
+
+func (i *Instruction) String(constantPool []ConstantPoolInfo) string {
+	result := fmt.Sprintf("%d: %s", i.Pc, i.Name())
+	switch i.format() {
+	case operandLocal:
+		result += fmt.Sprintf(" %d", i.Index)
+	case operandByte, operandShort, operandNewArray:
+		result += fmt.Sprintf(" %d", i.Value)
+	case operandIinc:
+		result += fmt.Sprintf(" %d, %d", i.Index, i.Value)
+	case operandConstantU1, operandConstant, operandInvokeDynamic:
+		result += fmt.Sprintf(" #%d", i.Index)
+	case operandInvokeInterface, operandMultiANewArray:
+		result += fmt.Sprintf(" #%d, %d", i.Index, i.Value)
+	case operandBranch, operandBranchWide:
+		result += fmt.Sprintf(" %d", i.Pc+int(i.Branch))
+	case operandTableSwitch:
+		result += fmt.Sprintf(" { // %d to %d", i.Low, i.High)
+		for n, offset := range i.Offsets {
+			result += fmt.Sprintf("\n\t%d: %d", int(i.Low)+n, i.Pc+int(offset))
+		}
+		result += fmt.Sprintf("\n\tdefault: %d\n}", i.Pc+int(i.Default))
+	case operandLookupSwitch:
+		result += fmt.Sprintf(" { // %d", len(i.Keys))
+		for n, offset := range i.Offsets {
+			result += fmt.Sprintf("\n\t%d: %d", i.Keys[n], i.Pc+int(offset))
+		}
+		result += fmt.Sprintf("\n\tdefault: %d\n}", i.Pc+int(i.Default))
+	}
+	if i.HasConstantPoolIndex() && int(i.Index) < len(constantPool) && constantPool[i.Index] != nil {
+		result += "	// " + constantPool[i.Index].TagName() + " " + ResolveConstant(constantPool, i.Index)
+	}
+	return result
+}
+
+func ParseInstructions(code []byte) ([]Instruction, error) {
+	instructions := make([]Instruction, 0)
+	pc := 0
+	for pc < len(code) {
+		ins := Instruction{Pc: pc, Opcode: code[pc]}
+		if ins.Opcode == OPCODE_WIDE {
+			if pc+1 >= len(code) {
+				return nil, fmt.Errorf("truncated wide instruction at pc %d", pc)
+			}
+			ins.Wide = true
+			ins.Opcode = code[pc+1]
+			if f := ins.format(); f != operandLocal && f != operandIinc {
+				return nil, fmt.Errorf("invalid wide instruction %s at pc %d", ins.Name(), pc)
+			}
+		}
+		if _, ok := opcodes[ins.Opcode]; !ok {
+			return nil, fmt.Errorf("unknown opcode 0x%02x at pc %d", ins.Opcode, pc)
+		}
+		operands := pc + 1
+		if ins.Wide {
+			operands++
+		}
+		if ins.format() == operandTableSwitch || ins.format() == operandLookupSwitch {
+			operands += switchPadding(pc)
+			if operands+8 > len(code) {
+				return nil, fmt.Errorf("truncated %s at pc %d", ins.Name(), pc)
+			}
+			ins.Default = int32(binary.BigEndian.Uint32(code[operands:]))
+			if ins.format() == operandTableSwitch {
+				if operands+12 > len(code) {
+					return nil, fmt.Errorf("truncated %s at pc %d", ins.Name(), pc)
+				}
+				ins.Low = int32(binary.BigEndian.Uint32(code[operands+4:]))
+				ins.High = int32(binary.BigEndian.Uint32(code[operands+8:]))
+				count := int64(ins.High) - int64(ins.Low) + 1
+				if count < 0 || int64(operands)+12+4*count > int64(len(code)) {
+					return nil, fmt.Errorf("invalid tableswitch at pc %d", pc)
+				}
+				ins.Offsets = make([]int32, count)
+				for n := range ins.Offsets {
+					ins.Offsets[n] = int32(binary.BigEndian.Uint32(code[operands+12+4*n:]))
+				}
+			} else {
+				count := int64(int32(binary.BigEndian.Uint32(code[operands+4:])))
+				if count < 0 || int64(operands)+8+8*count > int64(len(code)) {
+					return nil, fmt.Errorf("invalid lookupswitch at pc %d", pc)
+				}
+				ins.Keys = make([]int32, count)
+				ins.Offsets = make([]int32, count)
+				for n := range ins.Offsets {
+					ins.Keys[n] = int32(binary.BigEndian.Uint32(code[operands+8+8*n:]))
+					ins.Offsets[n] = int32(binary.BigEndian.Uint32(code[operands+12+8*n:]))
+				}
+			}
+		} else {
+			if pc+ins.Len() > len(code) {
+				return nil, fmt.Errorf("truncated %s at pc %d", ins.Name(), pc)
+			}
+			switch ins.format() {
+			case operandLocal:
+				if ins.Wide {
+					ins.Index = binary.BigEndian.Uint16(code[operands:])
+				} else {
+					ins.Index = uint16(code[operands])
+				}
+			case operandByte:
+				ins.Value = int32(int8(code[operands]))
+			case operandShort:
+				ins.Value = int32(int16(binary.BigEndian.Uint16(code[operands:])))
+			case operandNewArray:
+				ins.Value = int32(code[operands])
+			case operandConstantU1:
+				ins.Index = uint16(code[operands])
+			case operandConstant, operandInvokeDynamic:
+				ins.Index = binary.BigEndian.Uint16(code[operands:])
+			case operandInvokeInterface, operandMultiANewArray:
+				ins.Index = binary.BigEndian.Uint16(code[operands:])
+				ins.Value = int32(code[operands+2])
+			case operandIinc:
+				if ins.Wide {
+					ins.Index = binary.BigEndian.Uint16(code[operands:])
+					ins.Value = int32(int16(binary.BigEndian.Uint16(code[operands+2:])))
+				} else {
+					ins.Index = uint16(code[operands])
+					ins.Value = int32(int8(code[operands+1]))
+				}
+			case operandBranch:
+				ins.Branch = int32(int16(binary.BigEndian.Uint16(code[operands:])))
+			case operandBranchWide:
+				ins.Branch = int32(binary.BigEndian.Uint32(code[operands:]))
+			}
+		}
+		instructions = append(instructions, ins)
+		pc += ins.Len()
+	}
+	return instructions, nil
+}
+
+// 按照每条指令的Pc编码, 调用方需保证Pc连续
+func EncodeInstructions(instructions []Instruction) []byte {
+	code := make([]byte, 0)
+	for _, ins := range instructions {
+		if ins.Wide {
+			code = append(code, OPCODE_WIDE)
+		}
+		code = append(code, ins.Opcode)
+		switch ins.format() {
+		case operandLocal:
+			if ins.Wide {
+				code = binary.BigEndian.AppendUint16(code, ins.Index)
+			} else {
+				code = append(code, uint8(ins.Index))
+			}
+		case operandByte, operandNewArray:
+			code = append(code, uint8(ins.Value))
+		case operandShort:
+			code = binary.BigEndian.AppendUint16(code, uint16(ins.Value))
+		case operandConstantU1:
+			code = append(code, uint8(ins.Index))
+		case operandConstant:
+			code = binary.BigEndian.AppendUint16(code, ins.Index)
+		case operandInvokeDynamic:
+			code = binary.BigEndian.AppendUint16(code, ins.Index)
+			code = append(code, 0, 0)
+		case operandInvokeInterface:
+			code = binary.BigEndian.AppendUint16(code, ins.Index)
+			code = append(code, uint8(ins.Value), 0)
+		case operandMultiANewArray:
+			code = binary.BigEndian.AppendUint16(code, ins.Index)
+			code = append(code, uint8(ins.Value))
+		case operandIinc:
+			if ins.Wide {
+				code = binary.BigEndian.AppendUint16(code, ins.Index)
+				code = binary.BigEndian.AppendUint16(code, uint16(ins.Value))
+			} else {
+				code = append(code, uint8(ins.Index), uint8(ins.Value))
+			}
+		case operandBranch:
+			code = binary.BigEndian.AppendUint16(code, uint16(ins.Branch))
+		case operandBranchWide:
+			code = binary.BigEndian.AppendUint32(code, uint32(ins.Branch))
+		case operandTableSwitch, operandLookupSwitch:
+			for n := 0; n < switchPadding(ins.Pc); n++ {
+				code = append(code, 0)
+			}
+			code = binary.BigEndian.AppendUint32(code, uint32(ins.Default))
+			if ins.format() == operandTableSwitch {
+				code = binary.BigEndian.AppendUint32(code, uint32(ins.Low))
+				code = binary.BigEndian.AppendUint32(code, uint32(ins.High))
+				for _, offset := range ins.Offsets {
+					code = binary.BigEndian.AppendUint32(code, uint32(offset))
+				}
+			} else {
+				code = binary.BigEndian.AppendUint32(code, uint32(len(ins.Keys)))
+				for n, key := range ins.Keys {
+					code = binary.BigEndian.AppendUint32(code, uint32(key))
+					code = binary.BigEndian.AppendUint32(code, uint32(ins.Offsets[n]))
+				}
+			}
+		}
+	}
+	return code
+}
+
+func (c *Code) Instructions() ([]Instruction, error) {
+	return ParseInstructions(c.Code)
+}
+
+// 用新的指令序列替换字节码. 指令的Pc和跳转偏移量仍基于原来的字节码, 只允许修改指令本身(例如ldc改为ldc_w),
+// 重新排布后会同步更新异常表、行号表、局部变量表、栈映射帧和类型注解中的偏移量
+func (c *Code) SetInstructions(instructions []Instruction) error {
+	relocated, pcs, err := c.relocateInstructions(instructions)
+	if err != nil {
+		return err
+	}
+	c.applyRelocation(relocated, pcs)
+	return nil
+}
+
+// 计算重新排布后的指令和新旧pc的对应关系, 不修改Code, 失败时返回错误
+func (c *Code) relocateInstructions(instructions []Instruction) ([]Instruction, map[int]int, error) {
+	pcs := make(map[int]int, len(instructions)+1)
+	relocated := make([]Instruction, len(instructions))
+	pc := 0
+	for n, ins := range instructions {
+		pcs[ins.Pc] = pc
+		ins.Pc = pc
+		relocated[n] = ins
+		pc += ins.Len()
+	}
+	pcs[int(c.CodeLength)] = pc
+	if pc > math.MaxUint16 {
+		return nil, nil, fmt.Errorf("code length %d exceeds 65535", pc)
+	}
+
+	for n := range relocated {
+		ins := &relocated[n]
+		old := instructions[n].Pc
+		target := func(offset int32) (int32, error) {
+			newPc, ok := pcs[old+int(offset)]
+			if !ok {
+				return 0, fmt.Errorf("%s at pc %d jumps to invalid pc %d", ins.Name(), old, old+int(offset))
+			}
+			return int32(newPc - ins.Pc), nil
+		}
+		var err error
+		switch ins.format() {
+		case operandBranch, operandBranchWide:
+			if ins.Branch, err = target(ins.Branch); err != nil {
+				return nil, nil, err
+			}
+			if ins.format() == operandBranch && (ins.Branch < math.MinInt16 || ins.Branch > math.MaxInt16) {
+				return nil, nil, fmt.Errorf("%s at pc %d: branch offset %d overflows", ins.Name(), old, ins.Branch)
+			}
+		case operandTableSwitch, operandLookupSwitch:
+			if ins.Default, err = target(ins.Default); err != nil {
+				return nil, nil, err
+			}
+			offsets := make([]int32, len(ins.Offsets))
+			for i, offset := range ins.Offsets {
+				if offsets[i], err = target(offset); err != nil {
+					return nil, nil, err
+				}
+			}
+			ins.Offsets = offsets
+		}
+	}
+	return relocated, pcs, nil
+}
+
+// 按relocateInstructions的结果改写字节码和属性中的偏移量
+func (c *Code) applyRelocation(relocated []Instruction, pcs map[int]int) {
+	relocate := func(old uint16) uint16 {
+		if newPc, ok := pcs[int(old)]; ok {
+			return uint16(newPc)
+		}
+		return old
+	}
+	for n := range c.Table {
+		c.Table[n].StartPc = relocate(c.Table[n].StartPc)
+		c.Table[n].EndPc = relocate(c.Table[n].EndPc)
+		c.Table[n].HandlerPc = relocate(c.Table[n].HandlerPc)
+	}
+	grown := 0
+	for _, attr := range c.Attributes {
+		switch a := visibleAttribute(attr).(type) {
+		case *LineNumberTable:
+			for n := range a.LineNumber {
+				a.LineNumber[n].StartPc = relocate(a.LineNumber[n].StartPc)
+			}
+		case *LocalVariableTable:
+			for n := range a.LocalVariable {
+				v := &a.LocalVariable[n]
+				start := relocate(v.StartPc)
+				v.Length = relocate(v.StartPc+v.Length) - start
+				v.StartPc = start
+			}
+		case *LocalVariableTypeTable:
+			for n := range a.LocalVariableType {
+				v := &a.LocalVariableType[n]
+				start := relocate(v.StartPc)
+				v.Length = relocate(v.StartPc+v.Length) - start
+				v.StartPc = start
+			}
+		case *StackMapTable:
+			grown += a.relocate(relocate)
+		case *RuntimeVisibleTypeAnnotations:
+			for n := range a.Annotations {
+				ann := &a.Annotations[n]
+				switch ann.Kind() {
+				case TARGET_LOCALVAR:
+					for i := range ann.Tables {
+						t := &ann.Tables[i]
+						start := relocate(t.StartPc)
+						t.Length = relocate(t.StartPc+t.Length) - start
+						t.StartPc = start
+					}
+				case TARGET_OFFSET, TARGET_TYPE_ARGUMENT:
+					ann.Offset = relocate(ann.Offset)
+				}
+			}
+		}
+	}
+
+	code := EncodeInstructions(relocated)
+	c.Length = uint32(int(c.Length) + len(code) - int(c.CodeLength) + grown)
+	c.Code = code
+	c.CodeLength = uint32(len(code))
+}
+
+// 重新计算栈映射帧的offset_delta, 偏移量超过63的same_frame和same_locals_1_stack_item_frame会换成extended形式,
+// 返回属性增加的字节数
+func (s *StackMapTable) relocate(relocate func(uint16) uint16) int {
+	grown := 0
+	previous, relocatedPrevious := -1, -1
+	for n := range s.Entries {
+		frame := &s.Entries[n]
+		offset := previous + int(frame.OffsetDelta) + 1
+		newOffset := int(relocate(uint16(offset)))
+		frame.OffsetDelta = uint16(newOffset - relocatedPrevious - 1)
+		previous, relocatedPrevious = offset, newOffset
+		if frame.FrameType <= 63 {
+			if frame.OffsetDelta <= 63 {
+				frame.FrameType = uint8(frame.OffsetDelta)
+			} else {
+				frame.FrameType = 251
+				grown += 2
+			}
+		} else if frame.FrameType <= 127 {
+			if frame.OffsetDelta <= 63 {
+				frame.FrameType = uint8(64 + frame.OffsetDelta)
+			} else {
+				frame.FrameType = 247
+				grown += 2
+			}
+		}
+		for i := range frame.Locals {
+			if frame.Locals[i].Tag == 8 {
+				frame.Locals[i].Offset = relocate(frame.Locals[i].Offset)
+			}
+		}
+		for i := range frame.Stacks {
+			if frame.Stacks[i].Tag == 8 {
+				frame.Stacks[i].Offset = relocate(frame.Stacks[i].Offset)
+			}
+		}
+	}
+	s.Length = uint32(int(s.Length) + grown)
+	return grown
+}
@@ -0,0 +1,578 @@
+package bytecode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// JSON输出只依赖下面这些结构, 修改字段名或者类型时需要同步修改文档和下游
+
+type refJSON struct {
+	Index uint16 `json:"index"`
+	Value string `json:"value"`
+}
+
+type constantJSON struct {
+	Index                    uint16  `json:"index"`
+	Tag                      uint8   `json:"tag"`
+	Kind                     string  `json:"kind"`
+	Value                    string  `json:"value"`
+	NameIndex                *uint16 `json:"nameIndex,omitempty"`
+	DescriptorIndex          *uint16 `json:"descriptorIndex,omitempty"`
+	ClassIndex               *uint16 `json:"classIndex,omitempty"`
+	NameAndTypeIndex         *uint16 `json:"nameAndTypeIndex,omitempty"`
+	StringIndex              *uint16 `json:"stringIndex,omitempty"`
+	ReferenceKind            *uint8  `json:"referenceKind,omitempty"`
+	ReferenceKindName        string  `json:"referenceKindName,omitempty"`
+	ReferenceIndex           *uint16 `json:"referenceIndex,omitempty"`
+	BootstrapMethodAttrIndex *uint16 `json:"bootstrapMethodAttrIndex,omitempty"`
+}
+
+type classFileJSON struct {
+	SchemaVersion     string          `json:"schemaVersion"`
+	Magic             string          `json:"magic"`
+	MinorVersion      uint16          `json:"minorVersion"`
+	MajorVersion      uint16          `json:"majorVersion"`
+	ConstantPoolCount uint16          `json:"constantPoolCount"`
+	ConstantPool      []constantJSON  `json:"constantPool"`
+	AccessFlags       uint16          `json:"accessFlags"`
+	ThisClass         refJSON         `json:"thisClass"`
+	SuperClass        *refJSON        `json:"superClass"`
+	Interfaces        []refJSON       `json:"interfaces"`
+	Fields            []memberJSON    `json:"fields"`
+	Methods           []memberJSON    `json:"methods"`
+	Attributes        []attributeJSON `json:"attributes"`
+}
+
+type memberJSON struct {
+	AccessFlags uint16          `json:"accessFlags"`
+	Name        refJSON         `json:"name"`
+	Descriptor  refJSON         `json:"descriptor"`
+	Attributes  []attributeJSON `json:"attributes"`
+}
+
+// 所有属性共有的字段, 其余字段由具体的属性类型决定
+type attributeJSON map[string]interface{}
+
+type instructionJSON struct {
+	Pc       int        `json:"pc"`
+	Opcode   uint8      `json:"opcode"`
+	Mnemonic string     `json:"mnemonic"`
+	Wide     bool       `json:"wide,omitempty"`
+	Local    *uint16    `json:"local,omitempty"`
+	Constant *refJSON   `json:"constant,omitempty"`
+	Value    *int32     `json:"value,omitempty"`
+	Target   *int       `json:"target,omitempty"`
+	Default  *int       `json:"default,omitempty"`
+	Cases    []caseJSON `json:"cases,omitempty"`
+}
+
+type caseJSON struct {
+	Key    int32 `json:"key"`
+	Target int   `json:"target"`
+}
+
+type annotationJSON struct {
+	Type     refJSON           `json:"type"`
+	Elements []elementPairJSON `json:"elements"`
+}
+
+type typeAnnotationJSON struct {
+	TargetType uint8          `json:"targetType"`
+	Target     typeTargetJSON `json:"target"`
+	Path       []typePathJSON `json:"path"`
+	Annotation annotationJSON `json:"annotation"`
+}
+
+// 只输出TargetType对应的target_info中的字段
+type typeTargetJSON struct {
+	Kind                 string         `json:"kind"`
+	TypeParameterIndex   *uint8         `json:"typeParameterIndex,omitempty"`
+	SupertypeIndex       *uint16        `json:"supertypeIndex,omitempty"`
+	BoundIndex           *uint8         `json:"boundIndex,omitempty"`
+	FormalParameterIndex *uint8         `json:"formalParameterIndex,omitempty"`
+	ThrowsTypeIndex      *uint16        `json:"throwsTypeIndex,omitempty"`
+	LocalVariables       []localVarJSON `json:"localVariables,omitempty"`
+	ExceptionTableIndex  *uint16        `json:"exceptionTableIndex,omitempty"`
+	Offset               *uint16        `json:"offset,omitempty"`
+	TypeArgumentIndex    *uint8         `json:"typeArgumentIndex,omitempty"`
+}
+
+type typePathJSON struct {
+	Kind              uint8 `json:"kind"`
+	TypeArgumentIndex uint8 `json:"typeArgumentIndex"`
+}
+
+type localVarJSON struct {
+	StartPc uint16 `json:"startPc"`
+	Length  uint16 `json:"length"`
+	Index   uint16 `json:"index"`
+}
+
+type elementPairJSON struct {
+	Name  refJSON          `json:"name"`
+	Value elementValueJSON `json:"value"`
+}
+
+type elementValueJSON struct {
+	Tag        string             `json:"tag"`
+	Const      *refJSON           `json:"const,omitempty"`
+	EnumType   *refJSON           `json:"enumType,omitempty"`
+	EnumConst  *refJSON           `json:"enumConst,omitempty"`
+	Class      *refJSON           `json:"class,omitempty"`
+	Annotation *annotationJSON    `json:"annotation,omitempty"`
+	Values     []elementValueJSON `json:"values,omitempty"`
+}
+
+type verificationTypeJSON struct {
+	Tag    uint8    `json:"tag"`
+	Kind   string   `json:"kind"`
+	Class  *refJSON `json:"class,omitempty"`
+	Offset *uint16  `json:"offset,omitempty"`
+}
+
+var verificationTypeNames = []string{"top", "int", "float", "double", "long", "null", "uninitializedThis", "object", "uninitialized"}
+
+type jsonEncoder struct {
+	pool []ConstantPoolInfo
+}
+
+func (e *jsonEncoder) ref(index uint16) refJSON {
+	return refJSON{Index: index, Value: e.value(index)}
+}
+
+// 索引为0时表示没有引用
+func (e *jsonEncoder) optional(index uint16) *refJSON {
+	if index == 0 {
+		return nil
+	}
+	ref := e.ref(index)
+	return &ref
+}
+
+func (e *jsonEncoder) refs(indexes []uint16) []refJSON {
+	result := make([]refJSON, 0, len(indexes))
+	for _, index := range indexes {
+		result = append(result, e.ref(index))
+	}
+	return result
+}
+
+func (e *jsonEncoder) value(index uint16) string {
+	if int(index) >= len(e.pool) || e.pool[index] == nil {
+		return ResolveConstant(e.pool, index)
+	}
+	switch c := e.pool[index].(type) {
+	case *ConstantUtf8:
+		return DecodeModifiedUtf8(c.Value)
+	case *ConstantFloat:
+		return formatFloat(float64(c.Value), 32)
+	case *ConstantDouble:
+		return formatFloat(c.Value, 64)
+	case *ConstantString:
+		return e.value(c.StringIndex)
+	case *ConstantClass:
+		return e.value(c.NameIndex)
+	}
+	return ResolveConstant(e.pool, index)
+}
+
+func formatFloat(value float64, bits int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(value, 'g', -1, bits)
+}
+
+func (f *ClassFile) MarshalJSON() ([]byte, error) {
+	e := &jsonEncoder{pool: f.ConstantPool}
+	result := classFileJSON{
+		SchemaVersion:     JSON_SCHEMA_VERSION,
+		Magic:             fmt.Sprintf("%08X", f.Magic),
+		MinorVersion:      f.MinorVersion,
+		MajorVersion:      f.MajorVersion,
+		ConstantPoolCount: f.ConstantPoolCount,
+		ConstantPool:      make([]constantJSON, 0, len(f.ConstantPool)),
+		AccessFlags:       f.AccessFlags,
+		ThisClass:         e.ref(f.ThisClass),
+		SuperClass:        e.optional(f.SuperClass),
+		Interfaces:        e.refs(f.Interfaces),
+		Fields:            make([]memberJSON, 0, len(f.Fields)),
+		Methods:           make([]memberJSON, 0, len(f.Methods)),
+		Attributes:        e.attributes(f.Attributes),
+	}
+	for i, item := range f.ConstantPool {
+		if item != nil && item.TagValue() != 0 {
+			result.ConstantPool = append(result.ConstantPool, e.constant(uint16(i), item))
+		}
+	}
+	for _, field := range f.Fields {
+		result.Fields = append(result.Fields, memberJSON{AccessFlags: field.AccessFlags, Name: e.ref(field.NameIndex),
+			Descriptor: e.ref(field.DescriptorIndex), Attributes: e.attributes(field.Attributes)})
+	}
+	for _, method := range f.Methods {
+		result.Methods = append(result.Methods, memberJSON{AccessFlags: method.AccessFlags, Name: e.ref(method.NameIndex),
+			Descriptor: e.ref(method.DescriptorIndex), Attributes: e.attributes(method.Attributes)})
+	}
+	return json.Marshal(result)
+}
+
+func (e *jsonEncoder) constant(index uint16, item ConstantPoolInfo) constantJSON {
+	result := constantJSON{Index: index, Tag: item.TagValue(), Kind: item.TagName(), Value: e.value(index)}
+	switch c := item.(type) {
+	case *ConstantClass:
+		result.NameIndex = &c.NameIndex
+	case *ConstantString:
+		result.StringIndex = &c.StringIndex
+	case *ConstantFieldref:
+		result.ClassIndex, result.NameAndTypeIndex = &c.ClassIndex, &c.NameAndTypeIndex
+	case *ConstantMethodref:
+		result.ClassIndex, result.NameAndTypeIndex = &c.ClassIndex, &c.NameAndTypeIndex
+	case *ConstantInterfaceMethodref:
+		result.ClassIndex, result.NameAndTypeIndex = &c.ClassIndex, &c.NameAndTypeIndex
+	case *ConstantNameAndType:
+		result.NameIndex, result.DescriptorIndex = &c.NameIndex, &c.DescriptorIndex
+	case *ConstantMethodHandle:
+		result.ReferenceKind, result.ReferenceIndex = &c.ReferenceKind, &c.ReferenceIndex
+		result.ReferenceKindName = ReferenceKindName(c.ReferenceKind)
+	case *ConstantMethodType:
+		result.DescriptorIndex = &c.DescriptorIndex
+	case *ConstantDynamic:
+		result.BootstrapMethodAttrIndex, result.NameAndTypeIndex = &c.BootstrapMethodAttrIndex, &c.NameAndTypeIndex
+	case *ConstantInvokeDynamic:
+		result.BootstrapMethodAttrIndex, result.NameAndTypeIndex = &c.BootstrapMethodAttrIndex, &c.NameAndTypeIndex
+	case *ConstantModule:
+		result.NameIndex = &c.NameIndex
+	case *ConstantPackage:
+		result.NameIndex = &c.NameIndex
+	}
+	return result
+}
+
+func (e *jsonEncoder) attributes(attrs []AttributeInfo) []attributeJSON {
+	result := make([]attributeJSON, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr != nil {
+			result = append(result, e.attribute(attr))
+		}
+	}
+	return result
+}
+
+func (e *jsonEncoder) attribute(attr AttributeInfo) attributeJSON {
+	base := attr.base()
+	result := attributeJSON{"name": base.Name, "nameIndex": base.NameIndex, "length": base.Length}
+	switch a := visibleAttribute(attr).(type) {
+	case *ConstantValue:
+		result["value"] = e.ref(a.ConstantValueIndex)
+	case *Code:
+		result["maxStack"] = a.MaxStack
+		result["maxLocals"] = a.MaxLocals
+		result["codeLength"] = a.CodeLength
+		if instructions, err := a.Instructions(); err != nil {
+			result["instructionError"] = err.Error()
+		} else {
+			result["instructions"] = e.instructions(instructions)
+		}
+		table := make([]map[string]interface{}, 0, len(a.Table))
+		for _, t := range a.Table {
+			table = append(table, map[string]interface{}{"startPc": t.StartPc, "endPc": t.EndPc,
+				"handlerPc": t.HandlerPc, "catchType": e.optional(t.CatchType)})
+		}
+		result["exceptionTable"] = table
+		result["attributes"] = e.attributes(a.Attributes)
+	case *StackMapTable:
+		frames := make([]map[string]interface{}, 0, len(a.Entries))
+		for _, frame := range a.Entries {
+			frames = append(frames, map[string]interface{}{"frameType": frame.FrameType, "kind": frameKind(frame.FrameType),
+				"offsetDelta": frame.OffsetDelta, "locals": e.verificationTypes(frame.Locals), "stack": e.verificationTypes(frame.Stacks)})
+		}
+		result["entries"] = frames
+	case *Exceptions:
+		result["exceptions"] = e.refs(a.ExceptionIndexTable)
+	case *InnerClasses:
+		classes := make([]map[string]interface{}, 0, len(a.Classes))
+		for _, c := range a.Classes {
+			classes = append(classes, map[string]interface{}{"innerClass": e.ref(c.InnerClassIndex), "outerClass": e.optional(c.OuterClassIndex),
+				"innerName": e.optional(c.InnerNameIndex), "accessFlags": c.InnerClassAccessFlags})
+		}
+		result["classes"] = classes
+	case *EnclosingMethod:
+		result["class"] = e.ref(a.ClassIndex)
+		result["method"] = e.optional(a.MethodIndex)
+	case *Signature:
+		result["signature"] = e.ref(a.SignatureIndex)
+	case *SourceFile:
+		result["sourceFile"] = e.ref(a.SourceFileIndex)
+	case *SourceDebugExtension:
+		result["debugExtension"] = DecodeModifiedUtf8(a.DebugExtension)
+	case *UnknownAttribute:
+		result["bytes"] = hex.EncodeToString(a.Info)
+	case *LineNumberTable:
+		lines := make([]map[string]interface{}, 0, len(a.LineNumber))
+		for _, line := range a.LineNumber {
+			lines = append(lines, map[string]interface{}{"startPc": line.StartPc, "lineNumber": line.LineNumber})
+		}
+		result["lineNumbers"] = lines
+	case *LocalVariableTable:
+		vars := make([]map[string]interface{}, 0, len(a.LocalVariable))
+		for _, v := range a.LocalVariable {
+			vars = append(vars, map[string]interface{}{"startPc": v.StartPc, "length": v.Length, "name": e.ref(v.NameIndex),
+				"descriptor": e.ref(v.DescriptorIndex), "index": v.Index})
+		}
+		result["localVariables"] = vars
+	case *LocalVariableTypeTable:
+		vars := make([]map[string]interface{}, 0, len(a.LocalVariableType))
+		for _, v := range a.LocalVariableType {
+			vars = append(vars, map[string]interface{}{"startPc": v.StartPc, "length": v.Length, "name": e.ref(v.NameIndex),
+				"signature": e.ref(v.SignatureIndex), "index": v.Index})
+		}
+		result["localVariableTypes"] = vars
+	case *RuntimeVisibleAnnotations:
+		result["annotations"] = e.annotations(a.Annotations)
+	case *RuntimeVisibleParameterAnnotations:
+		result["parameters"] = e.parameterAnnotations(a.ParameterAnnotations)
+	case *RuntimeVisibleTypeAnnotations:
+		result["annotations"] = e.typeAnnotations(a.Annotations)
+	case *AnnotationDefault:
+		result["defaultValue"] = e.elementValue(&a.DefaultValue)
+	case *BootstrapMethods:
+		methods := make([]map[string]interface{}, 0, len(a.Methods))
+		for _, m := range a.Methods {
+			methods = append(methods, map[string]interface{}{"methodRef": e.ref(m.BootstrapMethodRef), "arguments": e.refs(m.Arguments)})
+		}
+		result["methods"] = methods
+	case *MethodParameters:
+		params := make([]map[string]interface{}, 0, len(a.Parameters))
+		for _, p := range a.Parameters {
+			params = append(params, map[string]interface{}{"name": e.optional(p.NameIndex), "accessFlags": p.AccessFlags})
+		}
+		result["parameters"] = params
+	case *Module:
+		e.module(result, a)
+	case *ModulePackages:
+		result["packages"] = e.refs(a.PackageIndex)
+	case *ModuleMainClass:
+		result["mainClass"] = e.ref(a.MainClassIndex)
+	case *NestHost:
+		result["hostClass"] = e.ref(a.HostClassIndex)
+	case *NestMembers:
+		result["classes"] = e.refs(a.Classes)
+	case *Record:
+		components := make([]map[string]interface{}, 0, len(a.RecordComponentInfo))
+		for _, c := range a.RecordComponentInfo {
+			components = append(components, map[string]interface{}{"name": e.ref(c.NameIndex), "descriptor": e.ref(c.DescriptorIndex),
+				"attributes": e.attributes(c.Attributes)})
+		}
+		result["components"] = components
+	case *PermittedSubclasses:
+		result["classes"] = e.refs(a.Classes)
+	}
+	return result
+}
+
+func (e *jsonEncoder) module(result attributeJSON, m *Module) {
+	result["module"] = e.ref(m.ModuleNameIndex)
+	result["flags"] = m.ModuleFlags
+	result["version"] = e.optional(m.ModuleVersionIndex)
+	requires := make([]map[string]interface{}, 0, len(m.Requires))
+	for _, r := range m.Requires {
+		requires = append(requires, map[string]interface{}{"module": e.ref(r.RequiresIndex), "flags": r.RequiresFlags,
+			"version": e.optional(r.RequiresVersionIndex)})
+	}
+	result["requires"] = requires
+	exports := make([]map[string]interface{}, 0, len(m.Exports))
+	for _, x := range m.Exports {
+		exports = append(exports, map[string]interface{}{"package": e.ref(x.ExportsIndex), "flags": x.ExportsFlags, "to": e.refs(x.ExportsToIndex)})
+	}
+	result["exports"] = exports
+	opens := make([]map[string]interface{}, 0, len(m.Opens))
+	for _, o := range m.Opens {
+		opens = append(opens, map[string]interface{}{"package": e.ref(o.OpenIndex), "flags": o.OpenFlags, "to": e.refs(o.OpenToIndex)})
+	}
+	result["opens"] = opens
+	result["uses"] = e.refs(m.UsesIndex)
+	provides := make([]map[string]interface{}, 0, len(m.Provides))
+	for _, p := range m.Provides {
+		provides = append(provides, map[string]interface{}{"service": e.ref(p.ProvidesIndex), "with": e.refs(p.ProvidesWithIndex)})
+	}
+	result["provides"] = provides
+}
+
+func (e *jsonEncoder) instructions(instructions []Instruction) []instructionJSON {
+	result := make([]instructionJSON, 0, len(instructions))
+	for n := range instructions {
+		ins := &instructions[n]
+		item := instructionJSON{Pc: ins.Pc, Opcode: ins.Opcode, Mnemonic: ins.Name(), Wide: ins.Wide}
+		switch ins.format() {
+		case operandLocal:
+			item.Local = &ins.Index
+		case operandIinc:
+			item.Local, item.Value = &ins.Index, &ins.Value
+		case operandByte, operandShort, operandNewArray:
+			item.Value = &ins.Value
+		case operandConstantU1, operandConstant, operandInvokeDynamic:
+			item.Constant = e.optional(ins.Index)
+		case operandInvokeInterface, operandMultiANewArray:
+			item.Constant, item.Value = e.optional(ins.Index), &ins.Value
+		case operandBranch, operandBranchWide:
+			target := ins.Pc + int(ins.Branch)
+			item.Target = &target
+		case operandTableSwitch, operandLookupSwitch:
+			target := ins.Pc + int(ins.Default)
+			item.Default = &target
+			item.Cases = make([]caseJSON, 0, len(ins.Offsets))
+			for i, offset := range ins.Offsets {
+				key := ins.Low + int32(i)
+				if ins.format() == operandLookupSwitch {
+					key = ins.Keys[i]
+				}
+				item.Cases = append(item.Cases, caseJSON{Key: key, Target: ins.Pc + int(offset)})
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func frameKind(frameType uint8) string {
+	switch {
+	case frameType <= 63:
+		return "same"
+	case frameType <= 127:
+		return "same_locals_1_stack_item"
+	case frameType == 247:
+		return "same_locals_1_stack_item_extended"
+	case frameType >= 248 && frameType <= 250:
+		return "chop"
+	case frameType == 251:
+		return "same_extended"
+	case frameType >= 252 && frameType <= 254:
+		return "append"
+	case frameType == 255:
+		return "full"
+	}
+	return "reserved"
+}
+
+func (e *jsonEncoder) verificationTypes(types []VerificationTypeInfo) []verificationTypeJSON {
+	result := make([]verificationTypeJSON, 0, len(types))
+	for i := range types {
+		t := &types[i]
+		item := verificationTypeJSON{Tag: t.Tag, Kind: "unknown"}
+		if int(t.Tag) < len(verificationTypeNames) {
+			item.Kind = verificationTypeNames[t.Tag]
+		}
+		switch t.Tag {
+		case 7:
+			ref := e.ref(t.CpoolIndex)
+			item.Class = &ref
+		case 8:
+			item.Offset = &t.Offset
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func (e *jsonEncoder) annotations(annotations []Annotation) []annotationJSON {
+	result := make([]annotationJSON, 0, len(annotations))
+	for i := range annotations {
+		result = append(result, e.annotation(&annotations[i]))
+	}
+	return result
+}
+
+func (e *jsonEncoder) parameterAnnotations(parameters []ParameterAnnotation) [][]annotationJSON {
+	result := make([][]annotationJSON, 0, len(parameters))
+	for _, p := range parameters {
+		result = append(result, e.annotations(p.Annotations))
+	}
+	return result
+}
+
+func (e *jsonEncoder) typeAnnotations(annotations []TypeAnnotation) []typeAnnotationJSON {
+	result := make([]typeAnnotationJSON, 0, len(annotations))
+	for i := range annotations {
+		ann := &annotations[i]
+		path := make([]typePathJSON, 0, len(ann.TargetPath.Path))
+		for _, p := range ann.TargetPath.Path {
+			path = append(path, typePathJSON{Kind: p.TypePathKind, TypeArgumentIndex: p.TypeArgumentIndex})
+		}
+		result = append(result, typeAnnotationJSON{TargetType: ann.TargetType, Target: typeTarget(ann), Path: path,
+			Annotation: annotationJSON{Type: e.ref(ann.TypeIndex), Elements: e.elementPairs(ann.ValuePairs)}})
+	}
+	return result
+}
+
+func typeTarget(ann *TypeAnnotation) typeTargetJSON {
+	result := typeTargetJSON{Kind: ann.Kind().String()}
+	switch ann.Kind() {
+	case TARGET_TYPE_PARAMETER:
+		result.TypeParameterIndex = &ann.TypeParameterIndex
+	case TARGET_SUPERTYPE:
+		result.SupertypeIndex = &ann.SupertypeIndex
+	case TARGET_TYPE_PARAMETER_BOUND:
+		result.TypeParameterIndex, result.BoundIndex = &ann.TypeParameterIndex, &ann.BoundIndex
+	case TARGET_FORMAL_PARAMETER:
+		result.FormalParameterIndex = &ann.FormalParameterIndex
+	case TARGET_THROWS:
+		result.ThrowsTypeIndex = &ann.ThrowsTypeIndex
+	case TARGET_LOCALVAR:
+		result.LocalVariables = make([]localVarJSON, 0, len(ann.Tables))
+		for _, t := range ann.Tables {
+			result.LocalVariables = append(result.LocalVariables, localVarJSON{StartPc: t.StartPc, Length: t.Length, Index: t.Index})
+		}
+	case TARGET_CATCH:
+		result.ExceptionTableIndex = &ann.ExceptionTableIndex
+	case TARGET_OFFSET:
+		result.Offset = &ann.Offset
+	case TARGET_TYPE_ARGUMENT:
+		result.Offset, result.TypeArgumentIndex = &ann.Offset, &ann.TypeArgumentIndex
+	}
+	return result
+}
+
+func (e *jsonEncoder) annotation(a *Annotation) annotationJSON {
+	return annotationJSON{Type: e.ref(a.TypeIndex), Elements: e.elementPairs(a.ValuePairs)}
+}
+
+func (e *jsonEncoder) elementPairs(pairs []ElementValuePairs) []elementPairJSON {
+	result := make([]elementPairJSON, 0, len(pairs))
+	for i := range pairs {
+		result = append(result, elementPairJSON{Name: e.ref(pairs[i].ElementNameIndex), Value: e.elementValue(&pairs[i].ElementValue)})
+	}
+	return result
+}
+
+func (e *jsonEncoder) elementValue(v *ElementValue) elementValueJSON {
+	result := elementValueJSON{Tag: string(rune(v.Tag))}
+	switch v.Tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		ref := e.ref(v.ConstValueIndex)
+		result.Const = &ref
+	case 'e':
+		enumType, enumConst := e.ref(v.TypeNameIndex), e.ref(v.ConstNameIndex)
+		result.EnumType, result.EnumConst = &enumType, &enumConst
+	case 'c':
+		ref := e.ref(v.ClassInfoIndex)
+		result.Class = &ref
+	case '@':
+		ann := e.annotation(&v.AnnotationValue)
+		result.Annotation = &ann
+	case '[':
+		result.Values = make([]elementValueJSON, 0, len(v.Values))
+		for i := range v.Values {
+			result.Values = append(result.Values, e.elementValue(&v.Values[i]))
+		}
+	}
+	return result
+}
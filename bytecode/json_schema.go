@@ -0,0 +1,202 @@
+package bytecode
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// JSON输出的格式版本, 删除或者修改字段时增加主版本号, 只新增字段时增加次版本号
+const JSON_SCHEMA_VERSION = "1.2"
+
+//go:embed classfile.schema.json
+var JSONSchema []byte
+
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+type schemaValidator struct {
+	defs map[string]interface{}
+}
+
+// 使用内置的JSON Schema校验JSON输出, 只支持schema中用到的关键字.
+// dump多个类时每个类一个文档, 每个文档都要校验, 有多个文档时路径前面加上文档的序号
+func ValidateJSON(data []byte) ([]SchemaError, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		return nil, fmt.Errorf("invalid embedded schema: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	docs := make([]interface{}, 0, 1)
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", len(docs)+1, err)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no JSON document")
+	}
+	defs, _ := schema["$defs"].(map[string]interface{})
+	v := &schemaValidator{defs: defs}
+	errs := make([]SchemaError, 0)
+	for i, doc := range docs {
+		root := "$"
+		if len(docs) > 1 {
+			root = fmt.Sprintf("document %d $", i+1)
+		}
+		errs = append(errs, v.validate(root, schema, doc)...)
+	}
+	return errs, nil
+}
+
+func (v *schemaValidator) validate(path string, schema map[string]interface{}, value interface{}) []SchemaError {
+	fail := func(format string, args ...interface{}) []SchemaError {
+		return []SchemaError{{Path: path, Message: fmt.Sprintf(format, args...)}}
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		def, ok := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if !ok {
+			return fail("unresolvable $ref %s", ref)
+		}
+		return v.validate(path, def, value)
+	}
+	if variants, ok := schema["anyOf"].([]interface{}); ok {
+		for _, variant := range variants {
+			if len(v.validate(path, variant.(map[string]interface{}), value)) == 0 {
+				return nil
+			}
+		}
+		return fail("matches none of anyOf")
+	}
+	if variants, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		var closest []SchemaError
+		for _, variant := range variants {
+			errs := v.validate(path, variant.(map[string]interface{}), value)
+			if len(errs) == 0 {
+				matched++
+			} else if closest == nil || len(errs) < len(closest) {
+				closest = errs
+			}
+		}
+		if matched == 1 {
+			return nil
+		}
+		if matched == 0 {
+			return closest
+		}
+		return fail("matches %d variants of oneOf", matched)
+	}
+	if not, ok := schema["not"].(map[string]interface{}); ok && len(v.validate(path, not, value)) == 0 {
+		return fail("%v is excluded", value)
+	}
+	if expected, ok := schema["const"]; ok && !jsonEqual(expected, value) {
+		return fail("must be %v", expected)
+	}
+	if values, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, expected := range values {
+			found = found || jsonEqual(expected, value)
+		}
+		if !found {
+			return fail("%v is not one of %v", value, values)
+		}
+	}
+	if t, ok := schema["type"].(string); ok && !hasJSONType(t, value) {
+		return fail("must be %s", t)
+	}
+	if number, ok := value.(json.Number); ok {
+		n, _ := number.Float64()
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			return fail("%v is less than %v", number, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			return fail("%v is greater than %v", number, max)
+		}
+	}
+
+	errs := make([]SchemaError, 0)
+	switch val := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := val[name.(string)]; !ok {
+					errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("missing property %s", name)})
+				}
+			}
+		}
+		for name, item := range val {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("unexpected property %s", name)})
+				}
+				continue
+			}
+			errs = append(errs, v.validate(path+"."+name, property, item)...)
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				errs = append(errs, v.validate(fmt.Sprintf("%s[%d]", path, i), items, item)...)
+			}
+		}
+	}
+	return errs
+}
+
+func hasJSONType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := number.Int64()
+		return err == nil
+	}
+	return false
+}
+
+func jsonEqual(expected, value interface{}) bool {
+	if number, ok := value.(json.Number); ok {
+		n, err := number.Float64()
+		return err == nil && reflect.DeepEqual(expected, n)
+	}
+	return reflect.DeepEqual(expected, value)
+}
@@ -0,0 +1,98 @@
+package bytecode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testdata中的类覆盖Long/Double常量、注解及默认值、Record、类型注解、SMAP、invokedynamic和不认识的属性
+func loadTestClasses(t *testing.T) map[string][]byte {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join("testdata", "*.class"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("no classes in testdata")
+	}
+	classes := make(map[string][]byte)
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes[filepath.Base(name)] = data
+	}
+	return classes
+}
+
+func TestJSONMatchesSchema(t *testing.T) {
+	for name, data := range loadTestClasses(t) {
+		f, err := ParseClassFile(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		output, err := f.MarshalJSON()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		errs, err := ValidateJSON(output)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		for _, e := range errs {
+			t.Errorf("%s: %s", name, e.Error())
+		}
+	}
+}
+
+func TestSchemaRejectsInvalidJSON(t *testing.T) {
+	errs, err := ValidateJSON([]byte(`{"schemaVersion": "0.0"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) == 0 {
+		t.Error("expect errors for an incomplete document")
+	}
+}
+
+// dump多个类时输出多个JSON文档, 后面的文档也要校验
+func TestSchemaValidatesEveryDocument(t *testing.T) {
+	var output []byte
+	for name, data := range loadTestClasses(t) {
+		f, err := ParseClassFile(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		doc, err := f.MarshalJSON()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		output = append(append(output, doc...), '\n')
+	}
+	errs, err := ValidateJSON(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range errs {
+		t.Error(e.Error())
+	}
+
+	errs, err = ValidateJSON(append(output, `{"schemaVersion": "0.0"}`...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) == 0 {
+		t.Error("expect errors for an invalid document after valid ones")
+	}
+	if _, err := ValidateJSON(append(output, `{"schemaVersion":`...)); err == nil {
+		t.Error("expect an error for trailing data")
+	}
+	if _, err := ValidateJSON(nil); err == nil {
+		t.Error("expect an error for an empty input")
+	}
+}
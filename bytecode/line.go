@@ -0,0 +1,117 @@
+package bytecode
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 一段字节码对应的源码行, EndPc不包含在内
+type LineRange struct {
+	StartPc int
+	EndPc   int
+	Line    int
+}
+
+// 方法的行号表整理成按pc排序、互不重叠的区间. Code中可以有多个LineNumberTable, 表项不要求按pc排序;
+// start_pc相同时以后出现的为准, 超出代码长度的表项忽略, 相邻的同一行合并为一个区间
+func (m *MethodInfo) LineRanges() []LineRange {
+	ranges := make([]LineRange, 0)
+	code := m.Code()
+	if code == nil {
+		return ranges
+	}
+	entries := make([]LineNumber, 0)
+	for _, attr := range code.Attributes {
+		if table, ok := attr.(*LineNumberTable); ok {
+			entries = append(entries, table.LineNumber...)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartPc < entries[j].StartPc })
+	for i, entry := range entries {
+		start := int(entry.StartPc)
+		if start >= len(code.Code) || i+1 < len(entries) && entries[i+1].StartPc == entry.StartPc {
+			continue
+		}
+		if n := len(ranges); n > 0 {
+			if ranges[n-1].Line == int(entry.LineNumber) {
+				continue
+			}
+			ranges[n-1].EndPc = start
+		}
+		ranges = append(ranges, LineRange{StartPc: start, EndPc: len(code.Code), Line: int(entry.LineNumber)})
+	}
+	return ranges
+}
+
+// pc所在的源码行, 没有行号时返回0. 逐条指令查找时先取一次LineRanges再用LineAt
+func (m *MethodInfo) LineFor(pc int) int {
+	return LineAt(m.LineRanges(), pc)
+}
+
+// 在LineRanges的结果中二分查找pc所在的源码行, 没有行号时返回0
+func LineAt(ranges []LineRange, pc int) int {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].EndPc > pc })
+	if i < len(ranges) && ranges[i].StartPc <= pc {
+		return ranges[i].Line
+	}
+	return 0
+}
+
+// 源码行对应的每一段字节码的起始pc, 按pc排序. 循环、finally等会让一行对应多段字节码
+func (m *MethodInfo) PCsForLine(line int) []int {
+	pcs := make([]int, 0)
+	for _, r := range m.LineRanges() {
+		if r.Line == line {
+			pcs = append(pcs, r.StartPc)
+		}
+	}
+	return pcs
+}
+
+// SourceFile属性中的文件名, 没有时返回空字符串
+func (f *ClassFile) SourceFile() string {
+	for _, attr := range f.Attributes {
+		if source, ok := attr.(*SourceFile); ok {
+			if utf8, ok := ConstantAt(f.ConstantPool, source.SourceFileIndex).(*ConstantUtf8); ok {
+				return DecodeModifiedUtf8(utf8.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// 可能的源文件路径: 包的目录加上SourceFile, 例如com/acme/Main.java.
+// 没有SourceFile时按最外层的类名推测为.java文件
+func (f *ClassFile) SourcePath() string {
+	className := f.ClassName()
+	name := f.SourceFile()
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		name = path.Base(className)
+		if i := strings.Index(name, "$"); i > 0 {
+			name = name[:i]
+		}
+		name += ".java"
+	}
+	return strings.TrimPrefix(path.Join(path.Dir(className), name), "./")
+}
+
+// 类似异常栈中的位置, 例如Main.java:42, 没有行号时只有文件名.
+// 有SMAP时按默认的stratum换算, 例如Kotlin内联函数的行号换算为被内联的文件和行
+func (f *ClassFile) SourceLocation(m *MethodInfo, pc int) string {
+	line := m.LineFor(pc)
+	if smap, err := f.SMAP(); err == nil && smap != nil && line > 0 {
+		if position, ok := smap.Translate(line, ""); ok && position.File.Name != "" {
+			return position.String()
+		}
+	}
+	location := path.Base(f.SourcePath())
+	if line > 0 {
+		location += ":" + strconv.Itoa(line)
+	}
+	return location
+}
@@ -3,7 +3,9 @@ package bytecode
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
+
+	"class-file-parser/flags"
+	"class-file-parser/i18n"
 )
 
 const METHOD_ACC_PUBLIC = 0x0001
@@ -41,30 +43,11 @@ func (m *MethodInfo) Parse(data []byte, index int, constantPool []ConstantPoolIn
 
 func (m *MethodInfo) String(constantPool []ConstantPoolInfo) string {
 	result := ""
-	if METHOD_ACC_PRIVATE&m.AccessFlags != 0 {
-		result += "private "
-	} else if METHOD_ACC_PROTECTED&m.AccessFlags != 0 {
-		result += "protected "
-	} else if METHOD_ACC_PUBLIC&m.AccessFlags != 0 {
-		result += "public "
-	}
-	if METHOD_ACC_STATIC&m.AccessFlags != 0 {
-		result += "static "
-	}
-	if METHOD_ACC_FINAL&m.AccessFlags != 0 {
-		result += "final "
-	}
-	if METHOD_ACC_SYNCHRONIZED&m.AccessFlags != 0 {
-		result += "synchronized  "
-	}
-	if METHOD_ACC_NATIVE&m.AccessFlags != 0 {
-		result += "native "
-	}
-	if METHOD_ACC_ABSTRACT&m.AccessFlags != 0 {
-		result += "abstract "
+	if modifiers := m.Flags().String(); modifiers != "" {
+		result += modifiers + " "
 	}
 	result += constantPool[m.DescriptorIndex].String(constantPool) + " " + constantPool[m.NameIndex].String(constantPool)
-	result += fmt.Sprintf("\n属性个数: %d\n", m.AttributesCount)
+	result += "\n" + i18n.T("attribute_count", m.AttributesCount) + "\n"
 	for _, attr := range m.Attributes {
 		if attr != nil {
 			result += attr.GetName() + ": " + attr.String(constantPool) + "\n"
@@ -72,3 +55,25 @@ func (m *MethodInfo) String(constantPool []ConstantPoolInfo) string {
 	}
 	return result
 }
+
+func (m *MethodInfo) Flags() flags.MethodFlags {
+	return flags.MethodFlags(m.AccessFlags)
+}
+
+func (m *MethodInfo) Name(constantPool []ConstantPoolInfo) string {
+	return constantUtf8(constantPool, m.NameIndex)
+}
+
+func (m *MethodInfo) Descriptor(constantPool []ConstantPoolInfo) string {
+	return constantUtf8(constantPool, m.DescriptorIndex)
+}
+
+// 方法的Code属性, abstract和native方法没有Code属性, 返回nil
+func (m *MethodInfo) Code() *Code {
+	for _, attr := range m.Attributes {
+		if code, ok := attr.(*Code); ok {
+			return code
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package bytecode
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 编译器生成的lambda方法名, 例如lambda$main$0
+var lambdaName = regexp.MustCompile(`^lambda\$(.*)\$(\d+)$`)
+var lambdaRef = regexp.MustCompile(`lambda\$([^$"\s:(]*)\$\d+`)
+
+// SourceFile只保留文件名, 去掉构建时的目录, 返回是否有修改
+func (f *ClassFile) NormalizeSourceFile() bool {
+	changed := false
+	for _, attr := range f.Attributes {
+		if source, ok := attr.(*SourceFile); ok {
+			if utf8, ok := ConstantAt(f.ConstantPool, source.SourceFileIndex).(*ConstantUtf8); ok {
+				name := string(utf8.Value)
+				if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+					changed = setUtf8(utf8, name[i+1:]) || changed
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// 按方法体重新编号合成的lambda方法, 方法体相同时编号也相同, 返回是否有修改.
+// 方法名引用的Utf8常量整体替换, NameAndType和MethodHandle中的引用随之改变
+func (f *ClassFile) NormalizeLambdas() bool {
+	type lambda struct {
+		name   string
+		number int
+		key    string
+	}
+	groups := make(map[string][]lambda)
+	for i := range f.Methods {
+		m := &f.Methods[i]
+		name := m.Name(f.ConstantPool)
+		match := lambdaName.FindStringSubmatch(name)
+		if match == nil || m.AccessFlags&METHOD_ACC_SYNTHETIC == 0 {
+			continue
+		}
+		// 方法体中引用的其他lambda不考虑编号
+		c := &canonical{f: f, e: &jsonEncoder{pool: f.ConstantPool}}
+		c.method(m.Descriptor(f.ConstantPool), m)
+		key := lambdaRef.ReplaceAllString(strings.Join(c.lines, "\n"), "lambda$$$1$$")
+		number, _ := strconv.Atoi(match[2])
+		groups[match[1]] = append(groups[match[1]], lambda{name, number, key})
+	}
+	renames := make(map[string]string)
+	for prefix, lambdas := range groups {
+		sort.Slice(lambdas, func(i, j int) bool {
+			if lambdas[i].key != lambdas[j].key {
+				return lambdas[i].key < lambdas[j].key
+			}
+			return lambdas[i].number < lambdas[j].number
+		})
+		for i, l := range lambdas {
+			if name := "lambda$" + prefix + "$" + strconv.Itoa(i); name != l.name {
+				renames[l.name] = name
+			}
+		}
+	}
+	changed := false
+	for _, item := range f.ConstantPool {
+		if utf8, ok := item.(*ConstantUtf8); ok {
+			if name, ok := renames[string(utf8.Value)]; ok {
+				changed = setUtf8(utf8, name) || changed
+			}
+		}
+	}
+	return changed
+}
+
+func setUtf8(utf8 *ConstantUtf8, value string) bool {
+	if string(utf8.Value) == value {
+		return false
+	}
+	utf8.Value = []byte(value)
+	utf8.Length = uint16(len(utf8.Value))
+	return true
+}
@@ -0,0 +1,340 @@
+package bytecode
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSR-45中Java层的stratum, 也就是类文件本身的行号
+const STRATUM_JAVA = "Java"
+
+// SourceDebugExtension中的SMAP, 参见JSR-45. JSP、Kotlin的内联函数等用它把Java层的行号映射到源码
+type SMAP struct {
+	OutputFile     string         `json:"outputFile"`
+	DefaultStratum string         `json:"defaultStratum"`
+	Strata         []Stratum      `json:"strata"`
+	Vendors        []Vendor       `json:"vendors,omitempty"`
+	Embedded       []EmbeddedSMAP `json:"embedded,omitempty"`
+}
+
+type Stratum struct {
+	ID    string        `json:"id"`
+	Files []StratumFile `json:"files"`
+	Lines []LineInfo    `json:"lines"`
+}
+
+// Path是相对于源码目录的路径, 没有时为空
+type StratumFile struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+// InputStartLine#LineFileID,RepeatCount:OutputStartLine,OutputLineIncrement,
+// 输入的第i行对应输出的OutputStartLine+i*OutputLineIncrement开始的OutputLineIncrement行
+type LineInfo struct {
+	InputStartLine      int `json:"inputStartLine"`
+	FileID              int `json:"fileId"`
+	RepeatCount         int `json:"repeatCount"`
+	OutputStartLine     int `json:"outputStartLine"`
+	OutputLineIncrement int `json:"outputLineIncrement"`
+}
+
+type Vendor struct {
+	ID    string   `json:"id"`
+	Lines []string `json:"lines"`
+}
+
+// *O和*C之间未解析的SMAP, 它们的输出是外层的Stratum
+type EmbeddedSMAP struct {
+	Stratum string  `json:"stratum"`
+	SMAPs   []*SMAP `json:"smaps"`
+}
+
+// stratum中的源码位置
+type SourcePosition struct {
+	Stratum string
+	File    StratumFile
+	Line    int
+}
+
+// 例如Inline.kt:5
+func (p SourcePosition) String() string {
+	return p.File.Name + ":" + strconv.Itoa(p.Line)
+}
+
+var lineInfoPattern = regexp.MustCompile(`^(\d+)(?:#(\d+))?(?:,(\d+))?:(\d+)(?:,(\d+))?$`)
+
+type smapParser struct {
+	lines []string
+	pos   int
+}
+
+// 解析SMAP文本, 行尾可以是\n、\r\n或者\r. Kotlin在每个stratum后面都写*E, 最外层的*E之后还有内容时继续解析
+func ParseSMAP(text string) (*SMAP, error) {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	p := &smapParser{lines: strings.Split(strings.TrimRight(text, "\n"), "\n")}
+	return p.smap(false)
+}
+
+func (p *smapParser) next() (string, bool) {
+	if p.pos >= len(p.lines) {
+		return "", false
+	}
+	p.pos++
+	return strings.TrimSpace(p.lines[p.pos-1]), true
+}
+
+// 下一个section的开头, 没有更多内容时为空
+func (p *smapParser) section() string {
+	if p.pos >= len(p.lines) {
+		return ""
+	}
+	if line := strings.TrimSpace(p.lines[p.pos]); strings.HasPrefix(line, "*") {
+		return line
+	}
+	return ""
+}
+
+func (p *smapParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *smapParser) smap(embedded bool) (*SMAP, error) {
+	if line, _ := p.next(); line != "SMAP" {
+		return nil, p.errorf("expected SMAP header")
+	}
+	s := &SMAP{Strata: make([]Stratum, 0)}
+	var ok bool
+	if s.OutputFile, ok = p.next(); !ok {
+		return nil, p.errorf("missing output file name")
+	}
+	if s.DefaultStratum, ok = p.next(); !ok || s.DefaultStratum == "" {
+		return nil, p.errorf("missing default stratum")
+	}
+	var stratum *Stratum
+	for {
+		line, ok := p.next()
+		if !ok {
+			if embedded {
+				return nil, p.errorf("missing *E")
+			}
+			return s, nil
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "*S":
+			if len(fields) < 2 {
+				return nil, p.errorf("missing stratum id")
+			}
+			s.Strata = append(s.Strata, Stratum{ID: fields[1], Files: make([]StratumFile, 0), Lines: make([]LineInfo, 0)})
+			stratum = &s.Strata[len(s.Strata)-1]
+		case "*F", "*L":
+			if stratum == nil {
+				return nil, p.errorf("%s before *S", fields[0])
+			}
+			var err error
+			if fields[0] == "*F" {
+				err = p.files(stratum)
+			} else {
+				err = p.lineInfos(stratum)
+			}
+			if err != nil {
+				return nil, err
+			}
+		case "*V":
+			id, _ := p.next()
+			vendor := Vendor{ID: id, Lines: make([]string, 0)}
+			for p.pos < len(p.lines) && p.section() == "" {
+				vendor.Lines = append(vendor.Lines, p.lines[p.pos])
+				p.pos++
+			}
+			s.Vendors = append(s.Vendors, vendor)
+		case "*O":
+			if len(fields) < 2 {
+				return nil, p.errorf("missing stratum id")
+			}
+			e, err := p.embedded(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			s.Embedded = append(s.Embedded, e)
+		case "*E":
+			if embedded || p.section() == "" {
+				return s, nil
+			}
+		default:
+			if !strings.HasPrefix(line, "*") {
+				return nil, p.errorf("unexpected %q", line)
+			}
+			// 未知的section忽略
+			for p.pos < len(p.lines) && p.section() == "" {
+				p.pos++
+			}
+		}
+	}
+}
+
+// + 1 Foo.kt换行后是路径, 1 Foo.kt没有路径
+func (p *smapParser) files(stratum *Stratum) error {
+	for p.pos < len(p.lines) && p.section() == "" {
+		line, _ := p.next()
+		if line == "" {
+			continue
+		}
+		hasPath := strings.HasPrefix(line, "+")
+		fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "+")), " ", 2)
+		id, err := strconv.Atoi(fields[0])
+		if err != nil || len(fields) < 2 {
+			return p.errorf("invalid file %q", line)
+		}
+		file := StratumFile{ID: id, Name: strings.TrimSpace(fields[1])}
+		if hasPath {
+			if file.Path, _ = p.next(); file.Path == "" {
+				return p.errorf("missing path of file %d", id)
+			}
+		}
+		stratum.Files = append(stratum.Files, file)
+	}
+	return nil
+}
+
+// 省略的LineFileID沿用上一行的, RepeatCount和OutputLineIncrement默认为1
+func (p *smapParser) lineInfos(stratum *Stratum) error {
+	fileID := 0
+	for p.pos < len(p.lines) && p.section() == "" {
+		line, _ := p.next()
+		if line == "" {
+			continue
+		}
+		match := lineInfoPattern.FindStringSubmatch(line)
+		if match == nil {
+			return p.errorf("invalid line info %q", line)
+		}
+		number := func(s string, value int) int {
+			if s != "" {
+				value, _ = strconv.Atoi(s)
+			}
+			return value
+		}
+		fileID = number(match[2], fileID)
+		stratum.Lines = append(stratum.Lines, LineInfo{InputStartLine: number(match[1], 0), FileID: fileID,
+			RepeatCount: number(match[3], 1), OutputStartLine: number(match[4], 0), OutputLineIncrement: number(match[5], 1)})
+	}
+	return nil
+}
+
+func (p *smapParser) embedded(id string) (EmbeddedSMAP, error) {
+	e := EmbeddedSMAP{Stratum: id, SMAPs: make([]*SMAP, 0)}
+	for {
+		line := ""
+		if p.pos < len(p.lines) {
+			line = strings.TrimSpace(p.lines[p.pos])
+		}
+		switch {
+		case line == "SMAP":
+			s, err := p.smap(true)
+			if err != nil {
+				return e, err
+			}
+			e.SMAPs = append(e.SMAPs, s)
+		case strings.HasPrefix(line, "*C"):
+			p.pos++
+			if fields := strings.Fields(line); len(fields) < 2 || fields[1] != id {
+				return e, p.errorf("*C does not match *O %s", id)
+			}
+			return e, nil
+		default:
+			p.pos++
+			return e, p.errorf("expected SMAP or *C %s", id)
+		}
+	}
+}
+
+func (s *SMAP) stratum(id string) *Stratum {
+	for i := range s.Strata {
+		if s.Strata[i].ID == id {
+			return &s.Strata[i]
+		}
+	}
+	return nil
+}
+
+// Java层的行号换算为stratum中的源码位置, stratum为空时使用默认的stratum.
+// 嵌入的SMAP先换算到外层的stratum, 再在其中换算
+func (s *SMAP) Translate(line int, stratum string) (SourcePosition, bool) {
+	if stratum == "" {
+		stratum = s.DefaultStratum
+	}
+	if stratum == STRATUM_JAVA {
+		return SourcePosition{Stratum: STRATUM_JAVA, File: StratumFile{Name: s.OutputFile}, Line: line}, true
+	}
+	if st := s.stratum(stratum); st != nil {
+		return st.Translate(line)
+	}
+	for _, e := range s.Embedded {
+		outer := s.stratum(e.Stratum)
+		if outer == nil {
+			continue
+		}
+		position, ok := outer.Translate(line)
+		if !ok {
+			continue
+		}
+		for _, nested := range e.SMAPs {
+			if nested.OutputFile != position.File.Name {
+				continue
+			}
+			if result, ok := nested.Translate(position.Line, stratum); ok {
+				return result, true
+			}
+		}
+	}
+	return SourcePosition{}, false
+}
+
+// 输出行所在的输入行, 有多项时以第一项为准; OutputLineIncrement为0时输入行都对应OutputStartLine
+func (st *Stratum) Translate(line int) (SourcePosition, bool) {
+	for _, info := range st.Lines {
+		offset := line - info.OutputStartLine
+		if offset < 0 {
+			continue
+		}
+		i := 0
+		if info.OutputLineIncrement > 0 {
+			i = offset / info.OutputLineIncrement
+		} else if offset > 0 {
+			continue
+		}
+		if i >= info.RepeatCount {
+			continue
+		}
+		position := SourcePosition{Stratum: st.ID, File: StratumFile{ID: info.FileID}, Line: info.InputStartLine + i}
+		for _, file := range st.Files {
+			if file.ID == info.FileID {
+				position.File = file
+			}
+		}
+		return position, true
+	}
+	return SourcePosition{}, false
+}
+
+// 类的SourceDebugExtension中的SMAP, 没有或者不是SMAP时返回nil
+func (f *ClassFile) SMAP() (*SMAP, error) {
+	for _, attr := range f.Attributes {
+		if a, ok := attr.(*SourceDebugExtension); ok {
+			text := DecodeModifiedUtf8(a.DebugExtension)
+			if !strings.HasPrefix(text, "SMAP") {
+				return nil, nil
+			}
+			return ParseSMAP(text)
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,162 @@
+package bytecode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Kotlin在每个stratum后面都写*E, 第二个stratum也要解析
+func TestClassSMAP(t *testing.T) {
+	f, _ := parseTestClass(t, "FooKt.class")
+	smap, err := f.SMAP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if smap == nil {
+		t.Fatal("SMAP is missing")
+	}
+	if smap.OutputFile != "Foo.kt" || smap.DefaultStratum != "Kotlin" || len(smap.Strata) != 2 {
+		t.Fatalf("unexpected SMAP %+v", smap)
+	}
+	if got := smap.Strata[0].Files[1]; got != (StratumFile{ID: 2, Name: "Inline.kt", Path: "com/acme/InlineKt"}) {
+		t.Errorf("file is %+v", got)
+	}
+	tests := []struct {
+		line    int
+		stratum string
+		want    string
+	}{
+		{3, "", "Foo.kt:3"},
+		{22, "", "Inline.kt:6"},
+		{22, "Kotlin", "Inline.kt:6"},
+		{21, "KotlinDebug", "Foo.kt:7"},
+		{23, "KotlinDebug", "Foo.kt:7"},
+		{5, STRATUM_JAVA, "Foo.kt:5"},
+		{25, "", ""},
+		{3, "Unknown", ""},
+	}
+	for _, test := range tests {
+		position, ok := smap.Translate(test.line, test.stratum)
+		if got := position.String(); ok != (test.want != "") || ok && got != test.want {
+			t.Errorf("line %d in %q: got %s %v, want %s", test.line, test.stratum, got, ok, test.want)
+		}
+	}
+
+	// 行尾是\r\n时结果相同
+	var text string
+	for _, attr := range f.Attributes {
+		if a, ok := attr.(*SourceDebugExtension); ok {
+			text = string(a.DebugExtension)
+		}
+	}
+	crlf, err := ParseSMAP(strings.ReplaceAll(text, "\n", "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(crlf, smap) {
+		t.Error("SMAP with \\r\\n differs")
+	}
+
+	f, _ = parseTestClass(t, "Box.class")
+	if smap, err := f.SMAP(); smap != nil || err != nil {
+		t.Errorf("expect no SMAP, got %v %v", smap, err)
+	}
+}
+
+// Hi.java由Hi.jsp生成, Hi.jsp又由Hi.src生成. Tier把Java的两行映射到Hi.jsp的一行
+const EMBEDDED_SMAP = `SMAP
+Hi.java
+Tier
+*O Tier
+SMAP
+Hi.jsp
+Source
+*S Source
+*F
+1 Hi.src
+*L
+1#1,5:10
+*E
+*C Tier
+*S Tier
+*F
++ 1 Hi.jsp
+web/Hi.jsp
+*L
+10#1,5:100,2
+*V
+acme
+  anything *goes here
+*X custom
+ignored
+*E
+`
+
+func TestEmbeddedSMAP(t *testing.T) {
+	smap, err := ParseSMAP(EMBEDDED_SMAP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(smap.Embedded) != 1 || smap.Embedded[0].Stratum != "Tier" || len(smap.Embedded[0].SMAPs) != 1 ||
+		smap.Embedded[0].SMAPs[0].OutputFile != "Hi.jsp" {
+		t.Fatalf("unexpected embedded SMAPs %+v", smap.Embedded)
+	}
+	if want := []Vendor{{ID: "acme", Lines: []string{"  anything *goes here"}}}; !reflect.DeepEqual(smap.Vendors, want) {
+		t.Errorf("vendors are %+v", smap.Vendors)
+	}
+	if len(smap.Strata) != 1 || smap.Strata[0].Lines[0] != (LineInfo{InputStartLine: 10, FileID: 1, RepeatCount: 5, OutputStartLine: 100, OutputLineIncrement: 2}) {
+		t.Errorf("unexpected strata %+v", smap.Strata)
+	}
+	tests := []struct {
+		line    int
+		stratum string
+		want    string
+	}{
+		{100, "", "Hi.jsp:10"},
+		{103, "Tier", "Hi.jsp:11"},
+		{109, "Tier", "Hi.jsp:14"},
+		{110, "Tier", ""},
+		{103, "Source", "Hi.src:2"},
+		{100, "Source", "Hi.src:1"},
+		{99, "Source", ""},
+	}
+	for _, test := range tests {
+		position, ok := smap.Translate(test.line, test.stratum)
+		if got := position.String(); ok != (test.want != "") || ok && got != test.want {
+			t.Errorf("line %d in %q: got %s %v, want %s", test.line, test.stratum, got, ok, test.want)
+		}
+	}
+	if position, _ := smap.Translate(103, ""); position.File.Path != "web/Hi.jsp" {
+		t.Errorf("path is %q", position.File.Path)
+	}
+}
+
+// OutputLineIncrement为0时输入的几行都对应同一个输出行, 以第一行为准
+func TestStratumTranslateZeroIncrement(t *testing.T) {
+	st := &Stratum{ID: "S", Lines: []LineInfo{{InputStartLine: 1, FileID: 1, RepeatCount: 3, OutputStartLine: 50}}}
+	if position, ok := st.Translate(50); !ok || position.Line != 1 {
+		t.Errorf("line 50: got %v %v", position, ok)
+	}
+	if _, ok := st.Translate(51); ok {
+		t.Error("line 51 should not be mapped")
+	}
+}
+
+func TestParseSMAPErrors(t *testing.T) {
+	for _, text := range []string{
+		"NOTSMAP\nFoo.kt\nKotlin\n",
+		"SMAP\nFoo.kt\n",
+		"SMAP\nFoo.kt\nKotlin\n*F\n1 Foo.kt\n",
+		"SMAP\nFoo.kt\nKotlin\n*S Kotlin\n*L\nabc\n*E\n",
+		"SMAP\nFoo.kt\nKotlin\n*S Kotlin\n*F\n+ 1 Foo.kt\n",
+		"SMAP\nFoo.kt\nKotlin\nFoo\n",
+		"SMAP\nFoo.kt\nKotlin\n*O JSP\nSMAP\nA.jsp\nJSP\n*S JSP\n*E\n*C Other\n*E\n",
+		"SMAP\nFoo.kt\nKotlin\n*O JSP\nSMAP\nA.jsp\nJSP\n*S JSP\n",
+		"SMAP\nFoo.kt\nKotlin\n*O JSP\n*S JSP\n*E\n",
+	} {
+		if _, err := ParseSMAP(text); err == nil {
+			t.Errorf("expect an error for %q", text)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package bytecode
+
+import (
+	"fmt"
+
+	"class-file-parser/i18n"
+)
+
+// target_info的形式, 参见JVMS 4.7.20.1
+type TargetKind uint8
+
+const (
+	TARGET_TYPE_PARAMETER       TargetKind = iota // 0x00 0x01
+	TARGET_SUPERTYPE                              // 0x10
+	TARGET_TYPE_PARAMETER_BOUND                   // 0x11 0x12
+	TARGET_EMPTY                                  // 0x13 0x14 0x15
+	TARGET_FORMAL_PARAMETER                       // 0x16
+	TARGET_THROWS                                 // 0x17
+	TARGET_LOCALVAR                               // 0x40 0x41, 局部变量和try-with-resources的资源变量
+	TARGET_CATCH                                  // 0x42
+	TARGET_OFFSET                                 // 0x43 0x44 0x45 0x46
+	TARGET_TYPE_ARGUMENT                          // 0x47 - 0x4B
+	TARGET_UNKNOWN
+)
+
+var targetKindNames = []string{"typeParameter", "supertype", "typeParameterBound", "empty", "formalParameter",
+	"throws", "localvar", "catch", "offset", "typeArgument", "unknown"}
+
+func (k TargetKind) String() string {
+	if int(k) < len(targetKindNames) {
+		return targetKindNames[k]
+	}
+	return targetKindNames[TARGET_UNKNOWN]
+}
+
+func TargetKindOf(targetType uint8) TargetKind {
+	switch targetType {
+	case 0x00, 0x01:
+		return TARGET_TYPE_PARAMETER
+	case 0x10:
+		return TARGET_SUPERTYPE
+	case 0x11, 0x12:
+		return TARGET_TYPE_PARAMETER_BOUND
+	case 0x13, 0x14, 0x15:
+		return TARGET_EMPTY
+	case 0x16:
+		return TARGET_FORMAL_PARAMETER
+	case 0x17:
+		return TARGET_THROWS
+	case 0x40, 0x41:
+		return TARGET_LOCALVAR
+	case 0x42:
+		return TARGET_CATCH
+	case 0x43, 0x44, 0x45, 0x46:
+		return TARGET_OFFSET
+	case 0x47, 0x48, 0x49, 0x4A, 0x4B:
+		return TARGET_TYPE_ARGUMENT
+	}
+	return TARGET_UNKNOWN
+}
+
+func (t *TypeAnnotation) Kind() TargetKind {
+	return TargetKindOf(t.TargetType)
+}
+
+// type_path中每一步的类型
+const (
+	PATH_ARRAY         = 0
+	PATH_NESTED        = 1
+	PATH_WILDCARD      = 2
+	PATH_TYPE_ARGUMENT = 3
+)
+
+var targetMessages = map[uint8]string{
+	0x00: "type_target.class_type_parameter",
+	0x01: "type_target.method_type_parameter",
+	0x11: "type_target.class_bound",
+	0x12: "type_target.method_bound",
+	0x13: "type_target.field",
+	0x14: "type_target.return",
+	0x15: "type_target.receiver",
+	0x16: "type_target.parameter",
+	0x17: "type_target.throws",
+	0x40: "type_target.local_variable",
+	0x41: "type_target.resource_variable",
+	0x42: "type_target.catch",
+	0x43: "type_target.instanceof",
+	0x44: "type_target.new",
+	0x45: "type_target.constructor_reference",
+	0x46: "type_target.method_reference",
+	0x47: "type_target.cast",
+	0x48: "type_target.constructor_invocation",
+	0x49: "type_target.method_invocation",
+	0x4A: "type_target.constructor_reference_argument",
+	0x4B: "type_target.method_reference_argument",
+}
+
+// 注解所在的位置, 例如method parameter 1, type argument 0 of array element.
+// code是注解所在方法的Code属性, 用于从LocalVariableTable中找到局部变量名, 可以为nil
+func (t *TypeAnnotation) Location(constantPool []ConstantPoolInfo, code *Code) string {
+	id := targetMessages[t.TargetType]
+	result := ""
+	switch t.Kind() {
+	case TARGET_TYPE_PARAMETER:
+		result = i18n.T(id, t.TypeParameterIndex)
+	case TARGET_SUPERTYPE:
+		result = i18n.T("type_target.interface", t.SupertypeIndex)
+		if t.SupertypeIndex == 65535 {
+			result = i18n.T("type_target.superclass")
+		}
+	case TARGET_TYPE_PARAMETER_BOUND:
+		result = i18n.T(id, t.BoundIndex, t.TypeParameterIndex)
+	case TARGET_EMPTY:
+		result = i18n.T(id)
+	case TARGET_FORMAL_PARAMETER:
+		result = i18n.T(id, t.FormalParameterIndex)
+	case TARGET_THROWS:
+		result = i18n.T(id, t.ThrowsTypeIndex)
+	case TARGET_LOCALVAR:
+		result = i18n.T(id, t.variables(constantPool, code))
+	case TARGET_CATCH:
+		result = i18n.T(id, t.ExceptionTableIndex)
+	case TARGET_OFFSET:
+		result = i18n.T(id, t.Offset)
+	case TARGET_TYPE_ARGUMENT:
+		result = i18n.T(id, t.TypeArgumentIndex, t.Offset)
+	default:
+		result = i18n.T("type_target.unknown", t.TargetType)
+	}
+	if path := t.TargetPath.String(); path != "" {
+		result += ", " + path
+	}
+	return result
+}
+
+// 一个局部变量可能对应多段代码, 每段都输出槽位和pc范围
+func (t *TypeAnnotation) variables(constantPool []ConstantPoolInfo, code *Code) string {
+	result := ""
+	for i, table := range t.Tables {
+		if i > 0 {
+			result += "; "
+		}
+		if name := variableName(constantPool, code, table.StartPc, table.Index); name != "" {
+			result += name + " "
+		}
+		result += i18n.T("type_target.variable_range", table.Index, table.StartPc, uint32(table.StartPc)+uint32(table.Length))
+	}
+	return result
+}
+
+func variableName(constantPool []ConstantPoolInfo, code *Code, pc, slot uint16) string {
+	if code == nil {
+		return ""
+	}
+	for _, attr := range code.Attributes {
+		if table, ok := attr.(*LocalVariableTable); ok {
+			for _, v := range table.LocalVariable {
+				if v.Index == slot && v.StartPc <= pc && uint32(pc) < uint32(v.StartPc)+uint32(v.Length) {
+					return constantUtf8(constantPool, v.NameIndex)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// 路径从最外层的类型开始, 输出时从最内层开始, 例如type argument 0 of array element
+func (t TypePath) String() string {
+	result := ""
+	for i, path := range t.Path {
+		step := path.String()
+		if i > 0 {
+			step = i18n.T("type_path.join", step, result)
+		}
+		result = step
+	}
+	return result
+}
+
+func (p Path) String() string {
+	switch p.TypePathKind {
+	case PATH_ARRAY:
+		return i18n.T("type_path.array")
+	case PATH_NESTED:
+		return i18n.T("type_path.nested")
+	case PATH_WILDCARD:
+		return i18n.T("type_path.wildcard")
+	case PATH_TYPE_ARGUMENT:
+		return i18n.T("type_path.type_argument", p.TypeArgumentIndex)
+	}
+	return fmt.Sprintf("path(%d)", p.TypePathKind)
+}
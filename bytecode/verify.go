@@ -0,0 +1,23 @@
+package bytecode
+
+import (
+	"fmt"
+)
+
+// 汇总常量池静态约束、常量池引用以及类文件结构约束的检查结果
+func (f *ClassFile) Verify() []Finding {
+	findings := make([]Finding, 0)
+	for _, err := range f.Validate() {
+		findings = append(findings, Finding{Severity: SEVERITY_ERROR, Rule: "constant-pool",
+			Location: fmt.Sprintf("constant #%d", err.Index), Message: err.Message})
+	}
+	for _, err := range f.CheckConstantPoolRefs() {
+		message := err.Message
+		if err.Index != 0 || message == "is zero" {
+			message = fmt.Sprintf("#%d %s", err.Index, message)
+		}
+		findings = append(findings, Finding{Severity: SEVERITY_ERROR, Rule: "constant-pool-ref",
+			Location: err.Location, Message: message})
+	}
+	return append(findings, f.CheckFormat()...)
+}
@@ -0,0 +1,171 @@
+package bytecode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 预览特性的次版本号, 参见JVMS 4.1
+const PREVIEW_MINOR_VERSION = 65535
+
+// 主版本号对应的JDK版本, 45对应1.0.2和1.1
+var releases = map[uint16]struct {
+	name string
+	lts  bool
+}{
+	46: {"1.2", false}, 47: {"1.3", false}, 48: {"1.4", false}, 49: {"1.5", false},
+	50: {"1.6", false}, 51: {"1.7", false}, 52: {"1.8", true}, 53: {"9", false},
+	54: {"10", false}, 55: {"11", true}, 56: {"12", false}, 57: {"13", false},
+	58: {"14", false}, 59: {"15", false}, 60: {"16", false}, 61: {"17", true},
+	62: {"18", false}, 63: {"19", false}, 64: {"20", false}, 65: {"21", true},
+	66: {"22", false}, 67: {"23", false}, 68: {"24", false}, 69: {"25", true},
+	70: {"26", false}, 71: {"27", false},
+}
+
+// JDK版本名称, 例如1.8、17, 未知的主版本号返回空字符串
+func ReleaseName(major uint16) string {
+	return releases[major].name
+}
+
+func IsLTS(major uint16) bool {
+	return releases[major].lts
+}
+
+// 解析--release的写法, 例如8、1.8、17, 返回对应的主版本号
+func ParseRelease(release string) (uint16, error) {
+	number := strings.TrimPrefix(release, "1.")
+	if n, err := strconv.Atoi(number); err == nil && (number == release || n <= 8) {
+		switch {
+		case n >= 2 && uint16(n)+44 <= MAX_MAJOR_VERSION:
+			return uint16(n) + 44, nil
+		case n == 1:
+			return 45, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown release %s", release)
+}
+
+// 类文件使用了JDK 12开始的预览特性, 只能在相同版本的JDK上使用--enable-preview运行
+func (f *ClassFile) IsPreview() bool {
+	return f.MajorVersion >= 56 && f.MinorVersion == PREVIEW_MINOR_VERSION
+}
+
+// 类文件中需要一定版本才能使用的特性
+type Requirement struct {
+	Feature  string
+	Location string
+	Major    uint16
+}
+
+func (r Requirement) String() string {
+	return fmt.Sprintf("%s (%s) requires %d.0", r.Feature, r.Location, r.Major)
+}
+
+// 作为预览特性时允许的最低主版本号
+var previewAttributes = map[string]uint16{"Record": 58, "PermittedSubclasses": 59}
+
+type requirements struct {
+	f      *ClassFile
+	result []Requirement
+	seen   map[string]bool
+}
+
+// 每种特性只记录第一次出现的位置
+func (r *requirements) add(feature, location string, major uint16) {
+	if major <= 45 || r.seen[feature] {
+		return
+	}
+	if preview, ok := previewAttributes[feature]; ok && r.f.IsPreview() && r.f.MajorVersion >= preview {
+		return
+	}
+	r.seen[feature] = true
+	r.result = append(r.result, Requirement{feature, location, major})
+}
+
+// 类文件使用到的特性及其需要的最低版本: 常量类型、属性、invokedynamic、接口中的非抽象方法等,
+// 版本超过类文件声明的版本时类文件不能被加载或者特性会被忽略
+func (f *ClassFile) Requirements() []Requirement {
+	r := &requirements{f: f, seen: make(map[string]bool)}
+	if f.AccessFlags&ACC_MODULE != 0 {
+		r.add("ACC_MODULE", "class", 53)
+	}
+	for i, item := range f.ConstantPool {
+		if item == nil {
+			continue
+		}
+		location := fmt.Sprintf("#%d", i)
+		switch item.TagValue() {
+		case CONSTANT_MethodHandle, CONSTANT_MethodType, CONSTANT_InvokeDynamic:
+			r.add("CONSTANT_"+item.TagName(), location, 51)
+		case CONSTANT_Module, CONSTANT_Package:
+			r.add("CONSTANT_"+item.TagName(), location, 53)
+		case CONSTANT_Dynamic:
+			r.add("CONSTANT_"+item.TagName(), location, 55)
+		}
+	}
+	r.attributes("class", f.Attributes)
+	for i := range f.Fields {
+		r.attributes("field "+f.Fields[i].Name(f.ConstantPool), f.Fields[i].Attributes)
+	}
+	for i := range f.Methods {
+		m := &f.Methods[i]
+		location := "method " + m.Name(f.ConstantPool) + m.Descriptor(f.ConstantPool)
+		if f.AccessFlags&ACC_INTERFACE != 0 && m.AccessFlags&METHOD_ACC_ABSTRACT == 0 && m.Name(f.ConstantPool) != "<clinit>" {
+			r.add("non-abstract interface method", location, 52)
+		}
+		r.attributes(location, m.Attributes)
+	}
+	return r.result
+}
+
+func (r *requirements) attributes(location string, attrs []AttributeInfo) {
+	for _, attr := range attrs {
+		if attr == nil {
+			continue
+		}
+		if rule, ok := attributeRules[attr.GetName()]; ok {
+			r.add(attr.GetName(), location, rule.major)
+		}
+		switch a := attr.(type) {
+		case *Code:
+			r.instructions(location, a)
+			r.attributes(location+" Code", a.Attributes)
+		case *Record:
+			for _, component := range a.RecordComponentInfo {
+				r.attributes(location+" component "+constantUtf8(r.f.ConstantPool, component.NameIndex), component.Attributes)
+			}
+		}
+	}
+}
+
+// 无法解析的字节码由verify报告
+func (r *requirements) instructions(location string, code *Code) {
+	instructions, err := code.Instructions()
+	if err != nil {
+		return
+	}
+	for i := range instructions {
+		ins := &instructions[i]
+		switch ins.Opcode {
+		case OPCODE_INVOKEDYNAMIC:
+			r.add("invokedynamic", fmt.Sprintf("%s pc %d", location, ins.Pc), 51)
+		case OPCODE_INVOKESTATIC, OPCODE_INVOKESPECIAL:
+			if int(ins.Index) < len(r.f.ConstantPool) && r.f.ConstantPool[ins.Index] != nil &&
+				r.f.ConstantPool[ins.Index].TagValue() == CONSTANT_InterfaceMethodref {
+				r.add(ins.Name()+" InterfaceMethodref", fmt.Sprintf("%s pc %d", location, ins.Pc), 52)
+			}
+		}
+	}
+}
+
+// 需要的最低主版本号, 不低于类文件声明的版本
+func (f *ClassFile) RequiredMajor() uint16 {
+	major := f.MajorVersion
+	for _, r := range f.Requirements() {
+		if r.Major > major {
+			major = r.Major
+		}
+	}
+	return major
+}
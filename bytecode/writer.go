@@ -0,0 +1,456 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// 按结构序列化类文件, 各种数量和长度按切片重新计算, 不使用解析时记录的值.
+// 不认识的属性按原始内容写回, nil属性跳过
+func (f *ClassFile) Bytes() ([]byte, error) {
+	w := &classWriter{}
+	w.u4(f.Magic)
+	w.u2(f.MinorVersion)
+	w.u2(f.MajorVersion)
+	w.count("constant_pool_count", len(f.ConstantPool))
+	for i := 1; i < len(f.ConstantPool); i++ {
+		item := f.ConstantPool[i]
+		if item == nil {
+			// long和double之后的位置
+			if _, ok := ConstantAt(f.ConstantPool, uint16(i-1)).(*ConstantLong); ok {
+				continue
+			}
+			if _, ok := ConstantAt(f.ConstantPool, uint16(i-1)).(*ConstantDouble); ok {
+				continue
+			}
+			w.fail("constant #%d is empty", i)
+			continue
+		}
+		w.constant(i, item)
+	}
+	w.u2(f.AccessFlags)
+	w.u2(f.ThisClass)
+	w.u2(f.SuperClass)
+	w.count("interfaces_count", len(f.Interfaces))
+	for _, index := range f.Interfaces {
+		w.u2(index)
+	}
+	w.count("fields_count", len(f.Fields))
+	for i := range f.Fields {
+		field := &f.Fields[i]
+		w.member(field.AccessFlags, field.NameIndex, field.DescriptorIndex, field.Attributes)
+	}
+	w.count("methods_count", len(f.Methods))
+	for i := range f.Methods {
+		method := &f.Methods[i]
+		w.member(method.AccessFlags, method.NameIndex, method.DescriptorIndex, method.Attributes)
+	}
+	w.attributes(f.Attributes)
+	if w.err != nil {
+		return nil, w.err
+	}
+	return w.buf, nil
+}
+
+// 只保留第一个错误
+type classWriter struct {
+	buf []byte
+	err error
+}
+
+func (w *classWriter) fail(format string, args ...interface{}) {
+	if w.err == nil {
+		w.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (w *classWriter) u1(v uint8) {
+	w.buf = append(w.buf, v)
+}
+
+func (w *classWriter) u2(v uint16) {
+	w.buf = binary.BigEndian.AppendUint16(w.buf, v)
+}
+
+func (w *classWriter) u4(v uint32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, v)
+}
+
+func (w *classWriter) count(name string, n int) {
+	if n > math.MaxUint16 {
+		w.fail("%s %d exceeds %d", name, n, math.MaxUint16)
+	}
+	w.u2(uint16(n))
+}
+
+func (w *classWriter) count1(name string, n int) {
+	if n > math.MaxUint8 {
+		w.fail("%s %d exceeds %d", name, n, math.MaxUint8)
+	}
+	w.u1(uint8(n))
+}
+
+func (w *classWriter) constant(index int, item ConstantPoolInfo) {
+	w.u1(item.TagValue())
+	switch c := item.(type) {
+	case *ConstantUtf8:
+		w.count(fmt.Sprintf("constant #%d length", index), len(c.Value))
+		w.buf = append(w.buf, c.Value...)
+	case *ConstantInteger:
+		w.u4(uint32(c.Value))
+	case *ConstantFloat:
+		w.u4(math.Float32bits(c.Value))
+	case *ConstantLong:
+		w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(c.Value))
+	case *ConstantDouble:
+		w.buf = binary.BigEndian.AppendUint64(w.buf, math.Float64bits(c.Value))
+	case *ConstantClass:
+		w.u2(c.NameIndex)
+	case *ConstantString:
+		w.u2(c.StringIndex)
+	case *ConstantFieldref:
+		w.u2(c.ClassIndex)
+		w.u2(c.NameAndTypeIndex)
+	case *ConstantMethodref:
+		w.u2(c.ClassIndex)
+		w.u2(c.NameAndTypeIndex)
+	case *ConstantInterfaceMethodref:
+		w.u2(c.ClassIndex)
+		w.u2(c.NameAndTypeIndex)
+	case *ConstantNameAndType:
+		w.u2(c.NameIndex)
+		w.u2(c.DescriptorIndex)
+	case *ConstantMethodHandle:
+		w.u1(c.ReferenceKind)
+		w.u2(c.ReferenceIndex)
+	case *ConstantMethodType:
+		w.u2(c.DescriptorIndex)
+	case *ConstantDynamic:
+		w.u2(c.BootstrapMethodAttrIndex)
+		w.u2(c.NameAndTypeIndex)
+	case *ConstantInvokeDynamic:
+		w.u2(c.BootstrapMethodAttrIndex)
+		w.u2(c.NameAndTypeIndex)
+	case *ConstantModule:
+		w.u2(c.NameIndex)
+	case *ConstantPackage:
+		w.u2(c.NameIndex)
+	default:
+		w.fail("constant #%d has unknown type %s", index, item.TagName())
+	}
+}
+
+func (w *classWriter) member(accessFlags, nameIndex, descriptorIndex uint16, attrs []AttributeInfo) {
+	w.u2(accessFlags)
+	w.u2(nameIndex)
+	w.u2(descriptorIndex)
+	w.attributes(attrs)
+}
+
+func (w *classWriter) attributes(attrs []AttributeInfo) {
+	n := 0
+	for _, attr := range attrs {
+		if attr != nil {
+			n++
+		}
+	}
+	w.count("attributes_count", n)
+	for _, attr := range attrs {
+		if attr == nil {
+			continue
+		}
+		body := &classWriter{}
+		body.attribute(attr)
+		if body.err != nil {
+			w.fail("%s: %v", attr.GetName(), body.err)
+		}
+		w.u2(attr.base().NameIndex)
+		w.u4(uint32(len(body.buf)))
+		w.buf = append(w.buf, body.buf...)
+	}
+}
+
+func (w *classWriter) attribute(attr AttributeInfo) {
+	switch a := visibleAttribute(attr).(type) {
+	case *ConstantValue:
+		w.u2(a.ConstantValueIndex)
+	case *Code:
+		w.u2(a.MaxStack)
+		w.u2(a.MaxLocals)
+		w.u4(uint32(len(a.Code)))
+		w.buf = append(w.buf, a.Code...)
+		w.count("exception_table_length", len(a.Table))
+		for _, e := range a.Table {
+			w.u2(e.StartPc)
+			w.u2(e.EndPc)
+			w.u2(e.HandlerPc)
+			w.u2(e.CatchType)
+		}
+		w.attributes(a.Attributes)
+	case *StackMapTable:
+		w.count("number_of_entries", len(a.Entries))
+		for i := range a.Entries {
+			w.frame(&a.Entries[i])
+		}
+	case *Exceptions:
+		w.indexes("number_of_exceptions", a.ExceptionIndexTable)
+	case *InnerClasses:
+		w.count("number_of_classes", len(a.Classes))
+		for _, c := range a.Classes {
+			w.u2(c.InnerClassIndex)
+			w.u2(c.OuterClassIndex)
+			w.u2(c.InnerNameIndex)
+			w.u2(c.InnerClassAccessFlags)
+		}
+	case *EnclosingMethod:
+		w.u2(a.ClassIndex)
+		w.u2(a.MethodIndex)
+	case *Synthetic, *Deprecated:
+	case *Signature:
+		w.u2(a.SignatureIndex)
+	case *SourceFile:
+		w.u2(a.SourceFileIndex)
+	case *SourceDebugExtension:
+		w.buf = append(w.buf, a.DebugExtension...)
+	case *LineNumberTable:
+		w.count("line_number_table_length", len(a.LineNumber))
+		for _, l := range a.LineNumber {
+			w.u2(l.StartPc)
+			w.u2(l.LineNumber)
+		}
+	case *LocalVariableTable:
+		w.count("local_variable_table_length", len(a.LocalVariable))
+		for _, v := range a.LocalVariable {
+			w.u2(v.StartPc)
+			w.u2(v.Length)
+			w.u2(v.NameIndex)
+			w.u2(v.DescriptorIndex)
+			w.u2(v.Index)
+		}
+	case *LocalVariableTypeTable:
+		w.count("local_variable_type_table_length", len(a.LocalVariableType))
+		for _, v := range a.LocalVariableType {
+			w.u2(v.StartPc)
+			w.u2(v.Length)
+			w.u2(v.NameIndex)
+			w.u2(v.SignatureIndex)
+			w.u2(v.Index)
+		}
+	case *RuntimeVisibleAnnotations:
+		w.annotations(a.Annotations)
+	case *RuntimeVisibleParameterAnnotations:
+		w.count1("num_parameters", len(a.ParameterAnnotations))
+		for _, p := range a.ParameterAnnotations {
+			w.annotations(p.Annotations)
+		}
+	case *RuntimeVisibleTypeAnnotations:
+		w.count("num_annotations", len(a.Annotations))
+		for i := range a.Annotations {
+			w.typeAnnotation(&a.Annotations[i])
+		}
+	case *AnnotationDefault:
+		w.elementValue(&a.DefaultValue)
+	case *BootstrapMethods:
+		w.count("num_bootstrap_methods", len(a.Methods))
+		for _, m := range a.Methods {
+			w.u2(m.BootstrapMethodRef)
+			w.indexes("num_bootstrap_arguments", m.Arguments)
+		}
+	case *MethodParameters:
+		w.count1("parameters_count", len(a.Parameters))
+		for _, p := range a.Parameters {
+			w.u2(p.NameIndex)
+			w.u2(p.AccessFlags)
+		}
+	case *Module:
+		w.module(a)
+	case *ModulePackages:
+		w.indexes("package_count", a.PackageIndex)
+	case *ModuleMainClass:
+		w.u2(a.MainClassIndex)
+	case *NestHost:
+		w.u2(a.HostClassIndex)
+	case *NestMembers:
+		w.indexes("number_of_classes", a.Classes)
+	case *PermittedSubclasses:
+		w.indexes("number_of_classes", a.Classes)
+	case *Record:
+		w.count("components_count", len(a.RecordComponentInfo))
+		for _, c := range a.RecordComponentInfo {
+			w.u2(c.NameIndex)
+			w.u2(c.DescriptorIndex)
+			w.attributes(c.Attributes)
+		}
+	case *UnknownAttribute:
+		w.buf = append(w.buf, a.Info...)
+	default:
+		w.fail("unsupported attribute")
+	}
+}
+
+func (w *classWriter) indexes(name string, indexes []uint16) {
+	w.count(name, len(indexes))
+	for _, index := range indexes {
+		w.u2(index)
+	}
+}
+
+func (w *classWriter) frame(frame *StackMapFrame) {
+	w.u1(frame.FrameType)
+	switch t := frame.FrameType; {
+	case t <= 63:
+	case t <= 127:
+		w.stackItem(frame)
+	case t == 247:
+		w.u2(frame.OffsetDelta)
+		w.stackItem(frame)
+	case t >= 248 && t <= 251:
+		w.u2(frame.OffsetDelta)
+	case t >= 252 && t <= 254:
+		w.u2(frame.OffsetDelta)
+		w.verificationTypes(frame.Locals)
+	case t == 255:
+		w.u2(frame.OffsetDelta)
+		w.count("number_of_locals", len(frame.Locals))
+		w.verificationTypes(frame.Locals)
+		w.count("number_of_stack_items", len(frame.Stacks))
+		w.verificationTypes(frame.Stacks)
+	default:
+		w.fail("reserved stack map frame type %d", t)
+	}
+}
+
+// same_locals_1_stack_item的帧只有一个栈元素
+func (w *classWriter) stackItem(frame *StackMapFrame) {
+	if len(frame.Stacks) != 1 {
+		w.fail("stack map frame type %d needs 1 stack item, but has %d", frame.FrameType, len(frame.Stacks))
+		return
+	}
+	w.verificationTypes(frame.Stacks)
+}
+
+func (w *classWriter) verificationTypes(types []VerificationTypeInfo) {
+	for _, v := range types {
+		w.u1(v.Tag)
+		switch v.Tag {
+		case 7:
+			w.u2(v.CpoolIndex)
+		case 8:
+			w.u2(v.Offset)
+		}
+	}
+}
+
+func (w *classWriter) annotations(annotations []Annotation) {
+	w.count("num_annotations", len(annotations))
+	for i := range annotations {
+		w.annotation(&annotations[i])
+	}
+}
+
+func (w *classWriter) annotation(a *Annotation) {
+	w.u2(a.TypeIndex)
+	w.elementValuePairs(a.ValuePairs)
+}
+
+func (w *classWriter) elementValuePairs(pairs []ElementValuePairs) {
+	w.count("num_element_value_pairs", len(pairs))
+	for i := range pairs {
+		w.u2(pairs[i].ElementNameIndex)
+		w.elementValue(&pairs[i].ElementValue)
+	}
+}
+
+func (w *classWriter) elementValue(e *ElementValue) {
+	w.u1(e.Tag)
+	switch e.Tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		w.u2(e.ConstValueIndex)
+	case 'e':
+		w.u2(e.TypeNameIndex)
+		w.u2(e.ConstNameIndex)
+	case 'c':
+		w.u2(e.ClassInfoIndex)
+	case '@':
+		w.annotation(&e.AnnotationValue)
+	case '[':
+		w.count("num_values", len(e.Values))
+		for i := range e.Values {
+			w.elementValue(&e.Values[i])
+		}
+	default:
+		w.fail("unknown element value tag %d", e.Tag)
+	}
+}
+
+func (w *classWriter) typeAnnotation(t *TypeAnnotation) {
+	w.u1(t.TargetType)
+	switch t.Kind() {
+	case TARGET_TYPE_PARAMETER:
+		w.u1(t.TypeParameterIndex)
+	case TARGET_SUPERTYPE:
+		w.u2(t.SupertypeIndex)
+	case TARGET_TYPE_PARAMETER_BOUND:
+		w.u1(t.TypeParameterIndex)
+		w.u1(t.BoundIndex)
+	case TARGET_EMPTY:
+	case TARGET_FORMAL_PARAMETER:
+		w.u1(t.FormalParameterIndex)
+	case TARGET_THROWS:
+		w.u2(t.ThrowsTypeIndex)
+	case TARGET_LOCALVAR:
+		w.count("table_length", len(t.Tables))
+		for _, table := range t.Tables {
+			w.u2(table.StartPc)
+			w.u2(table.Length)
+			w.u2(table.Index)
+		}
+	case TARGET_CATCH:
+		w.u2(t.ExceptionTableIndex)
+	case TARGET_OFFSET:
+		w.u2(t.Offset)
+	case TARGET_TYPE_ARGUMENT:
+		w.u2(t.Offset)
+		w.u1(t.TypeArgumentIndex)
+	default:
+		w.fail("unknown type annotation target type 0x%02x", t.TargetType)
+	}
+	w.count1("path_length", len(t.TargetPath.Path))
+	for _, p := range t.TargetPath.Path {
+		w.u1(p.TypePathKind)
+		w.u1(p.TypeArgumentIndex)
+	}
+	w.u2(t.TypeIndex)
+	w.elementValuePairs(t.ValuePairs)
+}
+
+func (w *classWriter) module(m *Module) {
+	w.u2(m.ModuleNameIndex)
+	w.u2(m.ModuleFlags)
+	w.u2(m.ModuleVersionIndex)
+	w.count("requires_count", len(m.Requires))
+	for _, r := range m.Requires {
+		w.u2(r.RequiresIndex)
+		w.u2(r.RequiresFlags)
+		w.u2(r.RequiresVersionIndex)
+	}
+	w.count("exports_count", len(m.Exports))
+	for _, e := range m.Exports {
+		w.u2(e.ExportsIndex)
+		w.u2(e.ExportsFlags)
+		w.indexes("exports_to_count", e.ExportsToIndex)
+	}
+	w.count("opens_count", len(m.Opens))
+	for _, o := range m.Opens {
+		w.u2(o.OpenIndex)
+		w.u2(o.OpenFlags)
+		w.indexes("opens_to_count", o.OpenToIndex)
+	}
+	w.indexes("uses_count", m.UsesIndex)
+	w.count("provides_count", len(m.Provides))
+	for _, p := range m.Provides {
+		w.u2(p.ProvidesIndex)
+		w.indexes("provides_with_count", p.ProvidesWithIndex)
+	}
+}
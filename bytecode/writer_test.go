@@ -0,0 +1,62 @@
+package bytecode
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 解析之后原样写出, 包括不认识的属性, 应该与原来的字节完全相同
+func TestBytesRoundTrip(t *testing.T) {
+	for name, data := range loadTestClasses(t) {
+		f, err := ParseClassFile(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		output, err := f.Bytes()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(output, data) {
+			t.Errorf("%s: %d bytes written, %d bytes read, first difference at %d", name, len(output), len(data), firstDifference(output, data))
+		}
+	}
+}
+
+func TestBytesKeepsUnknownAttributes(t *testing.T) {
+	data := loadTestClasses(t)["Lambdas.class"]
+	f, err := ParseClassFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ParseClassFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, attr := range g.Attributes {
+		if unknown, ok := attr.(*UnknownAttribute); ok && unknown.GetName() == "ScalaSig" {
+			found = bytes.Equal(unknown.Info, []byte{5, 0, 0})
+		}
+	}
+	if !found {
+		t.Error("ScalaSig attribute is lost")
+	}
+}
+
+func firstDifference(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) < len(b) {
+		return len(a)
+	}
+	return len(b)
+}
@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"class-file-parser/i18n"
+)
+
+const (
+	EXIT_OK       = 0
+	EXIT_FINDINGS = 1 //verify发现错误、diff存在差异或者search没有匹配
+	EXIT_ERROR    = 2 //参数错误, 或者输入无法读取、解析
+)
+
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name  string
+	args  string
+	usage string //消息ID
+	run   func(e *env, args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"dump", "<input>...", "cmd.dump", runDump},
+		{"disasm", "<input>...", "cmd.disasm", runDisasm},
+		{"constants", "<input>...", "cmd.constants", runConstants},
+		{"members", "<input>...", "cmd.members", runMembers},
+		{"verify", "<input>...", "cmd.verify", runVerify},
+		{"deps", "<input>...", "cmd.deps", runDeps},
+		{"diff", "<old> <new>", "cmd.diff", runDiff},
+		{"compat", "<old> <new>", "cmd.compat", runCompat},
+		{"repro", "<a.jar> <b.jar>", "cmd.repro", runRepro},
+		{"versions", "[-max-release N] <input>...", "cmd.versions", runVersions},
+		{"search", "<pattern> <input>...", "cmd.search", runSearch},
+		{"stats", "<input>...", "cmd.stats", runStats},
+		{"schema", "[validate <json>...]", "cmd.schema", runSchema},
+		{"stub", "[-d dir] <input>...", "cmd.stub", runStub},
+		{"forbidden", "-rules file <input>...", "cmd.forbidden", runForbidden},
+		{"sniff", "build [-o file] [-release N] <jdk>... | check [-s file] [-cp classpath] <input>...", "cmd.sniff", runSniff},
+		{"index", "build [-o file] [-cp classpath] <input>... | query [-i file] [-meta] [annotation]...", "cmd.index", runIndex},
+		{"retrace", "[-mapping mapping.txt] [-cp classpath] [stacktrace]...", "cmd.retrace", runRetrace},
+		{"remap", "-mapping file [-from ns] [-to ns] [-reverse] [-cp classpath] -d dir <input>...", "cmd.remap", runRemap},
+	}
+}
+
+// 执行命令行, 返回进程的退出码
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	if err := i18n.SetLanguage(language(args)); err != nil {
+		fmt.Fprintln(e.stderr, err.Error())
+		return EXIT_ERROR
+	}
+	// -lang可以出现在命令之前
+	for len(args) > 0 && langFlag(args[0]) != "" {
+		if langFlag(args[0]) == "lang" && len(args) > 1 {
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		usage(e.stderr)
+		return EXIT_ERROR
+	}
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage(e.stdout)
+		return EXIT_OK
+	}
+	// 兼容旧的用法: -file fileName.class
+	if strings.HasPrefix(name, "-") {
+		return runDump(e, args)
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(e, args[1:])
+		}
+	}
+	fmt.Fprintln(e.stderr, i18n.T("error.unknown_command", name))
+	usage(e.stderr)
+	return EXIT_ERROR
+}
+
+// 参数是-lang或者-lang=value时返回去掉-之后的部分
+func langFlag(arg string) string {
+	name := strings.TrimLeft(arg, "-")
+	if name == arg || (name != "lang" && !strings.HasPrefix(name, "lang=")) {
+		return ""
+	}
+	return name
+}
+
+// 帮助信息在解析参数之前输出, 所以提前从参数中找到-lang, 没有时使用LANG
+func language(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		switch name := langFlag(arg); {
+		case name == "lang" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(name, "lang="):
+			return strings.TrimPrefix(name, "lang=")
+		}
+	}
+	return i18n.FromEnv()
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, i18n.T("usage.main"))
+	fmt.Fprintf(w, "\n%s\n\n%s\n", i18n.T("usage.inputs"), i18n.T("usage.commands"))
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, i18n.T(c.usage))
+	}
+	fmt.Fprintf(w, "\n%s\n", i18n.T("usage.command_help"))
+}
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// 所有命令共用的参数
+type options struct {
+	*env
+	flags   *flag.FlagSet
+	format  string
+	formats []string //-format允许的值
+	filter  string
+	files   stringList
+	workers int
+	summary bool
+}
+
+func newOptions(e *env, name string) *options {
+	o := &options{env: e, flags: flag.NewFlagSet(name, flag.ContinueOnError), formats: []string{"text", "json"}}
+	o.flags.SetOutput(e.stderr)
+	o.flags.StringVar(&o.format, "format", "text", i18n.T("flag.format"))
+	o.flags.StringVar(&o.filter, "filter", "", i18n.T("flag.filter"))
+	o.flags.Var(&o.files, "file", i18n.T("flag.file"))
+	o.flags.IntVar(&o.workers, "j", runtime.NumCPU(), i18n.T("flag.j"))
+	o.flags.BoolVar(&o.summary, "summary", false, i18n.T("flag.summary"))
+	o.flags.String("lang", i18n.Language(), i18n.T("flag.lang"))
+	o.flags.Usage = func() {
+		for _, c := range commands {
+			if c.name == name {
+				fmt.Fprintf(e.stderr, "%s\n%s\n\n%s\n", i18n.T("usage.command", c.name, c.args), i18n.T(c.usage), i18n.T("usage.flags"))
+			}
+		}
+		o.flags.PrintDefaults()
+	}
+	return o
+}
+
+// 参数和输入可以交替出现, 返回所有的输入
+func (o *options) parse(args []string) ([]string, error) {
+	inputs := make([]string, 0)
+	for {
+		if err := o.flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = o.flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		inputs = append(inputs, args[0])
+		args = args[1:]
+	}
+	for _, format := range o.formats {
+		if o.format == format {
+			return append(o.files, inputs...), nil
+		}
+	}
+	err := errors.New(i18n.T("error.unknown_format", o.format))
+	fmt.Fprintln(o.stderr, err.Error())
+	return nil, err
+}
+
+// 解析参数, 至少需要一个输入
+func (o *options) parseInputs(args []string) ([]string, error) {
+	inputs, err := o.parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		fmt.Fprintln(o.stderr, i18n.T("error.no_input"))
+		o.flags.Usage()
+		return nil, errNoInput
+	}
+	return inputs, nil
+}
+
+var errNoInput = errors.New("no input")
+
+// -h只输出帮助, 不是错误
+func parseStatus(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return EXIT_OK
+	}
+	return EXIT_ERROR
+}
+
+func (o *options) json() bool {
+	return o.format == "json"
+}
+
+// 报告问题的命令还可以输出SARIF, 需要在解析参数之前调用
+func (o *options) allowSARIF() {
+	o.formats = append(o.formats, "sarif")
+	o.flags.Lookup("format").Usage = i18n.T("flag.format_sarif")
+}
+
+func (o *options) sarif() bool {
+	return o.format == "sarif"
+}
+
+func (o *options) writeJSON(v interface{}) int {
+	encoder := json.NewEncoder(o.stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.encode_json", err.Error()))
+		return EXIT_ERROR
+	}
+	return EXIT_OK
+}
+
+func maxStatus(status ...int) int {
+	result := EXIT_OK
+	for _, s := range status {
+		if s > result {
+			result = s
+		}
+	}
+	return result
+}
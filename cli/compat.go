@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/compat"
+	"class-file-parser/i18n"
+	"class-file-parser/sarif"
+)
+
+// 检查新版本是否与旧版本二进制兼容, 存在不兼容的变化时退出码为1
+func runCompat(e *env, args []string) int {
+	o := newOptions(e, "compat")
+	o.allowSARIF()
+	inputs, err := o.parse(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	if len(inputs) != 2 {
+		fmt.Fprintln(o.stderr, i18n.T("error.compat_inputs"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	oldClasses, oldStatus := o.load(inputs[:1])
+	newClasses, newStatus := o.load(inputs[1:])
+	status := maxStatus(oldStatus, newStatus)
+
+	problems := compat.Check(classFiles(oldClasses), classFiles(newClasses))
+	switch {
+	case o.json():
+		status = maxStatus(status, o.writeJSON(problems))
+	case o.sarif():
+		// 位置在新版本的类中, 删除的类在旧版本中
+		files := make(map[string]*bytecode.ClassFile)
+		for _, classes := range [][]Class{oldClasses, newClasses} {
+			for _, c := range classes {
+				files[c.File.ClassName()] = c.File
+			}
+		}
+		log, locator := sarif.New(), newSourceLocator()
+		for _, p := range problems {
+			log.Add(p.Rule, "", sarif.LEVEL_ERROR, p.String(), locator.classLocation(p.Class, files[p.Class], p.Member))
+		}
+		status = maxStatus(status, o.writeJSON(log))
+	default:
+		for _, problem := range problems {
+			fmt.Fprintln(o.stdout, problem.String())
+		}
+	}
+	if len(problems) > 0 {
+		status = maxStatus(status, EXIT_FINDINGS)
+	}
+	return status
+}
+
+func classFiles(classes []Class) []*bytecode.ClassFile {
+	result := make([]*bytecode.ClassFile, 0, len(classes))
+	for _, c := range classes {
+		result = append(result, c.File)
+	}
+	return result
+}
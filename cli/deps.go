@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"class-file-parser/deps"
+	"class-file-parser/i18n"
+)
+
+type depsJSON struct {
+	Source       string   `json:"source"`
+	Archive      string   `json:"archive"`
+	Class        string   `json:"class"`
+	Dependencies []string `json:"dependencies"`
+}
+
+// 类级别的输出与原来相同, 每行一条依赖; 包和archive级别输出依赖所在的jar或者模块.
+// -internals只输出对JDK内部API的引用, 存在时退出码为1
+func runDeps(e *env, args []string) int {
+	o := newOptions(e, "deps")
+	level := o.flags.String("level", deps.LEVEL_CLASS, i18n.T("flag.level"))
+	excludeJDK := o.flags.Bool("exclude-jdk", false, i18n.T("flag.exclude_jdk"))
+	internals := o.flags.Bool("internals", false, i18n.T("flag.internals"))
+	dot := o.flags.Bool("dot", false, i18n.T("flag.dot"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	switch *level {
+	case deps.LEVEL_CLASS, deps.LEVEL_PACKAGE, deps.LEVEL_ARCHIVE:
+	default:
+		fmt.Fprintln(o.stderr, i18n.T("error.unknown_level", *level))
+		return EXIT_ERROR
+	}
+	classes, status := o.load(inputs)
+	input := make([]deps.Class, 0, len(classes))
+	for _, c := range classes {
+		input = append(input, deps.Class{Archive: archiveName(c.Source, inputs), File: c.File})
+	}
+	if *internals {
+		*level = deps.LEVEL_CLASS
+	}
+	result := deps.Analyze(input, *level, *excludeJDK)
+	if *internals {
+		found := make([]deps.Dependency, 0)
+		for _, d := range result {
+			if d.Internal {
+				found = append(found, d)
+			}
+		}
+		result = found
+		// 与输出格式无关, -dot时也要返回EXIT_FINDINGS
+		if len(result) > 0 {
+			status = maxStatus(status, EXIT_FINDINGS)
+		}
+	}
+
+	switch {
+	case *dot:
+		writeDot(o, result, *level)
+	case *internals:
+		if o.json() {
+			status = maxStatus(status, o.writeJSON(result))
+		} else {
+			for _, d := range result {
+				line := fmt.Sprintf("%s -> %s  %s", d.From, d.To, i18n.T("deps.internal", d.ToArchive))
+				if replacement := deps.Replacement(d.To); replacement != "" {
+					line += ", " + i18n.T("deps.replacement", replacement)
+				}
+				fmt.Fprintln(o.stdout, line)
+			}
+		}
+	case *level == deps.LEVEL_CLASS:
+		status = maxStatus(status, writeClassDeps(o, classes, input, result))
+	case o.json():
+		status = maxStatus(status, o.writeJSON(result))
+	default:
+		for _, d := range result {
+			if *level == deps.LEVEL_ARCHIVE {
+				fmt.Fprintf(o.stdout, "%s -> %s\n", d.From, d.To)
+			} else {
+				fmt.Fprintf(o.stdout, "%s -> %s (%s)\n", d.From, d.To, d.ToArchive)
+			}
+		}
+	}
+	return status
+}
+
+// jar中的类为jar的文件名, 其他的类为所在的输入目录或者文件名
+func archiveName(source string, inputs []string) string {
+	if i := strings.Index(source, "!/"); i >= 0 {
+		return filepath.Base(source[:i])
+	}
+	for _, input := range inputs {
+		if source == input || strings.HasPrefix(source, strings.TrimSuffix(input, string(filepath.Separator))+string(filepath.Separator)) {
+			return filepath.Base(input)
+		}
+	}
+	return filepath.Base(source)
+}
+
+// 按加载的顺序输出每个类的依赖, 依赖按名称排序
+func writeClassDeps(o *options, classes []Class, input []deps.Class, result []deps.Dependency) int {
+	byClass := make(map[string][]string)
+	for _, d := range result {
+		key := d.FromArchive + "!/" + d.From
+		byClass[key] = append(byClass[key], d.To)
+	}
+	items := make([]depsJSON, 0, len(classes))
+	for i, c := range classes {
+		dependencies := byClass[input[i].Archive+"!/"+c.File.ClassName()]
+		if dependencies == nil {
+			dependencies = make([]string, 0)
+		}
+		sort.Strings(dependencies)
+		items = append(items, depsJSON{Source: c.Source, Archive: input[i].Archive, Class: c.File.ClassName(), Dependencies: dependencies})
+	}
+	if o.json() {
+		return o.writeJSON(items)
+	}
+	for _, item := range items {
+		for _, dependency := range item.Dependencies {
+			fmt.Fprintf(o.stdout, "%s -> %s\n", item.Class, dependency)
+		}
+	}
+	return EXIT_OK
+}
+
+// Graphviz的DOT格式, 类和包级别的节点按所在的jar或者模块分组, 对内部API的依赖标为红色
+func writeDot(o *options, result []deps.Dependency, level string) {
+	fmt.Fprintln(o.stdout, "digraph \"deps\" {")
+	if level != deps.LEVEL_ARCHIVE {
+		clusters := make(map[string][]string)
+		archives := make([]string, 0)
+		add := func(archive, name string) {
+			if clusters[archive] == nil {
+				archives = append(archives, archive)
+			}
+			for _, node := range clusters[archive] {
+				if node == name {
+					return
+				}
+			}
+			clusters[archive] = append(clusters[archive], name)
+		}
+		for _, d := range result {
+			add(d.FromArchive, d.From)
+			add(d.ToArchive, d.To)
+		}
+		sort.Strings(archives)
+		for i, archive := range archives {
+			fmt.Fprintf(o.stdout, "  subgraph \"cluster_%d\" {\n    label=%q;\n", i, archive)
+			for _, name := range clusters[archive] {
+				fmt.Fprintf(o.stdout, "    %q [label=%q];\n", archive+"!/"+name, name)
+			}
+			fmt.Fprintln(o.stdout, "  }")
+		}
+	}
+	for _, d := range result {
+		from, to := d.From, d.To
+		if level != deps.LEVEL_ARCHIVE {
+			from, to = d.FromArchive+"!/"+from, d.ToArchive+"!/"+to
+		}
+		attrs := ""
+		if d.Internal {
+			attrs = " [color=red]"
+		}
+		fmt.Fprintf(o.stdout, "  %q -> %q%s;\n", from, to, attrs)
+	}
+	fmt.Fprintln(o.stdout, "}")
+}
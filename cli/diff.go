@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/flags"
+	"class-file-parser/i18n"
+)
+
+type changeJSON struct {
+	Kind   string `json:"kind"` //added, removed或者changed
+	Class  string `json:"class"`
+	Member string `json:"member,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	// 结构化比较的结果, unified diff格式的行
+	Diff []string `json:"diff,omitempty"`
+}
+
+func (c changeJSON) String() string {
+	marks := map[string]string{"added": "+", "removed": "-", "changed": "~"}
+	result := marks[c.Kind] + " " + c.Class
+	if c.Member != "" {
+		result += " " + c.Member
+	}
+	if c.Detail != "" {
+		result += ": " + c.Detail
+	}
+	return result
+}
+
+type differ struct {
+	changes []changeJSON
+}
+
+func (d *differ) add(kind, class, member, format string, args ...interface{}) {
+	d.changes = append(d.changes, changeJSON{Kind: kind, Class: class, Member: member, Detail: fmt.Sprintf(format, args...)})
+}
+
+// 按类名索引, 同名的类只保留第一个
+func byName(classes []Class) (map[string]*bytecode.ClassFile, []string) {
+	result := make(map[string]*bytecode.ClassFile)
+	names := make([]string, 0)
+	for _, c := range classes {
+		name := c.File.ClassName()
+		if _, ok := result[name]; !ok {
+			result[name] = c.File
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return result, names
+}
+
+// 两边各只有一个类时直接比较, 即使类名不同
+func (d *differ) classes(oldClasses, newClasses []Class) {
+	oldByName, oldNames := byName(oldClasses)
+	newByName, newNames := byName(newClasses)
+	if len(oldNames) == 1 && len(newNames) == 1 && oldNames[0] != newNames[0] {
+		d.class(oldNames[0]+" -> "+newNames[0], oldByName[oldNames[0]], newByName[newNames[0]])
+		return
+	}
+	for _, name := range oldNames {
+		if newByName[name] == nil {
+			d.add("removed", name, "", "")
+		}
+	}
+	for _, name := range newNames {
+		if oldByName[name] == nil {
+			d.add("added", name, "", "")
+		} else {
+			d.class(name, oldByName[name], newByName[name])
+		}
+	}
+}
+
+func (d *differ) class(name string, a, b *bytecode.ClassFile) {
+	if a.MajorVersion != b.MajorVersion || a.MinorVersion != b.MinorVersion {
+		d.add("changed", name, "", "version %d.%d -> %d.%d", a.MajorVersion, a.MinorVersion, b.MajorVersion, b.MinorVersion)
+	}
+	if a.AccessFlags != b.AccessFlags {
+		d.add("changed", name, "", "access flags [%s] -> [%s]", a.Flags().Javap(), b.Flags().Javap())
+	}
+	if a.SuperClassName() != b.SuperClassName() {
+		d.add("changed", name, "", "super class %s -> %s", a.SuperClassName(), b.SuperClassName())
+	}
+	if oldInterfaces, newInterfaces := strings.Join(a.InterfaceNames(), ", "), strings.Join(b.InterfaceNames(), ", "); oldInterfaces != newInterfaces {
+		d.add("changed", name, "", "interfaces [%s] -> [%s]", oldInterfaces, newInterfaces)
+	}
+	d.members(name, "field", memberFlags(a.ConstantPool, a.Fields, nil), memberFlags(b.ConstantPool, b.Fields, nil))
+	d.members(name, "method", memberFlags(a.ConstantPool, nil, a.Methods), memberFlags(b.ConstantPool, nil, b.Methods))
+	d.structure(name, a, b)
+}
+
+// 比较常量、属性和字节码, 与常量池的顺序无关
+func (d *differ) structure(name string, a, b *bytecode.ClassFile) {
+	if hunks := unified(a.Canonical(), b.Canonical(), DIFF_CONTEXT); len(hunks) > 0 {
+		d.changes = append(d.changes, changeJSON{Kind: "changed", Class: name, Detail: "structure", Diff: hunks})
+	}
+}
+
+// 成员名称加描述符到访问标志的映射
+func memberFlags(pool []bytecode.ConstantPoolInfo, fields []bytecode.FieldInfo, methods []bytecode.MethodInfo) map[string]flags.AccessFlags {
+	result := make(map[string]flags.AccessFlags)
+	for i := range fields {
+		result[fields[i].Name(pool)+":"+fields[i].Descriptor(pool)] = fields[i].Flags()
+	}
+	for i := range methods {
+		result[methods[i].Name(pool)+methods[i].Descriptor(pool)] = methods[i].Flags()
+	}
+	return result
+}
+
+func (d *differ) members(class, kind string, a, b map[string]flags.AccessFlags) {
+	keys := make([]string, 0)
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		oldFlags, inOld := a[key]
+		newFlags, inNew := b[key]
+		switch {
+		case !inNew:
+			d.add("removed", class, kind+" "+key, "")
+		case !inOld:
+			d.add("added", class, kind+" "+key, "")
+		case oldFlags.Value() != newFlags.Value():
+			d.add("changed", class, kind+" "+key, "access flags [%s] -> [%s]", oldFlags.Javap(), newFlags.Javap())
+		}
+	}
+}
+
+func runDiff(e *env, args []string) int {
+	o := newOptions(e, "diff")
+	inputs, err := o.parse(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	if len(inputs) != 2 {
+		fmt.Fprintln(o.stderr, i18n.T("error.diff_inputs"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	oldClasses, oldStatus := o.load(inputs[:1])
+	newClasses, newStatus := o.load(inputs[1:])
+	status := maxStatus(oldStatus, newStatus)
+
+	d := &differ{changes: make([]changeJSON, 0)}
+	d.classes(oldClasses, newClasses)
+	if o.json() {
+		status = maxStatus(status, o.writeJSON(d.changes))
+	} else {
+		for _, change := range d.changes {
+			if change.Diff == nil {
+				fmt.Fprintln(o.stdout, change.String())
+			}
+		}
+		for _, change := range d.changes {
+			if change.Diff != nil {
+				oldName, newName := change.Class, change.Class
+				if i := strings.Index(change.Class, " -> "); i >= 0 {
+					oldName, newName = change.Class[:i], change.Class[i+len(" -> "):]
+				}
+				fmt.Fprintf(o.stdout, "--- a/%s\n+++ b/%s\n%s\n", oldName, newName, strings.Join(change.Diff, "\n"))
+			}
+		}
+	}
+	if len(d.changes) > 0 {
+		status = maxStatus(status, EXIT_FINDINGS)
+	}
+	return status
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/flags"
+	"class-file-parser/i18n"
+)
+
+// 修饰符不为空时在后面加一个空格
+func prefix(modifiers string) string {
+	if modifiers == "" {
+		return ""
+	}
+	return modifiers + " "
+}
+
+// 每个类输出一个JSON文档, 只有一个类时与Schema描述的格式完全一致
+func runDump(e *env, args []string) int {
+	o := newOptions(e, "dump")
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	classes, status := o.load(inputs)
+	for _, c := range classes {
+		if o.json() {
+			status = maxStatus(status, o.writeJSON(c.File))
+			continue
+		}
+		text, err := dumpText(c.File)
+		if err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: c.Source, Err: err}.Error())
+			status = maxStatus(status, EXIT_ERROR)
+			continue
+		}
+		fmt.Fprintln(o.stdout, i18n.T("dump.size", c.Source, c.Size))
+		fmt.Fprintln(o.stdout, text)
+	}
+	return status
+}
+
+// 文本输出直接按索引读取常量池, 常量池格式错误时可能panic, 转为error
+func dumpText(f *bytecode.ClassFile) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed class file: %v", r)
+		}
+	}()
+	return f.String(), nil
+}
+
+type instructionJSON struct {
+	Pc       int    `json:"pc"`
+	Mnemonic string `json:"mnemonic"`
+	Text     string `json:"text"`
+}
+
+type handlerJSON struct {
+	StartPc   uint16 `json:"startPc"`
+	EndPc     uint16 `json:"endPc"`
+	HandlerPc uint16 `json:"handlerPc"`
+	CatchType string `json:"catchType"`
+}
+
+type codeJSON struct {
+	Name           string            `json:"name"`
+	Descriptor     string            `json:"descriptor"`
+	AccessFlags    uint16            `json:"accessFlags"`
+	MaxStack       uint16            `json:"maxStack"`
+	MaxLocals      uint16            `json:"maxLocals"`
+	Instructions   []instructionJSON `json:"instructions"`
+	ExceptionTable []handlerJSON     `json:"exceptionTable"`
+	Error          string            `json:"error,omitempty"`
+}
+
+type disasmJSON struct {
+	Source  string     `json:"source"`
+	Class   string     `json:"class"`
+	Methods []codeJSON `json:"methods"`
+}
+
+func disassemble(c Class) disasmJSON {
+	pool := c.File.ConstantPool
+	result := disasmJSON{Source: c.Source, Class: c.File.ClassName(), Methods: make([]codeJSON, 0)}
+	for i := range c.File.Methods {
+		method := &c.File.Methods[i]
+		code := method.Code()
+		if code == nil {
+			continue
+		}
+		m := codeJSON{
+			Name:           method.Name(pool),
+			Descriptor:     method.Descriptor(pool),
+			AccessFlags:    method.AccessFlags,
+			MaxStack:       code.MaxStack,
+			MaxLocals:      code.MaxLocals,
+			Instructions:   make([]instructionJSON, 0),
+			ExceptionTable: make([]handlerJSON, 0),
+		}
+		instructions, err := code.Instructions()
+		if err != nil {
+			m.Error = err.Error()
+		}
+		for _, instruction := range instructions {
+			text := strings.TrimPrefix(instruction.String(pool), fmt.Sprintf("%d: ", instruction.Pc))
+			m.Instructions = append(m.Instructions, instructionJSON{Pc: instruction.Pc, Mnemonic: instruction.Name(), Text: text})
+		}
+		for _, handler := range code.Table {
+			catchType := "any"
+			if handler.CatchType != 0 {
+				catchType = bytecode.ResolveConstant(pool, handler.CatchType)
+			}
+			m.ExceptionTable = append(m.ExceptionTable, handlerJSON{handler.StartPc, handler.EndPc, handler.HandlerPc, catchType})
+		}
+		result.Methods = append(result.Methods, m)
+	}
+	return result
+}
+
+func runDisasm(e *env, args []string) int {
+	o := newOptions(e, "disasm")
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	classes, status := o.load(inputs)
+	result := make([]disasmJSON, 0)
+	for _, c := range classes {
+		d := disassemble(c)
+		for _, m := range d.Methods {
+			if m.Error != "" {
+				fmt.Fprintf(o.stderr, "%s: %s%s: %s\n", c.Source, m.Name, m.Descriptor, m.Error)
+				status = maxStatus(status, EXIT_ERROR)
+			}
+		}
+		if o.json() {
+			result = append(result, d)
+			continue
+		}
+		fmt.Fprintf(o.stdout, "class %s // %s\n", d.Class, d.Source)
+		for _, m := range d.Methods {
+			fmt.Fprintf(o.stdout, "  %s%s%s\n", prefix(flags.MethodFlags(m.AccessFlags).String()), m.Name, m.Descriptor)
+			fmt.Fprintf(o.stdout, "    %s\n", i18n.T("disasm.stack", m.MaxStack, m.MaxLocals))
+			for _, instruction := range m.Instructions {
+				text := strings.ReplaceAll(instruction.Text, "\n", "\n      ")
+				fmt.Fprintf(o.stdout, "    %4d: %s\n", instruction.Pc, text)
+			}
+			if len(m.ExceptionTable) > 0 {
+				fmt.Fprintf(o.stdout, "    %s\n      %s\n", i18n.T("disasm.exception_table"), i18n.T("disasm.exception_header"))
+				for _, h := range m.ExceptionTable {
+					fmt.Fprintf(o.stdout, "      %4d  %4d  %6d %s\n", h.StartPc, h.EndPc, h.HandlerPc, h.CatchType)
+				}
+			}
+			fmt.Fprintln(o.stdout)
+		}
+	}
+	if o.json() {
+		status = maxStatus(status, o.writeJSON(result))
+	}
+	return status
+}
+
+type constantJSON struct {
+	Index uint16 `json:"index"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+type constantsJSON struct {
+	Source    string         `json:"source"`
+	Class     string         `json:"class"`
+	Constants []constantJSON `json:"constants"`
+}
+
+func runConstants(e *env, args []string) int {
+	o := newOptions(e, "constants")
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	classes, status := o.load(inputs)
+	result := make([]constantsJSON, 0)
+	for _, c := range classes {
+		pool := c.File.ConstantPool
+		item := constantsJSON{Source: c.Source, Class: c.File.ClassName(), Constants: make([]constantJSON, 0)}
+		for i := 1; i < len(pool); i++ {
+			if pool[i] != nil {
+				item.Constants = append(item.Constants, constantJSON{uint16(i), pool[i].TagName(), bytecode.ResolveConstant(pool, uint16(i))})
+			}
+		}
+		if o.json() {
+			result = append(result, item)
+			continue
+		}
+		fmt.Fprintf(o.stdout, "class %s // %s\n", item.Class, item.Source)
+		for _, constant := range item.Constants {
+			fmt.Fprintf(o.stdout, "  %6s = %-18s %s\n", fmt.Sprintf("#%d", constant.Index), constant.Tag, constant.Value)
+		}
+	}
+	if o.json() {
+		status = maxStatus(status, o.writeJSON(result))
+	}
+	return status
+}
+
+type memberJSON struct {
+	Name        string            `json:"name"`
+	Descriptor  string            `json:"descriptor"`
+	AccessFlags flags.AccessFlags `json:"accessFlags"`
+}
+
+type membersJSON struct {
+	Source  string       `json:"source"`
+	Class   string       `json:"class"`
+	Fields  []memberJSON `json:"fields"`
+	Methods []memberJSON `json:"methods"`
+}
+
+func members(c Class) membersJSON {
+	pool := c.File.ConstantPool
+	result := membersJSON{Source: c.Source, Class: c.File.ClassName(), Fields: make([]memberJSON, 0), Methods: make([]memberJSON, 0)}
+	for i := range c.File.Fields {
+		field := &c.File.Fields[i]
+		result.Fields = append(result.Fields, memberJSON{field.Name(pool), field.Descriptor(pool), field.Flags()})
+	}
+	for i := range c.File.Methods {
+		method := &c.File.Methods[i]
+		result.Methods = append(result.Methods, memberJSON{method.Name(pool), method.Descriptor(pool), method.Flags()})
+	}
+	return result
+}
+
+func runMembers(e *env, args []string) int {
+	o := newOptions(e, "members")
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	classes, status := o.load(inputs)
+	result := make([]membersJSON, 0)
+	for _, c := range classes {
+		item := members(c)
+		if o.json() {
+			result = append(result, item)
+			continue
+		}
+		fmt.Fprintf(o.stdout, "class %s // %s\n", item.Class, item.Source)
+		for _, field := range item.Fields {
+			fmt.Fprintf(o.stdout, "  field  %s%s:%s\n", prefix(field.AccessFlags.String()), field.Name, field.Descriptor)
+		}
+		for _, method := range item.Methods {
+			fmt.Fprintf(o.stdout, "  method %s%s%s\n", prefix(method.AccessFlags.String()), method.Name, method.Descriptor)
+		}
+	}
+	if o.json() {
+		status = maxStatus(status, o.writeJSON(result))
+	}
+	return status
+}
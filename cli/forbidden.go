@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"class-file-parser/forbidden"
+	"class-file-parser/i18n"
+	"class-file-parser/sarif"
+)
+
+// 按规则文件检查禁止使用的类、字段和方法, 存在时退出码为1
+func runForbidden(e *env, args []string) int {
+	o := newOptions(e, "forbidden")
+	o.allowSARIF()
+	rulesFile := o.flags.String("rules", "", i18n.T("flag.rules"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	if *rulesFile == "" {
+		fmt.Fprintln(o.stderr, i18n.T("error.missing_rules"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	file, err := os.Open(*rulesFile)
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_rules", err.Error()))
+		return EXIT_ERROR
+	}
+	rules, err := forbidden.ParseRules(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_rules", *rulesFile+": "+err.Error()))
+		return EXIT_ERROR
+	}
+
+	classes, status := o.load(inputs)
+	findings := make([]forbidden.Finding, 0)
+	for _, c := range classes {
+		findings = append(findings, rules.Scan(c.File)...)
+	}
+	switch {
+	case o.json():
+		status = maxStatus(status, o.writeJSON(findings))
+	case o.sarif():
+		log := sarif.New()
+		for _, f := range findings {
+			log.Add(f.Rule, f.Message, sarif.LEVEL_ERROR, findingMessage(f), sarif.ClassLocation(f.Class, f.SourcePath, f.Line, f.Method))
+		}
+		status = maxStatus(status, o.writeJSON(log))
+	default:
+		for _, f := range findings {
+			fmt.Fprintln(o.stdout, findingLocation(f)+": "+findingMessage(f))
+		}
+	}
+	if len(findings) > 0 {
+		status = maxStatus(status, EXIT_FINDINGS)
+	}
+	return status
+}
+
+// 例如com/acme/Main.main([Ljava/lang/String;)V pc 3 (Main.java:12)
+func findingLocation(f forbidden.Finding) string {
+	result := f.Class
+	if f.Method != "" {
+		result += "." + f.Method
+	}
+	if f.Pc >= 0 {
+		result += fmt.Sprintf(" pc %d", f.Pc)
+	}
+	if f.SourceFile != "" && f.Line > 0 {
+		result += fmt.Sprintf(" (%s:%d)", f.SourceFile, f.Line)
+	}
+	return result
+}
+
+func findingMessage(f forbidden.Finding) string {
+	message := i18n.T("forbidden.finding", f.Kind, f.Reference, f.Rule)
+	if f.Message != "" {
+		message += ": " + f.Message
+	}
+	return message
+}
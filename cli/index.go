@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"class-file-parser/i18n"
+	"class-file-parser/index"
+)
+
+const INDEX_FILE = "annotations.idx"
+
+type indexJSON struct {
+	Output      string `json:"output"`
+	Classes     int    `json:"classes"`
+	Annotations int    `json:"annotations"`
+}
+
+type targetJSON struct {
+	Annotation string `json:"annotation"`
+	Kind       string `json:"kind"`
+	Class      string `json:"class"`
+	Name       string `json:"name,omitempty"`
+	Descriptor string `json:"descriptor,omitempty"`
+	Position   int    `json:"position"`
+	Retention  string `json:"retention"`
+}
+
+// index build: 建立注解索引; index query: 查询使用了注解的类、字段、方法和参数
+func runIndex(e *env, args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "build":
+			return runIndexBuild(e, args[1:])
+		case "query":
+			return runIndexQuery(e, args[1:])
+		}
+	}
+	fmt.Fprintln(e.stderr, i18n.T("usage.command", "index", "build|query ..."))
+	return EXIT_ERROR
+}
+
+// 类路径中的类按顺序索引, 同名的类只索引第一个
+func runIndexBuild(e *env, args []string) int {
+	o := newOptions(e, "index")
+	output := o.flags.String("o", INDEX_FILE, i18n.T("flag.index_output"))
+	classpath := o.flags.String("cp", "", i18n.T("flag.cp"))
+	inputs, err := o.parse(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	for _, entry := range filepath.SplitList(*classpath) {
+		if entry != "" {
+			inputs = append(inputs, entry)
+		}
+	}
+	if len(inputs) == 0 {
+		fmt.Fprintln(o.stderr, i18n.T("error.no_input"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	classes, status := o.load(inputs)
+	x := index.New()
+	for _, c := range classes {
+		x.Add(c.File)
+	}
+	file, err := os.Create(*output)
+	if err == nil {
+		err = x.Write(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.write_file", err.Error()))
+		return EXIT_ERROR
+	}
+	result := indexJSON{Output: *output, Classes: len(x.Classes()), Annotations: len(x.Annotations())}
+	if o.json() {
+		return maxStatus(status, o.writeJSON(result))
+	}
+	fmt.Fprintln(o.stdout, i18n.T("index.built", result.Output, result.Classes, result.Annotations))
+	return status
+}
+
+// 注解名可以用.代替/, 没有注解名时列出所有注解及使用次数, 没有匹配时退出码为1
+func runIndexQuery(e *env, args []string) int {
+	o := newOptions(e, "index")
+	input := o.flags.String("i", INDEX_FILE, i18n.T("flag.index_input"))
+	meta := o.flags.Bool("meta", false, i18n.T("flag.meta"))
+	names, err := o.parse(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	file, err := os.Open(*input)
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_index", err.Error()))
+		return EXIT_ERROR
+	}
+	x, err := index.Read(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_index", *input+": "+err.Error()))
+		return EXIT_ERROR
+	}
+
+	if len(names) == 0 {
+		for _, name := range x.Annotations() {
+			fmt.Fprintf(o.stdout, "%6d %s\n", len(x.AnnotatedWith(name)), name)
+		}
+		return EXIT_OK
+	}
+	result := make([]targetJSON, 0)
+	missing := false
+	for _, name := range names {
+		name = strings.ReplaceAll(name, ".", "/")
+		targets := x.AnnotatedWith(name)
+		if *meta {
+			targets = x.MetaAnnotatedWith(name)
+		}
+		if len(targets) == 0 {
+			fmt.Fprintln(o.stderr, i18n.T("index.not_found", name))
+			missing = true
+		}
+		for _, t := range targets {
+			if o.json() {
+				result = append(result, targetJSON{Annotation: t.Annotation, Kind: t.Kind.String(), Class: t.Class,
+					Name: t.Name, Descriptor: t.Descriptor, Position: t.Position, Retention: t.Retention.String()})
+				continue
+			}
+			fmt.Fprintf(o.stdout, "%-9s %s @%s\n", t.Kind, t, t.Annotation)
+		}
+	}
+	status := EXIT_OK
+	if o.json() {
+		status = o.writeJSON(result)
+	}
+	// 任何一个注解没有匹配时都算没有找到
+	if missing {
+		status = maxStatus(status, EXIT_FINDINGS)
+	}
+	return status
+}
@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/i18n"
+)
+
+// 从输入中解析出的一个类文件
+type Class struct {
+	Source string //文件路径, jar中的类为jar路径!/条目名
+	Size   int
+	File   *bytecode.ClassFile
+}
+
+type InputError struct {
+	Source string
+	Err    error
+}
+
+func (e InputError) Error() string {
+	return e.Source + ": " + e.Err.Error()
+}
+
+func isArchive(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jar", ".zip", ".war", ".ear", ".jmod":
+		return true
+	}
+	return false
+}
+
+// 过滤器使用path.Match匹配内部形式的类名, 可以用.代替/, 结尾的**匹配所有子包
+func matchFilter(pattern, className string) bool {
+	if pattern == "" {
+		return true
+	}
+	pattern = strings.ReplaceAll(pattern, ".", "/")
+	if strings.HasSuffix(pattern, "**") {
+		return strings.HasPrefix(className, strings.TrimSuffix(pattern, "**"))
+	}
+	matched, _ := path.Match(pattern, className)
+	return matched
+}
+
+// 一个待解析的类文件, 目录和jar展开后每个类文件是一个job
+type job struct {
+	source string
+	read   func() ([]byte, error)
+}
+
+type result struct {
+	class *Class
+	err   error
+}
+
+// 批量处理的统计信息, 输入文件包括类文件和jar
+type Summary struct {
+	Files    int
+	Classes  int
+	Failures int
+	Bytes    int64
+	Duration time.Duration
+}
+
+func (s Summary) String() string {
+	return i18n.T("summary", s.Files, s.Classes, s.Failures, s.Bytes, s.Duration.Round(time.Millisecond))
+}
+
+type loader struct {
+	stdin   io.Reader
+	jobs    []job
+	files   int
+	closers []io.Closer
+}
+
+func (l *loader) add(source string, read func() ([]byte, error)) {
+	l.jobs = append(l.jobs, job{source: source, read: read})
+}
+
+// 展开阶段的错误也作为job, 保证错误和结果按输入的顺序输出
+func (l *loader) fail(source string, err error) {
+	l.add(source, func() ([]byte, error) {
+		return nil, err
+	})
+}
+
+// 输入可以是类文件、jar、目录, -表示从标准输入读取类文件或者jar
+func (l *loader) expand(input string) {
+	if input == "-" {
+		l.files++
+		data, err := io.ReadAll(l.stdin)
+		if err != nil {
+			l.fail("<stdin>", err)
+			return
+		}
+		if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+			archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			l.archive("<stdin>", archive, err)
+		} else {
+			l.add("<stdin>", func() ([]byte, error) {
+				return data, nil
+			})
+		}
+		return
+	}
+	info, err := os.Stat(input)
+	if err != nil {
+		l.fail(input, err)
+		return
+	}
+	if info.IsDir() {
+		l.dir(input)
+		return
+	}
+	l.file(input)
+}
+
+// WalkDir按字典序遍历, 展开的顺序是确定的
+func (l *loader) dir(root string) {
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			l.fail(name, err)
+			return nil
+		}
+		if !entry.IsDir() && (strings.HasSuffix(name, ".class") || isArchive(name)) {
+			l.file(name)
+		}
+		return nil
+	})
+	if err != nil {
+		l.fail(root, err)
+	}
+}
+
+func (l *loader) file(name string) {
+	l.files++
+	if isArchive(name) {
+		archive, err := zip.OpenReader(name)
+		if err == nil {
+			l.closers = append(l.closers, archive)
+			l.archive(name, &archive.Reader, nil)
+		} else {
+			l.archive(name, nil, err)
+		}
+		return
+	}
+	l.add(name, func() ([]byte, error) {
+		return os.ReadFile(name)
+	})
+}
+
+// zip.Reader的条目可以并发读取
+func (l *loader) archive(name string, archive *zip.Reader, err error) {
+	if err != nil {
+		l.fail(name, err)
+		return
+	}
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !strings.HasSuffix(entry.Name, ".class") {
+			continue
+		}
+		entry := entry
+		l.add(name+"!/"+entry.Name, func() ([]byte, error) {
+			return readEntry(entry)
+		})
+	}
+}
+
+func readEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func (j *job) run(filter string) result {
+	data, err := j.read()
+	if err != nil {
+		return result{err: err}
+	}
+	classFile, err := bytecode.ParseClassFile(data)
+	if err != nil {
+		return result{err: err}
+	}
+	if !matchFilter(filter, classFile.ClassName()) {
+		return result{}
+	}
+	return result{class: &Class{Source: j.source, Size: len(data), File: classFile}}
+}
+
+// 使用固定数量的goroutine解析, 结果按job的顺序保存
+func parseAll(jobs []job, workers int, filter string) []result {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]result, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = jobs[i].run(filter)
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// 加载所有输入, 错误输出到stderr, 存在错误时返回EXIT_ERROR
+func (o *options) load(inputs []string) ([]Class, int) {
+	start := time.Now()
+	l := &loader{stdin: o.stdin}
+	for _, input := range inputs {
+		l.expand(input)
+	}
+	results := parseAll(l.jobs, o.workers, o.filter)
+	for _, closer := range l.closers {
+		closer.Close()
+	}
+
+	summary := Summary{Files: l.files}
+	classes := make([]Class, 0, len(results))
+	for i, r := range results {
+		if r.err != nil {
+			summary.Failures++
+			fmt.Fprintln(o.stderr, InputError{Source: l.jobs[i].source, Err: r.err}.Error())
+			continue
+		}
+		if r.class != nil {
+			summary.Classes++
+			summary.Bytes += int64(r.class.Size)
+			classes = append(classes, *r.class)
+		}
+	}
+	summary.Duration = time.Since(start)
+	if o.summary {
+		fmt.Fprintln(o.stderr, summary.String())
+	}
+	if summary.Failures > 0 {
+		return classes, EXIT_ERROR
+	}
+	return classes, EXIT_OK
+}
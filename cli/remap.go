@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"class-file-parser/i18n"
+	"class-file-parser/remap"
+)
+
+type remapJSON struct {
+	Source   string `json:"source"`
+	Class    string `json:"class"`
+	Remapped string `json:"remapped"`
+	Path     string `json:"path"`
+}
+
+// 按ProGuard、Tiny或者SRG映射给类改名, 写入-d目录. -cp中的类只用于查找继承的成员, 不写出
+func runRemap(e *env, args []string) int {
+	o := newOptions(e, "remap")
+	mappingFile := o.flags.String("mapping", "", i18n.T("flag.remap_mapping"))
+	from := o.flags.String("from", "", i18n.T("flag.from"))
+	to := o.flags.String("to", "", i18n.T("flag.to"))
+	reverse := o.flags.Bool("reverse", false, i18n.T("flag.reverse"))
+	classpath := o.flags.String("cp", "", i18n.T("flag.remap_cp"))
+	dir := o.flags.String("d", "", i18n.T("flag.remap_d"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	if *mappingFile == "" || *dir == "" {
+		fmt.Fprintln(o.stderr, i18n.T("error.missing_remap"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	file, err := os.Open(*mappingFile)
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_mapping", err.Error()))
+		return EXIT_ERROR
+	}
+	mapping, err := remap.Parse(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_mapping", *mappingFile+": "+err.Error()))
+		return EXIT_ERROR
+	}
+	source, target := *from, *to
+	if *reverse {
+		if source == "" {
+			source = mapping.Namespaces[0]
+		}
+		if target == "" {
+			target = mapping.Namespaces[len(mapping.Namespaces)-1]
+		}
+		source, target = target, source
+	}
+	remapper, err := remap.New(mapping, source, target)
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_mapping", *mappingFile+": "+err.Error()))
+		return EXIT_ERROR
+	}
+
+	classes, status := o.load(inputs)
+	for _, c := range classes {
+		remapper.AddClass(c.File)
+	}
+	if paths := filepath.SplitList(*classpath); len(paths) > 0 {
+		libraries, loadStatus := o.load(paths)
+		status = maxStatus(status, loadStatus)
+		for _, c := range libraries {
+			remapper.AddClass(c.File)
+		}
+	}
+
+	result := make([]remapJSON, 0)
+	written := make(map[string]bool)
+	for _, c := range classes {
+		name := c.File.ClassName()
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		err := remapper.Remap(c.File)
+		var data []byte
+		if err == nil {
+			data, err = c.File.Bytes()
+		}
+		if err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: c.Source, Err: err}.Error())
+			status = maxStatus(status, EXIT_ERROR)
+			continue
+		}
+		item := remapJSON{Source: c.Source, Class: name, Remapped: c.File.ClassName(), Path: c.File.ClassName() + ".class"}
+		path := filepath.Join(*dir, filepath.FromSlash(item.Path))
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, data, 0644)
+		}
+		if err != nil {
+			fmt.Fprintln(o.stderr, i18n.T("error.write_file", err.Error()))
+			status = maxStatus(status, EXIT_ERROR)
+			continue
+		}
+		if o.json() {
+			result = append(result, item)
+		} else {
+			fmt.Fprintf(o.stdout, "%s -> %s\n", item.Class, item.Remapped)
+		}
+	}
+	if o.json() {
+		status = maxStatus(status, o.writeJSON(result))
+	}
+	return status
+}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/i18n"
+)
+
+// 可以忽略的差异
+const (
+	CAUSE_ORDER       = "order"            // 常量池、成员或者属性的顺序
+	CAUSE_SOURCE_FILE = "source-file"      // SourceFile中的目录
+	CAUSE_LAMBDA      = "lambda-numbering" // lambda方法的编号
+	CAUSE_ENTRY_ORDER = "entry-order"      // jar中条目的顺序
+	CAUSE_TIMESTAMP   = "timestamp"        // jar中条目的修改时间
+)
+
+type reproJSON struct {
+	Entries    int              `json:"entries"`
+	Identical  int              `json:"identical"`
+	Archive    []string         `json:"archive"`
+	Normalized []reproEntryJSON `json:"normalized"`
+	Different  []reproEntryJSON `json:"different"`
+}
+
+type reproEntryJSON struct {
+	Entry  string   `json:"entry"`
+	Kind   string   `json:"kind,omitempty"` //added, removed或者changed
+	Causes []string `json:"causes,omitempty"`
+	Detail string   `json:"detail,omitempty"`
+	Diff   []string `json:"diff,omitempty"`
+}
+
+type reproducer struct {
+	result     reproJSON
+	entryOrder bool
+	timestamp  bool
+}
+
+// 比较两次构建的jar, 忽略条目顺序、时间戳、常量池顺序、SourceFile的目录和lambda方法的编号,
+// 其他差异退出码为1
+func runRepro(e *env, args []string) int {
+	o := newOptions(e, "repro")
+	inputs, err := o.parse(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	if len(inputs) != 2 {
+		fmt.Fprintln(o.stderr, i18n.T("error.repro_inputs"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	archives := make([]*zip.Reader, 0, 2)
+	for _, input := range inputs {
+		archive, err := openArchive(input)
+		if err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: input, Err: err}.Error())
+			return EXIT_ERROR
+		}
+		archives = append(archives, archive)
+	}
+
+	r := &reproducer{result: reproJSON{Archive: make([]string, 0), Normalized: make([]reproEntryJSON, 0), Different: make([]reproEntryJSON, 0)}}
+	if err := r.archive("", archives[0], archives[1]); err != nil {
+		fmt.Fprintln(o.stderr, err.Error())
+		return EXIT_ERROR
+	}
+	if r.entryOrder {
+		r.result.Archive = append(r.result.Archive, CAUSE_ENTRY_ORDER)
+	}
+	if r.timestamp {
+		r.result.Archive = append(r.result.Archive, CAUSE_TIMESTAMP)
+	}
+
+	status := EXIT_OK
+	if o.json() {
+		status = o.writeJSON(r.result)
+	} else {
+		r.print(o)
+	}
+	if len(r.result.Different) > 0 {
+		status = maxStatus(status, EXIT_FINDINGS)
+	}
+	return status
+}
+
+func openArchive(name string) (*zip.Reader, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+func (r *reproducer) print(o *options) {
+	for _, entry := range r.result.Normalized {
+		fmt.Fprintf(o.stdout, "~ %s: %s\n", entry.Entry, strings.Join(entry.Causes, ", "))
+	}
+	marks := map[string]string{"added": "+", "removed": "-", "changed": "!"}
+	for _, entry := range r.result.Different {
+		line := marks[entry.Kind] + " " + entry.Entry
+		if entry.Detail != "" {
+			line += ": " + entry.Detail
+		}
+		fmt.Fprintln(o.stdout, line)
+		if entry.Diff != nil {
+			fmt.Fprintf(o.stdout, "--- a/%s\n+++ b/%s\n%s\n", entry.Entry, entry.Entry, strings.Join(entry.Diff, "\n"))
+		}
+	}
+	if len(r.result.Archive) > 0 {
+		fmt.Fprintln(o.stdout, i18n.T("repro.archive", strings.Join(r.result.Archive, ", ")))
+	}
+	fmt.Fprintln(o.stdout, i18n.T("repro.summary", r.result.Entries, r.result.Identical, len(r.result.Normalized), len(r.result.Different)))
+}
+
+// 按条目名匹配, 嵌套的jar展开比较, 条目名加上外层jar的前缀
+func (r *reproducer) archive(prefix string, a, b *zip.Reader) error {
+	oldEntries, oldNames := archiveEntries(a)
+	newEntries, newNames := archiveEntries(b)
+	common := make([]string, 0, len(oldNames))
+	for _, name := range oldNames {
+		if newEntries[name] != nil {
+			common = append(common, name)
+		}
+	}
+	order := make([]string, 0, len(common))
+	for _, name := range newNames {
+		if oldEntries[name] != nil {
+			order = append(order, name)
+		}
+	}
+	if strings.Join(common, "\n") != strings.Join(order, "\n") {
+		r.entryOrder = true
+	}
+
+	names := append([]string(nil), oldNames...)
+	for _, name := range newNames {
+		if oldEntries[name] == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		oldEntry, newEntry := oldEntries[name], newEntries[name]
+		switch {
+		case newEntry == nil:
+			r.result.Entries++
+			r.different(reproEntryJSON{Entry: prefix + name, Kind: "removed"})
+			continue
+		case oldEntry == nil:
+			r.result.Entries++
+			r.different(reproEntryJSON{Entry: prefix + name, Kind: "added"})
+			continue
+		}
+		if !oldEntry.Modified.Equal(newEntry.Modified) {
+			r.timestamp = true
+		}
+		oldData, err := readEntry(oldEntry)
+		if err != nil {
+			return InputError{Source: prefix + name, Err: err}
+		}
+		newData, err := readEntry(newEntry)
+		if err != nil {
+			return InputError{Source: prefix + name, Err: err}
+		}
+		if bytes.Equal(oldData, newData) {
+			r.result.Entries++
+			r.result.Identical++
+			continue
+		}
+		if isArchive(name) {
+			oldArchive, oldErr := zip.NewReader(bytes.NewReader(oldData), int64(len(oldData)))
+			newArchive, newErr := zip.NewReader(bytes.NewReader(newData), int64(len(newData)))
+			if oldErr == nil && newErr == nil {
+				if err := r.archive(prefix+name+"!/", oldArchive, newArchive); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		r.result.Entries++
+		if strings.HasSuffix(name, ".class") {
+			r.class(prefix+name, oldData, newData)
+		} else {
+			r.resource(prefix+name, oldData, newData)
+		}
+	}
+	return nil
+}
+
+func archiveEntries(archive *zip.Reader) (map[string]*zip.File, []string) {
+	entries := make(map[string]*zip.File)
+	names := make([]string, 0, len(archive.File))
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || entries[entry.Name] != nil {
+			continue
+		}
+		entries[entry.Name] = entry
+		names = append(names, entry.Name)
+	}
+	return entries, names
+}
+
+func (r *reproducer) different(entry reproEntryJSON) {
+	r.result.Different = append(r.result.Different, entry)
+}
+
+func (r *reproducer) class(name string, oldData, newData []byte) {
+	a, err := bytecode.ParseClassFile(oldData)
+	if err == nil {
+		var b *bytecode.ClassFile
+		if b, err = bytecode.ParseClassFile(newData); err == nil {
+			r.classFile(name, a, b)
+			return
+		}
+	}
+	r.different(reproEntryJSON{Entry: name, Kind: "changed", Detail: err.Error()})
+}
+
+// 依次应用每种规范化, 直到两边的结构相同; 减少了差异的规范化作为原因, 最后剩下的字节差异是顺序
+func (r *reproducer) classFile(name string, a, b *bytecode.ClassFile) {
+	steps := []struct {
+		cause     string
+		normalize func(f *bytecode.ClassFile) bool
+	}{
+		{CAUSE_SOURCE_FILE, (*bytecode.ClassFile).NormalizeSourceFile},
+		{CAUSE_LAMBDA, (*bytecode.ClassFile).NormalizeLambdas},
+	}
+	causes := make([]string, 0)
+	oldLines, newLines := a.Canonical(), b.Canonical()
+	for _, step := range steps {
+		edits := countEdits(oldLines, newLines)
+		if edits == 0 {
+			break
+		}
+		oldChanged, newChanged := step.normalize(a), step.normalize(b)
+		if oldChanged || newChanged {
+			oldLines, newLines = a.Canonical(), b.Canonical()
+			// 两边做了相同的修改时不是差异的原因
+			if countEdits(oldLines, newLines) < edits {
+				causes = append(causes, step.cause)
+			}
+		}
+	}
+	if countEdits(oldLines, newLines) > 0 {
+		r.different(reproEntryJSON{Entry: name, Kind: "changed", Diff: unified(oldLines, newLines, DIFF_CONTEXT)})
+		return
+	}
+	// 原始的字节不同, 规范化之后写出的字节仍然不同时, 其余的差异只在顺序上, 与其他原因同时记录
+	if len(causes) == 0 || !sameBytes(a, b) {
+		causes = append(causes, CAUSE_ORDER)
+	}
+	r.result.Normalized = append(r.result.Normalized, reproEntryJSON{Entry: name, Causes: causes})
+}
+
+func sameBytes(a, b *bytecode.ClassFile) bool {
+	oldData, err := a.Bytes()
+	if err != nil {
+		return false
+	}
+	newData, err := b.Bytes()
+	return err == nil && bytes.Equal(oldData, newData)
+}
+
+// 文本文件输出逐行的差异
+func (r *reproducer) resource(name string, oldData, newData []byte) {
+	entry := reproEntryJSON{Entry: name, Kind: "changed"}
+	if isText(oldData) && isText(newData) {
+		entry.Diff = unified(strings.Split(string(oldData), "\n"), strings.Split(string(newData), "\n"), DIFF_CONTEXT)
+	}
+	r.different(entry)
+}
+
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+func countEdits(a, b []string) int {
+	count := 0
+	for _, e := range diffLines(a, b) {
+		if e.op != ' ' {
+			count++
+		}
+	}
+	return count
+}
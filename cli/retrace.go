@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"class-file-parser/i18n"
+	"class-file-parser/retrace"
+)
+
+type retraceJSON struct {
+	Input  string            `json:"input"`
+	Output []string          `json:"output"`
+	Frames [][]retrace.Frame `json:"frames,omitempty"`
+}
+
+// 按ProGuard/R8的mapping.txt还原混淆的异常栈, 输入是栈的文本文件, 没有时从stdin读取.
+// -cp中是混淆后的类, 用于筛选有歧义的行号以及按SMAP换算没有混淆的类的行号
+func runRetrace(e *env, args []string) int {
+	o := newOptions(e, "retrace")
+	mappingFile := o.flags.String("mapping", "", i18n.T("flag.mapping"))
+	classpath := o.flags.String("cp", "", i18n.T("flag.retrace_cp"))
+	inputs, err := o.parse(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	if *mappingFile == "" && *classpath == "" {
+		fmt.Fprintln(o.stderr, i18n.T("error.missing_mapping"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	var mapping *retrace.Mapping
+	if *mappingFile != "" {
+		file, err := os.Open(*mappingFile)
+		if err != nil {
+			fmt.Fprintln(o.stderr, i18n.T("error.read_mapping", err.Error()))
+			return EXIT_ERROR
+		}
+		mapping, err = retrace.ParseMapping(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintln(o.stderr, i18n.T("error.read_mapping", *mappingFile+": "+err.Error()))
+			return EXIT_ERROR
+		}
+	}
+
+	retracer := retrace.New(mapping)
+	status := EXIT_OK
+	if paths := filepath.SplitList(*classpath); len(paths) > 0 {
+		classes, loadStatus := o.load(paths)
+		status = loadStatus
+		for _, c := range classes {
+			retracer.AddClass(c.File)
+		}
+	}
+	names, readers := make([]string, 0), make([]io.Reader, 0)
+	if len(inputs) == 0 {
+		names, readers = append(names, "<stdin>"), append(readers, o.stdin)
+	}
+	for _, input := range inputs {
+		file, err := os.Open(input)
+		if err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: input, Err: err}.Error())
+			status = EXIT_ERROR
+			continue
+		}
+		defer file.Close()
+		names, readers = append(names, input), append(readers, file)
+	}
+
+	lines := make([]retraceJSON, 0)
+	for i, reader := range readers {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			output, frames := retracer.Line(scanner.Text())
+			if o.json() {
+				lines = append(lines, retraceJSON{Input: scanner.Text(), Output: output, Frames: frames})
+				continue
+			}
+			for _, line := range output {
+				fmt.Fprintln(o.stdout, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: names[i], Err: err}.Error())
+			status = EXIT_ERROR
+		}
+	}
+	if o.json() {
+		status = maxStatus(status, o.writeJSON(lines))
+	}
+	return status
+}
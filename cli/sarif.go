@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/sarif"
+)
+
+var pcPattern = regexp.MustCompile(`\bpc (\d+)\b`)
+
+// 从verify、compat等命令的位置中取出成员和pc, 例如method run()V Code pc 3 invokevirtual
+// 得到run()V和3, field count:I得到count:I和-1; 不在成员中时成员为空
+func parseLocation(location string) (string, int) {
+	member := ""
+	for _, prefix := range []string{"method ", "field "} {
+		if strings.HasPrefix(location, prefix) {
+			member = strings.SplitN(location[len(prefix):], " ", 2)[0]
+		}
+	}
+	pc := -1
+	if match := pcPattern.FindStringSubmatch(location); match != nil {
+		pc, _ = strconv.Atoi(match[1])
+	}
+	return member, pc
+}
+
+// 同一个方法的行号区间只整理一次, 每条指令都有结果时不用反复排序
+type sourceLocator struct {
+	lines map[*bytecode.MethodInfo][]bytecode.LineRange
+}
+
+func newSourceLocator() *sourceLocator {
+	return &sourceLocator{lines: make(map[*bytecode.MethodInfo][]bytecode.LineRange)}
+}
+
+func (l *sourceLocator) ranges(m *bytecode.MethodInfo) []bytecode.LineRange {
+	ranges, ok := l.lines[m]
+	if !ok {
+		ranges = m.LineRanges()
+		l.lines[m] = ranges
+	}
+	return ranges
+}
+
+// 类文件中的位置对应的SARIF位置: 逻辑位置是类或者成员, 物理位置是可能的源文件,
+// 行号是pc所在的行, 没有pc时是方法的第一行; f为nil时只有类名
+func (l *sourceLocator) classLocation(className string, f *bytecode.ClassFile, location string) sarif.Location {
+	member, pc := parseLocation(location)
+	if f == nil {
+		return sarif.ClassLocation(className, "", 0, member)
+	}
+	pool := f.ConstantPool
+	line := 0
+	for i := range f.Methods {
+		m := &f.Methods[i]
+		if member == "" || m.Name(pool)+m.Descriptor(pool) != member {
+			continue
+		}
+		if pc >= 0 {
+			line = bytecode.LineAt(l.ranges(m), pc)
+			continue
+		}
+		for _, r := range l.ranges(m) {
+			if line == 0 || r.Line < line {
+				line = r.Line
+			}
+		}
+	}
+	return sarif.ClassLocation(className, f.SourcePath(), line, member)
+}
+
+// verify的级别对应的SARIF级别
+func sarifLevel(severity bytecode.Severity) string {
+	switch severity {
+	case bytecode.SEVERITY_ERROR:
+		return sarif.LEVEL_ERROR
+	case bytecode.SEVERITY_WARNING:
+		return sarif.LEVEL_WARNING
+	}
+	return sarif.LEVEL_NOTE
+}
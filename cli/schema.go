@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/i18n"
+)
+
+// schema: 输出JSON Schema; schema validate file...: 使用JSON Schema校验dump -format json的输出
+func runSchema(e *env, args []string) int {
+	if len(args) == 0 {
+		e.stdout.Write(bytecode.JSONSchema)
+		return EXIT_OK
+	}
+	if args[0] != "validate" || len(args) == 1 {
+		fmt.Fprintln(e.stderr, i18n.T("usage.command", "schema", "[validate <json>...]"))
+		return EXIT_ERROR
+	}
+	status := EXIT_OK
+	for _, name := range args[1:] {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(e.stderr, i18n.T("error.read_json", err.Error()))
+			status = EXIT_ERROR
+			continue
+		}
+		errs, err := bytecode.ValidateJSON(data)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "%s: %s\n", name, err.Error())
+			status = EXIT_ERROR
+			continue
+		}
+		for _, err := range errs {
+			fmt.Fprintf(e.stdout, "%s: %s\n", name, err.Error())
+		}
+		if len(errs) > 0 {
+			status = maxStatus(status, EXIT_FINDINGS)
+			continue
+		}
+		fmt.Fprintf(e.stdout, "%s: ok\n", name)
+	}
+	return status
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/i18n"
+)
+
+type matchJSON struct {
+	Source string `json:"source"`
+	Class  string `json:"class"`
+	Index  uint16 `json:"index"`
+	Tag    string `json:"tag"`
+	Value  string `json:"value"`
+}
+
+// 在常量池解析后的值中查找, 默认按子串匹配
+func runSearch(e *env, args []string) int {
+	o := newOptions(e, "search")
+	useRegexp := o.flags.Bool("regexp", false, i18n.T("flag.regexp"))
+	tags := o.flags.String("tag", "", i18n.T("flag.tag"))
+	inputs, err := o.parse(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	if len(inputs) < 2 {
+		fmt.Fprintln(o.stderr, i18n.T("error.missing_pattern"))
+		o.flags.Usage()
+		return EXIT_ERROR
+	}
+	pattern := inputs[0]
+	match := func(value string) bool {
+		return strings.Contains(value, pattern)
+	}
+	if *useRegexp {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintln(o.stderr, i18n.T("error.invalid_pattern", err.Error()))
+			return EXIT_ERROR
+		}
+		match = re.MatchString
+	}
+	allowed := make(map[string]bool)
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			allowed[tag] = true
+		}
+	}
+
+	classes, status := o.load(inputs[1:])
+	result := make([]matchJSON, 0)
+	for _, c := range classes {
+		pool := c.File.ConstantPool
+		for i := 1; i < len(pool); i++ {
+			if pool[i] == nil || (len(allowed) > 0 && !allowed[pool[i].TagName()]) {
+				continue
+			}
+			value := bytecode.ResolveConstant(pool, uint16(i))
+			if !match(value) {
+				continue
+			}
+			m := matchJSON{c.Source, c.File.ClassName(), uint16(i), pool[i].TagName(), value}
+			result = append(result, m)
+			if !o.json() {
+				fmt.Fprintf(o.stdout, "%s: #%d %s %s\n", m.Source, m.Index, m.Tag, m.Value)
+			}
+		}
+	}
+	if o.json() {
+		status = maxStatus(status, o.writeJSON(result))
+	}
+	if len(result) == 0 {
+		status = maxStatus(status, EXIT_FINDINGS)
+	}
+	return status
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/i18n"
+	"class-file-parser/sarif"
+	"class-file-parser/sniffer"
+)
+
+const SIGNATURE_FILE = "api.sig"
+
+type sniffJSON struct {
+	Output  string `json:"output"`
+	Classes int    `json:"classes"`
+}
+
+// sniff build: 生成JDK的API签名文件; sniff check: 检查类引用的API是否都在签名中, 类似animal-sniffer
+func runSniff(e *env, args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "build":
+			return runSniffBuild(e, args[1:])
+		case "check":
+			return runSniffCheck(e, args[1:])
+		}
+	}
+	fmt.Fprintln(e.stderr, i18n.T("usage.command", "sniff", "build|check ..."))
+	return EXIT_ERROR
+}
+
+// 输入可以是JDK的目录、lib/modules、ct.sym、jmod、jar或者类文件目录, 同名的类以第一个为准.
+// 指定-release时JDK目录读取lib/ct.sym, 否则读取lib/modules
+func runSniffBuild(e *env, args []string) int {
+	o := newOptions(e, "sniff")
+	output := o.flags.String("o", SIGNATURE_FILE, i18n.T("flag.signature_output"))
+	release := o.flags.String("release", "", i18n.T("flag.release"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	number := 0
+	if *release != "" {
+		major, err := bytecode.ParseRelease(*release)
+		if err != nil {
+			fmt.Fprintln(o.stderr, i18n.T("error.unknown_release", *release))
+			return EXIT_ERROR
+		}
+		number = int(major) - 44
+	}
+
+	signature := sniffer.New()
+	status := EXIT_OK
+	visit := func(name string, data []byte) error {
+		f, err := bytecode.ParseClassFile(data)
+		if err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: name, Err: err}.Error())
+			status = EXIT_ERROR
+		} else if matchFilter(o.filter, f.ClassName()) {
+			signature.Add(f)
+		}
+		return nil
+	}
+	for _, input := range inputs {
+		if info, err := os.Stat(input); err == nil && info.IsDir() {
+			if *release != "" && isFile(filepath.Join(input, "lib", "ct.sym")) {
+				input = filepath.Join(input, "lib", "ct.sym")
+			} else if isFile(filepath.Join(input, "lib", "modules")) {
+				input = filepath.Join(input, "lib", "modules")
+			}
+		}
+		switch {
+		case filepath.Base(input) == "ct.sym":
+			if *release == "" {
+				err = fmt.Errorf("-release is required for ct.sym")
+			} else {
+				err = sniffer.ReadCtSym(input, number, visit)
+			}
+		case isJImage(input):
+			err = sniffer.ReadJImage(input, visit)
+		default:
+			classes, loadStatus := o.load([]string{input})
+			for _, c := range classes {
+				signature.Add(c.File)
+			}
+			status, err = maxStatus(status, loadStatus), nil
+		}
+		if err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: input, Err: err}.Error())
+			status = EXIT_ERROR
+		}
+	}
+
+	file, err := os.Create(*output)
+	if err == nil {
+		err = signature.Write(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.write_file", err.Error()))
+		return EXIT_ERROR
+	}
+	result := sniffJSON{Output: *output, Classes: len(signature.Classes())}
+	if o.json() {
+		return maxStatus(status, o.writeJSON(result))
+	}
+	fmt.Fprintln(o.stdout, i18n.T("sniff.built", result.Output, result.Classes))
+	return status
+}
+
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+func isJImage(name string) bool {
+	file, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	magic := make([]byte, 4)
+	if _, err := file.Read(magic); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, []byte{0xDA, 0xDA, 0xFE, 0xCA}) || bytes.Equal(magic, []byte{0xCA, 0xFE, 0xDA, 0xDA})
+}
+
+// 输入和-cp中的类不需要在签名中, 只检查输入中的类; 存在签名中没有的引用时退出码为1
+func runSniffCheck(e *env, args []string) int {
+	o := newOptions(e, "sniff")
+	o.allowSARIF()
+	input := o.flags.String("s", SIGNATURE_FILE, i18n.T("flag.signature"))
+	classpath := o.flags.String("cp", "", i18n.T("flag.cp"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	file, err := os.Open(*input)
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_signature", err.Error()))
+		return EXIT_ERROR
+	}
+	signature, err := sniffer.Read(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(o.stderr, i18n.T("error.read_signature", *input+": "+err.Error()))
+		return EXIT_ERROR
+	}
+
+	classes, status := o.load(inputs)
+	checker := sniffer.NewChecker(signature)
+	for _, c := range classes {
+		checker.AddClasspath(c.File)
+	}
+	if paths := filepath.SplitList(*classpath); len(paths) > 0 {
+		// 类路径中的类只用于查找, 不按-filter过滤
+		filter := o.filter
+		o.filter = ""
+		libraries, libraryStatus := o.load(paths)
+		o.filter = filter
+		status = maxStatus(status, libraryStatus)
+		for _, c := range libraries {
+			checker.AddClasspath(c.File)
+		}
+	}
+	problems := make([]sniffer.Problem, 0)
+	log, locator := sarif.New(), newSourceLocator()
+	for _, c := range classes {
+		found := checker.Check(c.File)
+		for _, p := range found {
+			log.Add("missing-"+p.Kind, "", sarif.LEVEL_ERROR, i18n.T("sniff.missing", p.Class, p.Kind, p.Reference(), p.Location),
+				locator.classLocation(p.Class, c.File, p.Location))
+		}
+		problems = append(problems, found...)
+	}
+	switch {
+	case o.json():
+		status = maxStatus(status, o.writeJSON(problems))
+	case o.sarif():
+		status = maxStatus(status, o.writeJSON(log))
+	default:
+		for _, p := range problems {
+			fmt.Fprintln(o.stdout, i18n.T("sniff.missing", p.Class, p.Kind, p.Reference(), p.Location))
+		}
+	}
+	if len(problems) > 0 {
+		status = maxStatus(status, EXIT_FINDINGS)
+	}
+	return status
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"class-file-parser/i18n"
+)
+
+type statsJSON struct {
+	Classes        int            `json:"classes"`
+	Bytes          int            `json:"bytes"`
+	Constants      int            `json:"constants"`
+	ConstantsByTag map[string]int `json:"constantsByTag"`
+	Fields         int            `json:"fields"`
+	Methods        int            `json:"methods"`
+	CodeBytes      int            `json:"codeBytes"`
+	Instructions   int            `json:"instructions"`
+	Versions       map[string]int `json:"versions"`
+}
+
+func runStats(e *env, args []string) int {
+	o := newOptions(e, "stats")
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	classes, status := o.load(inputs)
+	result := statsJSON{ConstantsByTag: make(map[string]int), Versions: make(map[string]int)}
+	for _, c := range classes {
+		f := c.File
+		result.Classes++
+		result.Bytes += c.Size
+		for _, item := range f.ConstantPool[1:] {
+			if item != nil {
+				result.Constants++
+				result.ConstantsByTag[item.TagName()]++
+			}
+		}
+		result.Fields += len(f.Fields)
+		result.Methods += len(f.Methods)
+		for i := range f.Methods {
+			if code := f.Methods[i].Code(); code != nil {
+				result.CodeBytes += len(code.Code)
+				instructions, _ := code.Instructions()
+				result.Instructions += len(instructions)
+			}
+		}
+		result.Versions[fmt.Sprintf("%d.%d", f.MajorVersion, f.MinorVersion)]++
+	}
+	if o.json() {
+		return maxStatus(status, o.writeJSON(result))
+	}
+	fmt.Fprintln(o.stdout, i18n.T("stats.classes", result.Classes))
+	fmt.Fprintln(o.stdout, i18n.T("stats.bytes", result.Bytes))
+	fmt.Fprintln(o.stdout, i18n.T("stats.constants", result.Constants))
+	for _, key := range sortedKeys(result.ConstantsByTag) {
+		fmt.Fprintf(o.stdout, "  %-18s %d\n", key, result.ConstantsByTag[key])
+	}
+	fmt.Fprintln(o.stdout, i18n.T("stats.fields", result.Fields))
+	fmt.Fprintln(o.stdout, i18n.T("stats.methods", result.Methods))
+	fmt.Fprintln(o.stdout, i18n.T("stats.code_bytes", result.CodeBytes))
+	fmt.Fprintln(o.stdout, i18n.T("stats.instructions", result.Instructions))
+	fmt.Fprintln(o.stdout, i18n.T("stats.versions"))
+	for _, key := range sortedKeys(result.Versions) {
+		fmt.Fprintf(o.stdout, "  %-18s %d\n", key, result.Versions[key])
+	}
+	return status
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
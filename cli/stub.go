@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/flags"
+	"class-file-parser/i18n"
+	"class-file-parser/stub"
+)
+
+type stubJSON struct {
+	Source string `json:"source"`
+	Class  string `json:"class"`
+	Path   string `json:"path"`
+	Stub   string `json:"stub"`
+}
+
+// 成员类在外部类的源文件中生成, 局部类、匿名类和module-info不生成
+func runStub(e *env, args []string) int {
+	o := newOptions(e, "stub")
+	dir := o.flags.String("d", "", i18n.T("flag.d"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	classes, status := o.load(inputs)
+	files := make(map[string]*bytecode.ClassFile)
+	for _, c := range classes {
+		if _, ok := files[c.File.ClassName()]; !ok {
+			files[c.File.ClassName()] = c.File
+		}
+	}
+	lookup := func(name string) *bytecode.ClassFile {
+		return files[name]
+	}
+
+	result := make([]stubJSON, 0)
+	for _, c := range classes {
+		if c.File.Flags()&flags.CLASS_MODULE != 0 {
+			continue
+		}
+		if outer, nested := stub.DeclaringClass(c.File); nested {
+			if outer != "" && files[outer] == nil {
+				fmt.Fprintln(o.stderr, i18n.T("error.stub_outer", c.Source, outer))
+				status = maxStatus(status, EXIT_ERROR)
+			}
+			continue
+		}
+		source, err := stub.Generate(c.File, lookup)
+		if err != nil {
+			fmt.Fprintln(o.stderr, InputError{Source: c.Source, Err: err}.Error())
+			status = maxStatus(status, EXIT_ERROR)
+			continue
+		}
+		item := stubJSON{Source: c.Source, Class: c.File.ClassName(), Path: stub.FileName(c.File), Stub: source}
+		switch {
+		case *dir != "":
+			name := filepath.Join(*dir, filepath.FromSlash(item.Path))
+			err := os.MkdirAll(filepath.Dir(name), 0755)
+			if err == nil {
+				err = os.WriteFile(name, []byte(source), 0644)
+			}
+			if err != nil {
+				fmt.Fprintln(o.stderr, i18n.T("error.write_file", err.Error()))
+				status = maxStatus(status, EXIT_ERROR)
+			}
+		case o.json():
+			result = append(result, item)
+		default:
+			fmt.Fprintf(o.stdout, "// %s\n%s\n", item.Path, item.Stub)
+		}
+	}
+	if o.json() && *dir == "" {
+		status = maxStatus(status, o.writeJSON(result))
+	}
+	return status
+}
@@ -0,0 +1,168 @@
+package cli
+
+import "fmt"
+
+const (
+	DIFF_CONTEXT = 3
+	MAX_EDITS    = 4096 // 超过后不再寻找最短的编辑序列, 剩余部分整体删除再插入
+)
+
+type edit struct {
+	op   byte // ' ', '-'或者'+'
+	line string
+}
+
+// Myers差分算法, 先去掉相同的前缀和后缀
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d]保存第d步之后对角线-d到d上的最远位置, 回溯时不需要最后一步
+	trace := make([][]int, 0)
+	for d := 0; d <= n+m; d++ {
+		if d > MAX_EDITS {
+			return replace(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			x := v[offset+k-1] + 1
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, append(trace, nil))
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return replace(a, b)
+}
+
+func backtrack(a, b []string, trace [][]int) []edit {
+	x, y := len(a), len(b)
+	reversed := make([]edit, 0, x+y)
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1] // 下标k+d-1对应对角线k
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, edit{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if x == prevX {
+			reversed = append(reversed, edit{'+', b[y-1]})
+			y--
+		} else {
+			reversed = append(reversed, edit{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 {
+		reversed = append(reversed, edit{' ', a[x-1]})
+		x--
+	}
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+func replace(a, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, edit{'-', line})
+	}
+	for _, line := range b {
+		edits = append(edits, edit{'+', line})
+	}
+	return edits
+}
+
+// 按unified diff的格式输出, 每个hunk以@@ -start,count +start,count @@开头, 没有差异时返回nil
+func unified(a, b []string, context int) []string {
+	edits := diffLines(a, b)
+	// 每个编辑之前的行号
+	oldLines := make([]int, len(edits)+1)
+	newLines := make([]int, len(edits)+1)
+	for i, e := range edits {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if e.op != '+' {
+			oldLines[i+1]++
+		}
+		if e.op != '-' {
+			newLines[i+1]++
+		}
+	}
+	var result []string
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// 两处变化之间的相同行不超过2*context时合并为一个hunk
+		end, unchanged := i, 0
+		for j := i; j < len(edits) && unchanged <= 2*context; j++ {
+			if edits[j].op == ' ' {
+				unchanged++
+			} else {
+				end, unchanged = j+1, 0
+			}
+		}
+		end += context
+		if end > len(edits) {
+			end = len(edits)
+		}
+		result = append(result, fmt.Sprintf("@@ -%s +%s @@", hunkRange(oldLines[start], oldLines[end]-oldLines[start]),
+			hunkRange(newLines[start], newLines[end]-newLines[start])))
+		for _, e := range edits[start:end] {
+			result = append(result, string(e.op)+e.line)
+		}
+		i = end
+	}
+	return result
+}
+
+// 行号从1开始, 范围为空时是之前一行的行号
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
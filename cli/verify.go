@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/i18n"
+	"class-file-parser/sarif"
+)
+
+type findingJSON struct {
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+type verifyJSON struct {
+	Source   string        `json:"source"`
+	Class    string        `json:"class"`
+	Findings []findingJSON `json:"findings"`
+}
+
+func runVerify(e *env, args []string) int {
+	o := newOptions(e, "verify")
+	o.allowSARIF()
+	warnings := o.flags.Bool("warnings", true, i18n.T("flag.warnings"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	classes, status := o.load(inputs)
+	result := make([]verifyJSON, 0)
+	log, locator := sarif.New(), newSourceLocator()
+	for _, c := range classes {
+		item := verifyJSON{Source: c.Source, Class: c.File.ClassName(), Findings: make([]findingJSON, 0)}
+		for _, finding := range c.File.Verify() {
+			if finding.Severity == bytecode.SEVERITY_ERROR {
+				status = maxStatus(status, EXIT_FINDINGS)
+			} else if !*warnings {
+				continue
+			}
+			item.Findings = append(item.Findings, findingJSON{finding.Severity.String(), finding.Rule, finding.Location, finding.Message})
+			if o.sarif() {
+				log.Add(finding.Rule, "", sarifLevel(finding.Severity), finding.Location+": "+finding.Message,
+					locator.classLocation(c.File.ClassName(), c.File, finding.Location))
+			} else if !o.json() {
+				fmt.Fprintf(o.stdout, "%s: %s\n", c.Source, finding.String())
+			}
+		}
+		result = append(result, item)
+	}
+	switch {
+	case o.json():
+		status = maxStatus(status, o.writeJSON(result))
+	case o.sarif():
+		status = maxStatus(status, o.writeJSON(log))
+	}
+	return status
+}
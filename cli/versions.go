@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/i18n"
+	"class-file-parser/sarif"
+)
+
+type versionsJSON struct {
+	Archive    string               `json:"archive"`
+	Classes    int                  `json:"classes"`
+	MaxVersion string               `json:"maxVersion"`
+	MaxRelease string               `json:"maxRelease"`
+	Versions   map[string]int       `json:"versions"`
+	Preview    []string             `json:"preview"`
+	Problems   []versionProblemJSON `json:"problems"`
+}
+
+type versionProblemJSON struct {
+	Kind     string `json:"kind"` //feature或者release
+	Class    string `json:"class"`
+	Version  string `json:"version"`
+	Required string `json:"required"`
+	Feature  string `json:"feature,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+// 按jar统计类文件版本, 使用了声明的版本不支持的特性或者超过-max-release时退出码为1
+func runVersions(e *env, args []string) int {
+	o := newOptions(e, "versions")
+	o.allowSARIF()
+	maxRelease := o.flags.String("max-release", "", i18n.T("flag.max_release"))
+	inputs, err := o.parseInputs(args)
+	if err != nil {
+		return parseStatus(err)
+	}
+	target := uint16(0)
+	if *maxRelease != "" {
+		if target, err = bytecode.ParseRelease(*maxRelease); err != nil {
+			fmt.Fprintln(o.stderr, i18n.T("error.unknown_release", *maxRelease))
+			return EXIT_ERROR
+		}
+	}
+	classes, status := o.load(inputs)
+
+	result := make([]*versionsJSON, 0)
+	byArchive := make(map[string]*versionsJSON)
+	maxClass := make(map[string]*bytecode.ClassFile)
+	log, locator := sarif.New(), newSourceLocator()
+	for _, c := range classes {
+		f := c.File
+		archive := archiveName(c.Source, inputs)
+		item := byArchive[archive]
+		if item == nil {
+			item = &versionsJSON{Archive: archive, Versions: make(map[string]int), Preview: make([]string, 0), Problems: make([]versionProblemJSON, 0)}
+			byArchive[archive] = item
+			result = append(result, item)
+		}
+		item.Classes++
+		version := fmt.Sprintf("%d.%d", f.MajorVersion, f.MinorVersion)
+		item.Versions[version]++
+		if m := maxClass[archive]; m == nil || f.MajorVersion > m.MajorVersion || f.MajorVersion == m.MajorVersion && f.MinorVersion > m.MinorVersion {
+			maxClass[archive] = f
+			item.MaxVersion, item.MaxRelease = version, bytecode.ReleaseName(f.MajorVersion)
+		}
+		if f.IsPreview() {
+			item.Preview = append(item.Preview, f.ClassName())
+		}
+		required, start := f.MajorVersion, len(item.Problems)
+		for _, r := range f.Requirements() {
+			if r.Major > required {
+				required = r.Major
+			}
+			if r.Major > f.MajorVersion {
+				item.Problems = append(item.Problems, versionProblemJSON{Kind: "feature", Class: f.ClassName(), Version: version,
+					Required: fmt.Sprintf("%d.0", r.Major), Feature: r.Feature, Location: r.Location})
+			}
+		}
+		if target != 0 && required > target {
+			item.Problems = append(item.Problems, versionProblemJSON{Kind: "release", Class: f.ClassName(), Version: version,
+				Required: fmt.Sprintf("%d.0", required)})
+		}
+		for _, p := range item.Problems[start:] {
+			log.Add("version-"+p.Kind, "", sarif.LEVEL_ERROR, versionMessage(p, *maxRelease), locator.classLocation(p.Class, f, p.Location))
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Archive < result[j].Archive
+	})
+
+	switch {
+	case o.json():
+		status = maxStatus(status, o.writeJSON(result))
+	case o.sarif():
+		status = maxStatus(status, o.writeJSON(log))
+	default:
+		for _, item := range result {
+			fmt.Fprintln(o.stdout, i18n.T("versions.archive", item.Archive, item.Classes, maxClass[item.Archive].Version()))
+			for _, key := range sortedKeys(item.Versions) {
+				fmt.Fprintf(o.stdout, "  %-12s %d\n", key, item.Versions[key])
+			}
+			for _, name := range item.Preview {
+				fmt.Fprintln(o.stdout, "  "+i18n.T("versions.preview", name))
+			}
+			for _, p := range item.Problems {
+				fmt.Fprintln(o.stdout, "  "+versionMessage(p, *maxRelease))
+			}
+		}
+	}
+	for _, item := range result {
+		if len(item.Problems) > 0 {
+			status = maxStatus(status, EXIT_FINDINGS)
+		}
+	}
+	return status
+}
+
+func versionMessage(p versionProblemJSON, maxRelease string) string {
+	if p.Kind == "feature" {
+		return i18n.T("versions.feature", p.Class, p.Feature, p.Location, p.Required, p.Version)
+	}
+	return i18n.T("versions.release", p.Class, p.Required, maxRelease)
+}
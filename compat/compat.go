@@ -0,0 +1,367 @@
+package compat
+
+import (
+	"sort"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/flags"
+)
+
+// 不兼容变化的规则, 参见JLS第13章
+const (
+	CLASS_REMOVED         = "class-removed"         //13.4.1
+	CLASS_KIND_CHANGED    = "class-kind-changed"    //13.4.1 类和接口互相转换
+	CLASS_FINAL           = "class-final"           //13.4.2
+	CLASS_ABSTRACT        = "class-abstract"        //13.4.1
+	SUPERTYPE_REMOVED     = "supertype-removed"     //13.4.4
+	VISIBILITY_REDUCED    = "visibility-reduced"    //13.4.3 13.4.7
+	FIELD_REMOVED         = "field-removed"         //13.4.8
+	METHOD_REMOVED        = "method-removed"        //13.4.12
+	DESCRIPTOR_CHANGED    = "descriptor-changed"    //13.4.8 13.4.14 13.4.15
+	FINAL_ADDED           = "final-added"           //13.4.9 13.4.17
+	STATIC_CHANGED        = "static-changed"        //13.4.10 13.4.19
+	CONSTANT_CHANGED      = "constant-changed"      //13.4.9 常量会被javac内联到使用方
+	METHOD_ABSTRACT       = "method-abstract"       //13.4.16
+	ABSTRACT_METHOD_ADDED = "abstract-method-added" //13.4.16 13.5.3
+)
+
+// 一个不兼容的变化, 类名是内部形式, Member例如method run(I)V或者field count:I
+type Problem struct {
+	Rule   string `json:"rule"`
+	Class  string `json:"class"`
+	Member string `json:"member,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (p Problem) String() string {
+	result := p.Rule + " " + p.Class
+	if p.Member != "" {
+		result += " " + p.Member
+	}
+	if p.Detail != "" {
+		result += ": " + p.Detail
+	}
+	return result
+}
+
+// 访问级别, 数值越大可见范围越大
+const (
+	ACCESS_PRIVATE = iota
+	ACCESS_PACKAGE
+	ACCESS_PROTECTED
+	ACCESS_PUBLIC
+)
+
+var accessNames = []string{"private", "package", "protected", "public"}
+
+// 字段和方法的public、private、protected、static、final、abstract位置相同
+func access(value uint16) int {
+	switch {
+	case value&flags.METHOD_PUBLIC.Value() != 0:
+		return ACCESS_PUBLIC
+	case value&flags.METHOD_PROTECTED.Value() != 0:
+		return ACCESS_PROTECTED
+	case value&flags.METHOD_PRIVATE.Value() != 0:
+		return ACCESS_PRIVATE
+	}
+	return ACCESS_PACKAGE
+}
+
+type member struct {
+	kind  string //field或者method
+	name  string
+	desc  string
+	flags uint16
+	file  *bytecode.ClassFile
+	field *bytecode.FieldInfo
+}
+
+func (m *member) key() string {
+	if m.kind == "field" {
+		return m.name + ":" + m.desc
+	}
+	return m.name + m.desc
+}
+
+func (m *member) is(flag uint16) bool {
+	return m.flags&flag != 0
+}
+
+// 字段的ConstantValue, 没有时返回空字符串
+func (m *member) constant() string {
+	if m.field == nil {
+		return ""
+	}
+	for _, attr := range m.field.Attributes {
+		if value, ok := attr.(*bytecode.ConstantValue); ok {
+			tag := byte('s')
+			if len(m.desc) == 1 {
+				tag = m.desc[0]
+			}
+			return bytecode.ResolveConstantValue(m.file.ConstantPool, value.ConstantValueIndex, tag).String()
+		}
+	}
+	return ""
+}
+
+// 同名的类只保留第一个, 与类路径的查找顺序一致
+type classSet map[string]*bytecode.ClassFile
+
+func newClassSet(classes []*bytecode.ClassFile) classSet {
+	result := make(classSet)
+	for _, f := range classes {
+		if _, ok := result[f.ClassName()]; !ok {
+			result[f.ClassName()] = f
+		}
+	}
+	return result
+}
+
+// 成员类的访问级别在InnerClasses中, 局部类和匿名类不属于API
+func (s classSet) access(f *bytecode.ClassFile) int {
+	level := ACCESS_PACKAGE
+	if f.Flags()&flags.CLASS_PUBLIC != 0 {
+		level = ACCESS_PUBLIC
+	}
+	outer, inner := "", false
+	for _, attr := range f.Attributes {
+		innerClasses, ok := attr.(*bytecode.InnerClasses)
+		if !ok {
+			continue
+		}
+		for _, c := range innerClasses.Classes {
+			if bytecode.ClassNameAt(f.ConstantPool, c.InnerClassIndex) != f.ClassName() {
+				continue
+			}
+			if c.OuterClassIndex == 0 || c.InnerNameIndex == 0 {
+				return ACCESS_PRIVATE
+			}
+			level, outer, inner = access(c.Flags().Value()), bytecode.ClassNameAt(f.ConstantPool, c.OuterClassIndex), true
+		}
+	}
+	if inner && s[outer] != nil && s[outer] != f {
+		if outerLevel := s.access(s[outer]); outerLevel < ACCESS_PROTECTED {
+			return outerLevel
+		}
+	}
+	return level
+}
+
+func (s classSet) exported(f *bytecode.ClassFile) bool {
+	return s.access(f) >= ACCESS_PROTECTED
+}
+
+// 使用方可以访问的成员, final类中的protected成员无法被子类访问
+func (s classSet) members(f *bytecode.ClassFile, exportedOnly bool) map[string]*member {
+	pool := f.ConstantPool
+	result := make(map[string]*member)
+	add := func(m *member) {
+		if m.is(flags.METHOD_SYNTHETIC.Value()) || m.name == "<clinit>" {
+			return
+		}
+		level := access(m.flags)
+		if exportedOnly && (level < ACCESS_PROTECTED || level == ACCESS_PROTECTED && f.Flags()&flags.CLASS_FINAL != 0) {
+			return
+		}
+		result[m.key()] = m
+	}
+	for i := range f.Fields {
+		field := &f.Fields[i]
+		add(&member{kind: "field", name: field.Name(pool), desc: field.Descriptor(pool), flags: field.Flags().Value(), file: f, field: field})
+	}
+	for i := range f.Methods {
+		method := &f.Methods[i]
+		add(&member{kind: "method", name: method.Name(pool), desc: method.Descriptor(pool), flags: method.Flags().Value(), file: f})
+	}
+	return result
+}
+
+// 所有父类和接口, 不在集合中的类型(例如JDK中的类)只包含自身
+func (s classSet) supertypes(name string) []string {
+	visited := map[string]bool{name: true}
+	queue := []string{name}
+	result := make([]string, 0)
+	for len(queue) > 0 {
+		f := s[queue[0]]
+		queue = queue[1:]
+		if f == nil {
+			continue
+		}
+		names := f.InterfaceNames()
+		if super := f.SuperClassName(); super != "" {
+			names = append([]string{super}, names...)
+		}
+		for _, super := range names {
+			if !visited[super] {
+				visited[super] = true
+				queue = append(queue, super)
+				result = append(result, super)
+			}
+		}
+	}
+	return result
+}
+
+// 父类型中声明的非私有成员, 删除的成员可以通过继承解析到时仍然兼容
+func (s classSet) inherited(name, key string) *member {
+	for _, super := range s.supertypes(name) {
+		if f := s[super]; f != nil {
+			if m := s.members(f, false)[key]; m != nil && access(m.flags) != ACCESS_PRIVATE {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+type checker struct {
+	old      classSet
+	new      classSet
+	problems []Problem
+}
+
+func (c *checker) add(rule, class, member, detail string) {
+	c.problems = append(c.problems, Problem{Rule: rule, Class: class, Member: member, Detail: detail})
+}
+
+// 检查旧版本中public和protected的API在新版本中是否二进制兼容, 结果按类名排序
+func Check(oldClasses, newClasses []*bytecode.ClassFile) []Problem {
+	c := &checker{old: newClassSet(oldClasses), new: newClassSet(newClasses), problems: make([]Problem, 0)}
+	names := make([]string, 0, len(c.old))
+	for name := range c.old {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := c.old[name]
+		if c.old.exported(a) {
+			c.class(name, a, c.new[name])
+		}
+	}
+	return c.problems
+}
+
+func (c *checker) class(name string, a, b *bytecode.ClassFile) {
+	if b == nil {
+		c.add(CLASS_REMOVED, name, "", "")
+		return
+	}
+	if oldLevel, newLevel := c.old.access(a), c.new.access(b); newLevel < ACCESS_PROTECTED || newLevel < oldLevel {
+		c.add(VISIBILITY_REDUCED, name, "", accessNames[oldLevel]+" -> "+accessNames[newLevel])
+		return
+	}
+	oldFlags, newFlags := a.Flags(), b.Flags()
+	isInterface := newFlags&flags.CLASS_INTERFACE != 0
+	if oldFlags&flags.CLASS_INTERFACE != newFlags&flags.CLASS_INTERFACE {
+		c.add(CLASS_KIND_CHANGED, name, "", oldFlags.Kind()+" -> "+newFlags.Kind())
+	} else if !isInterface {
+		if oldFlags&flags.CLASS_FINAL == 0 && newFlags&flags.CLASS_FINAL != 0 {
+			c.add(CLASS_FINAL, name, "", "")
+		}
+		if oldFlags&flags.CLASS_ABSTRACT == 0 && newFlags&flags.CLASS_ABSTRACT != 0 {
+			c.add(CLASS_ABSTRACT, name, "", "")
+		}
+	}
+	newSupertypes := make(map[string]bool)
+	for _, super := range c.new.supertypes(name) {
+		newSupertypes[super] = true
+	}
+	for _, super := range c.old.supertypes(name) {
+		if !newSupertypes[super] {
+			c.add(SUPERTYPE_REMOVED, name, "", super)
+		}
+	}
+
+	oldMembers, newMembers := c.old.members(a, true), c.new.members(b, false)
+	for _, key := range sortedKeys(oldMembers) {
+		m := oldMembers[key]
+		if n := newMembers[key]; n != nil {
+			c.member(name, m, n, newFlags&flags.CLASS_FINAL != 0)
+		} else if c.new.inherited(name, key) == nil {
+			c.removed(name, m, newMembers)
+		}
+	}
+	c.abstractMethods(name, a, b)
+}
+
+func (c *checker) member(class string, a, b *member, finalClass bool) {
+	label := a.kind + " " + a.key()
+	if oldLevel, newLevel := access(a.flags), access(b.flags); newLevel < oldLevel {
+		c.add(VISIBILITY_REDUCED, class, label, accessNames[oldLevel]+" -> "+accessNames[newLevel])
+	}
+	static := flags.METHOD_STATIC.Value()
+	if a.is(static) != b.is(static) {
+		c.add(STATIC_CHANGED, class, label, staticName(a)+" -> "+staticName(b))
+	}
+	// 静态方法和final类中的方法不能被覆盖, 增加final不影响使用方
+	final := flags.METHOD_FINAL.Value()
+	if !a.is(final) && b.is(final) && (a.kind == "field" || !b.is(static) && !finalClass) {
+		c.add(FINAL_ADDED, class, label, "")
+	}
+	if a.kind == "method" && !a.is(flags.METHOD_ABSTRACT.Value()) && b.is(flags.METHOD_ABSTRACT.Value()) {
+		c.add(METHOD_ABSTRACT, class, label, "")
+	}
+	if oldValue := a.constant(); oldValue != "" {
+		if newValue := b.constant(); newValue != oldValue {
+			if newValue == "" {
+				newValue = "<not constant>"
+			}
+			c.add(CONSTANT_CHANGED, class, label, oldValue+" -> "+newValue)
+		}
+	}
+}
+
+func staticName(m *member) string {
+	if m.is(flags.METHOD_STATIC.Value()) {
+		return "static"
+	}
+	return "instance"
+}
+
+// 同名成员只是描述符不同时报告为描述符变化, 构造方法同样按名称<init>匹配
+func (c *checker) removed(class string, m *member, newMembers map[string]*member) {
+	descriptors := make([]string, 0)
+	for _, key := range sortedKeys(newMembers) {
+		if n := newMembers[key]; n.kind == m.kind && n.name == m.name && access(n.flags) >= ACCESS_PROTECTED {
+			descriptors = append(descriptors, n.desc)
+		}
+	}
+	label := m.kind + " " + m.key()
+	switch {
+	case len(descriptors) > 0:
+		c.add(DESCRIPTOR_CHANGED, class, label, m.desc+" -> "+strings.Join(descriptors, ", "))
+	case m.kind == "field":
+		c.add(FIELD_REMOVED, class, label, "")
+	default:
+		c.add(METHOD_REMOVED, class, label, "")
+	}
+}
+
+// 接口或者抽象类中新增的抽象方法, 已有的实现类没有实现这些方法, 调用时抛出AbstractMethodError
+func (c *checker) abstractMethods(class string, a, b *bytecode.ClassFile) {
+	if b.Flags()&(flags.CLASS_INTERFACE|flags.CLASS_ABSTRACT) == 0 {
+		return
+	}
+	oldMembers, newMembers := c.old.members(a, false), c.new.members(b, true)
+	abstract := flags.METHOD_ABSTRACT.Value()
+	for _, key := range sortedKeys(newMembers) {
+		m := newMembers[key]
+		if m.kind != "method" || !m.is(abstract) || oldMembers[key] != nil {
+			continue
+		}
+		if inherited := c.old.inherited(class, key); inherited != nil && inherited.is(abstract) {
+			continue
+		}
+		c.add(ABSTRACT_METHOD_ADDED, class, m.kind+" "+key, "")
+	}
+}
+
+func sortedKeys(members map[string]*member) []string {
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
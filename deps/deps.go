@@ -0,0 +1,239 @@
+package deps
+
+import (
+	"sort"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/stub"
+)
+
+// 依赖的粒度
+const (
+	LEVEL_CLASS   = "class"
+	LEVEL_PACKAGE = "package"
+	LEVEL_ARCHIVE = "archive"
+)
+
+// 依赖的类不在输入中, 也不属于JDK
+const NOT_FOUND = "not found"
+
+// 默认包的名称
+const UNNAMED_PACKAGE = "<unnamed>"
+
+// 一个类和它所在的jar或者目录
+type Class struct {
+	Archive string
+	File    *bytecode.ClassFile
+}
+
+// 一条依赖, 粒度为类或者包时From和To是内部形式的名称, 粒度为archive时是jar名或者JDK的模块名
+type Dependency struct {
+	From        string `json:"from"`
+	FromArchive string `json:"fromArchive"`
+	To          string `json:"to"`
+	ToArchive   string `json:"toArchive"`
+	Internal    bool   `json:"internal,omitempty"` //To是JDK内部API
+}
+
+// 类引用到的其他类: 常量池中的类、描述符、泛型签名和注解, 按名称排序, 不包括类本身
+func References(f *bytecode.ClassFile) []string {
+	pool := f.ConstantPool
+	seen := make(map[string]bool)
+	for _, name := range f.ReferencedClasses() {
+		seen[name] = true
+	}
+	r := &references{pool: pool, seen: seen}
+	r.attributes(f.Attributes)
+	for i := range f.Fields {
+		r.attributes(f.Fields[i].Attributes)
+	}
+	for i := range f.Methods {
+		r.attributes(f.Methods[i].Attributes)
+	}
+	delete(seen, f.ClassName())
+	classes := make([]string, 0, len(seen))
+	for name := range seen {
+		classes = append(classes, name)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+type references struct {
+	pool []bytecode.ConstantPoolInfo
+	seen map[string]bool
+}
+
+func (r *references) add(name string) {
+	if name != "" {
+		r.seen[name] = true
+	}
+}
+
+// 描述符是对象或者对象数组时添加其中的类
+func (r *references) descriptor(desc string) {
+	desc = strings.TrimLeft(desc, "[")
+	if strings.HasPrefix(desc, "L") && strings.HasSuffix(desc, ";") {
+		r.add(desc[1 : len(desc)-1])
+	}
+}
+
+func (r *references) utf8(index uint16) string {
+	if int(index) < len(r.pool) {
+		if utf8, ok := r.pool[index].(*bytecode.ConstantUtf8); ok {
+			return string(utf8.Value)
+		}
+	}
+	return ""
+}
+
+// 签名不合法时忽略, 其中的类通常也出现在描述符中
+func (r *references) signature(text string) {
+	classes, _ := stub.SignatureClasses(text)
+	for _, name := range classes {
+		r.add(name)
+	}
+}
+
+func (r *references) annotation(a bytecode.AnnotationInfo) {
+	r.add(a.Type)
+	for _, element := range a.Elements {
+		r.value(element.Value)
+	}
+}
+
+func (r *references) value(v bytecode.AnnotationValue) {
+	switch value := v.Value.(type) {
+	case bytecode.EnumConstant:
+		r.add(value.Type)
+	case bytecode.ClassLiteral:
+		r.descriptor(value.Descriptor)
+	case bytecode.AnnotationInfo:
+		r.annotation(value)
+	case []bytecode.AnnotationValue:
+		for _, item := range value {
+			r.value(item)
+		}
+	}
+}
+
+func (r *references) attributes(attrs []bytecode.AttributeInfo) {
+	for _, a := range bytecode.AnnotationsOf(attrs, r.pool) {
+		r.annotation(a)
+	}
+	for _, attr := range attrs {
+		switch a := attr.(type) {
+		case *bytecode.Signature:
+			r.signature(r.utf8(a.SignatureIndex))
+		case *bytecode.RuntimeVisibleParameterAnnotations:
+			r.parameterAnnotations(a)
+		case *bytecode.RuntimeInvisibleParameterAnnotations:
+			r.parameterAnnotations(&a.RuntimeVisibleParameterAnnotations)
+		case *bytecode.RuntimeVisibleTypeAnnotations:
+			r.typeAnnotations(a)
+		case *bytecode.RuntimeInvisibleTypeAnnotations:
+			r.typeAnnotations(&a.RuntimeVisibleTypeAnnotations)
+		case *bytecode.AnnotationDefault:
+			r.value(a.DefaultValue.Resolve(r.pool, bytecode.RETENTION_VISIBLE))
+		case *bytecode.Code:
+			r.attributes(a.Attributes)
+		case *bytecode.LocalVariableTable:
+			for _, v := range a.LocalVariable {
+				r.descriptor(r.utf8(v.DescriptorIndex))
+			}
+		case *bytecode.LocalVariableTypeTable:
+			for _, v := range a.LocalVariableType {
+				r.signature(r.utf8(v.SignatureIndex))
+			}
+		case *bytecode.Record:
+			for _, component := range a.RecordComponentInfo {
+				r.descriptor(r.utf8(component.DescriptorIndex))
+				r.attributes(component.Attributes)
+			}
+		}
+	}
+}
+
+func (r *references) parameterAnnotations(a *bytecode.RuntimeVisibleParameterAnnotations) {
+	for _, parameter := range a.ParameterAnnotations {
+		for i := range parameter.Annotations {
+			r.annotation(parameter.Annotations[i].Resolve(r.pool, bytecode.RETENTION_VISIBLE))
+		}
+	}
+}
+
+func (r *references) typeAnnotations(a *bytecode.RuntimeVisibleTypeAnnotations) {
+	for i := range a.Annotations {
+		r.annotation(a.Annotations[i].Resolve(r.pool, bytecode.RETENTION_VISIBLE))
+	}
+}
+
+// 内部形式的包名, 默认包为<unnamed>
+func Package(className string) string {
+	if i := strings.LastIndex(className, "/"); i >= 0 {
+		return className[:i]
+	}
+	return UNNAMED_PACKAGE
+}
+
+// 计算依赖, 依赖的类先在输入中查找, 然后是JDK的模块, 都找不到时为not found.
+// 输入中同名的类以第一个为准; 粒度为包或者archive时, 同一个包或者archive内部的依赖不输出
+func Analyze(classes []Class, level string, excludeJDK bool) []Dependency {
+	archives := make(map[string]string)
+	for _, c := range classes {
+		if _, ok := archives[c.File.ClassName()]; !ok {
+			archives[c.File.ClassName()] = c.Archive
+		}
+	}
+	// 合并之后只要有一个类是内部API, 依赖就标记为内部API
+	seen := make(map[Dependency]int)
+	result := make([]Dependency, 0)
+	for _, c := range classes {
+		from := c.File.ClassName()
+		for _, to := range References(c.File) {
+			d := Dependency{From: from, FromArchive: c.Archive, To: to, ToArchive: archives[to]}
+			if d.ToArchive == "" {
+				d.ToArchive = Module(to)
+				if d.ToArchive == "" {
+					d.ToArchive = NOT_FOUND
+				} else if excludeJDK {
+					continue
+				}
+				d.Internal = IsInternal(to)
+			}
+			switch level {
+			case LEVEL_PACKAGE:
+				d.From, d.To = Package(d.From), Package(d.To)
+			case LEVEL_ARCHIVE:
+				d.From, d.To = d.FromArchive, d.ToArchive
+			}
+			if level != LEVEL_CLASS && d.From == d.To && d.FromArchive == d.ToArchive {
+				continue
+			}
+			internal := d.Internal
+			d.Internal = false
+			if i, ok := seen[d]; ok {
+				result[i].Internal = result[i].Internal || internal
+				continue
+			}
+			seen[d] = len(result)
+			d.Internal = internal
+			result = append(result, d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.FromArchive != b.FromArchive {
+			return a.FromArchive < b.FromArchive
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.ToArchive < b.ToArchive
+	})
+	return result
+}
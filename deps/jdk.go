@@ -0,0 +1,126 @@
+package deps
+
+import "strings"
+
+// JDK中的包所在的模块, 按最长前缀匹配, 模块名为空表示已经从JDK中移除的包.
+// 不以/结尾的是类名, 只匹配该类和它的内部类, 例如JDK 9之后sun.misc和sun.reflect只有几个类留在jdk.unsupported.
+// 不需要安装JDK, 只包含常用的模块
+var jdkModules = map[string]string{
+	"java/":                         "java.base",
+	"javax/crypto/":                 "java.base",
+	"javax/net/":                    "java.base",
+	"javax/security/auth/":          "java.base",
+	"javax/security/cert/":          "java.base",
+	"jdk/internal/":                 "java.base",
+	"sun/":                          "java.base",
+	"com/sun/crypto/provider/":      "java.base",
+	"sun/misc/Unsafe":               "jdk.unsupported",
+	"sun/misc/Signal":               "jdk.unsupported",
+	"sun/misc/SignalHandler":        "jdk.unsupported",
+	"sun/reflect/Reflection":        "jdk.unsupported",
+	"sun/reflect/ReflectionFactory": "jdk.unsupported",
+	"sun/misc/":                     "",
+	"sun/reflect/":                  "",
+	"java/applet/":                  "java.desktop",
+	"java/awt/":                     "java.desktop",
+	"java/beans/":                   "java.desktop",
+	"javax/accessibility/":          "java.desktop",
+	"javax/imageio/":                "java.desktop",
+	"javax/print/":                  "java.desktop",
+	"javax/sound/":                  "java.desktop",
+	"javax/swing/":                  "java.desktop",
+	"java/lang/instrument/":         "java.instrument",
+	"java/lang/management/":         "java.management",
+	"javax/management/":             "java.management",
+	"java/net/http/":                "java.net.http",
+	"java/rmi/":                     "java.rmi",
+	"javax/rmi/ssl/":                "java.rmi",
+	"java/sql/":                     "java.sql",
+	"javax/sql/":                    "java.sql",
+	"java/util/logging/":            "java.logging",
+	"java/util/prefs/":              "java.prefs",
+	"javax/annotation/processing/":  "java.compiler",
+	"javax/lang/model/":             "java.compiler",
+	"javax/tools/":                  "java.compiler",
+	"javax/naming/":                 "java.naming",
+	"javax/script/":                 "java.scripting",
+	"javax/security/auth/kerberos/": "java.security.jgss",
+	"org/ietf/jgss/":                "java.security.jgss",
+	"javax/security/sasl/":          "java.security.sasl",
+	"javax/smartcardio/":            "java.smartcardio",
+	"javax/transaction/xa/":         "java.transaction.xa",
+	"javax/xml/":                    "java.xml",
+	"org/w3c/dom/":                  "java.xml",
+	"org/xml/sax/":                  "java.xml",
+	"com/sun/org/apache/":           "java.xml",
+	"javax/xml/crypto/":             "java.xml.crypto",
+	"com/sun/management/":           "jdk.management",
+	"com/sun/net/httpserver/":       "jdk.httpserver",
+	"com/sun/jdi/":                  "jdk.jdi",
+	"com/sun/source/":               "jdk.compiler",
+	"com/sun/tools/javac/":          "jdk.compiler",
+	"jdk/jfr/":                      "jdk.jfr",
+	"jdk/net/":                      "jdk.net",
+	// Java 11移除的Java EE和CORBA模块, 需要单独的jar
+	"javax/activation/": "",
+	"javax/annotation/": "",
+	"javax/jws/":        "",
+	"javax/xml/bind/":   "",
+	"javax/xml/soap/":   "",
+	"javax/xml/ws/":     "",
+}
+
+// JDK内部API所在的包, 参见JEP 260
+var internalPackages = []string{"sun/", "jdk/internal/", "com/sun/org/apache/", "com/sun/crypto/provider/"}
+
+// 常用的内部API及其替代
+var replacements = map[string]string{
+	"sun/misc/BASE64Encoder":     "java/util/Base64",
+	"sun/misc/BASE64Decoder":     "java/util/Base64",
+	"sun/misc/Cleaner":           "java/lang/ref/Cleaner",
+	"sun/misc/Unsafe":            "java/lang/invoke/VarHandle",
+	"sun/reflect/Reflection":     "java/lang/StackWalker",
+	"sun/security/x509/X500Name": "javax/security/auth/x500/X500Principal",
+}
+
+// 类所在的JDK模块, 不属于JDK或者已经移除时返回空字符串
+func Module(className string) string {
+	module, _ := lookupModule(className)
+	return module
+}
+
+// 第二个返回值表示类属于JDK, 包括已经移除的
+func lookupModule(className string) (string, bool) {
+	module, length := "", 0
+	for prefix, name := range jdkModules {
+		if len(prefix) > length && matchModule(className, prefix) {
+			module, length = name, len(prefix)
+		}
+	}
+	return module, length > 0
+}
+
+func matchModule(className, prefix string) bool {
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(className, prefix)
+	}
+	return className == prefix || strings.HasPrefix(className, prefix+"$")
+}
+
+// 是否为JDK的内部API, 已经移除的内部API(例如sun/misc/BASE64Encoder)也算
+func IsInternal(className string) bool {
+	if _, ok := lookupModule(className); !ok {
+		return false
+	}
+	for _, prefix := range internalPackages {
+		if strings.HasPrefix(className, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 内部API建议使用的替代, 没有时返回空字符串
+func Replacement(className string) string {
+	return replacements[className]
+}
@@ -0,0 +1,352 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 访问标志的公共接口, 每种上下文的标志位含义不同, 所以各自是一个类型
+type AccessFlags interface {
+	Value() uint16
+	// 按Java源码中的修饰符顺序输出, 不能出现在源码中的标志使用小写名称
+	String() string
+	// javap -v的格式, 例如ACC_PUBLIC, ACC_SUPER
+	Javap() string
+	// 已定义的标志名称, 按位从低到高排列
+	Names() []string
+	// 当前上下文中没有定义的位
+	Undefined() uint16
+}
+
+type flag struct {
+	mask    uint16
+	name    string
+	keyword string //源码中的修饰符, 为空表示由声明的种类体现(例如interface), 不作为修饰符输出
+	order   int    //在源码中的顺序
+}
+
+// 一种上下文中定义的所有标志, 按位从低到高排列
+type table []flag
+
+func (t table) mask() uint16 {
+	mask := uint16(0)
+	for _, f := range t {
+		mask |= f.mask
+	}
+	return mask
+}
+
+func (t table) names(value uint16) []string {
+	names := make([]string, 0)
+	for _, f := range t {
+		if value&f.mask != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+func (t table) javap(value uint16) string {
+	names := t.names(value)
+	if undefined := value &^ t.mask(); undefined != 0 {
+		names = append(names, fmt.Sprintf("0x%04X", undefined))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (t table) source(value uint16) string {
+	keywords := make([]string, 0)
+	for order := 0; order <= len(t); order++ {
+		for _, f := range t {
+			if f.order == order && value&f.mask != 0 && f.keyword != "" {
+				keywords = append(keywords, f.keyword)
+			}
+		}
+	}
+	return strings.Join(keywords, " ")
+}
+
+type flagsJSON struct {
+	Value     uint16   `json:"value"`
+	Names     []string `json:"names"`
+	Undefined uint16   `json:"undefined,omitempty"`
+}
+
+func marshal(f AccessFlags) ([]byte, error) {
+	return json.Marshal(flagsJSON{Value: f.Value(), Names: f.Names(), Undefined: f.Undefined()})
+}
+
+// 类的访问标志, 参见JVMS 4.1表4.1-B
+type ClassFlags uint16
+
+const (
+	CLASS_PUBLIC     ClassFlags = 0x0001
+	CLASS_FINAL      ClassFlags = 0x0010
+	CLASS_SUPER      ClassFlags = 0x0020
+	CLASS_INTERFACE  ClassFlags = 0x0200
+	CLASS_ABSTRACT   ClassFlags = 0x0400
+	CLASS_SYNTHETIC  ClassFlags = 0x1000
+	CLASS_ANNOTATION ClassFlags = 0x2000
+	CLASS_ENUM       ClassFlags = 0x4000
+	CLASS_MODULE     ClassFlags = 0x8000
+)
+
+var classTable = table{
+	{0x0001, "ACC_PUBLIC", "public", 0},
+	{0x0010, "ACC_FINAL", "final", 2},
+	{0x0020, "ACC_SUPER", "", 0},
+	{0x0200, "ACC_INTERFACE", "", 0},
+	{0x0400, "ACC_ABSTRACT", "abstract", 1},
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 3},
+	{0x2000, "ACC_ANNOTATION", "", 0},
+	{0x4000, "ACC_ENUM", "", 0},
+	{0x8000, "ACC_MODULE", "", 0},
+}
+
+func (f ClassFlags) Value() uint16     { return uint16(f) }
+func (f ClassFlags) Javap() string     { return classTable.javap(uint16(f)) }
+func (f ClassFlags) Names() []string   { return classTable.names(uint16(f)) }
+func (f ClassFlags) Undefined() uint16 { return uint16(f) &^ classTable.mask() }
+
+func (f ClassFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
+
+// 接口隐含abstract, 不输出
+func (f ClassFlags) String() string {
+	if f&CLASS_INTERFACE != 0 {
+		f &^= CLASS_ABSTRACT
+	}
+	return classTable.source(uint16(f))
+}
+
+// 声明的种类: class、interface、@interface、enum或者module
+func (f ClassFlags) Kind() string {
+	return kind(uint16(f))
+}
+
+func kind(value uint16) string {
+	switch {
+	case value&uint16(CLASS_MODULE) != 0:
+		return "module"
+	case value&uint16(CLASS_ANNOTATION) != 0:
+		return "@interface"
+	case value&uint16(CLASS_INTERFACE) != 0:
+		return "interface"
+	case value&uint16(CLASS_ENUM) != 0:
+		return "enum"
+	}
+	return "class"
+}
+
+// 字段的访问标志, 参见JVMS 4.5表4.5-A
+type FieldFlags uint16
+
+const (
+	FIELD_PUBLIC    FieldFlags = 0x0001
+	FIELD_PRIVATE   FieldFlags = 0x0002
+	FIELD_PROTECTED FieldFlags = 0x0004
+	FIELD_STATIC    FieldFlags = 0x0008
+	FIELD_FINAL     FieldFlags = 0x0010
+	FIELD_VOLATILE  FieldFlags = 0x0040
+	FIELD_TRANSIENT FieldFlags = 0x0080
+	FIELD_SYNTHETIC FieldFlags = 0x1000
+	FIELD_ENUM      FieldFlags = 0x4000
+)
+
+var fieldTable = table{
+	{0x0001, "ACC_PUBLIC", "public", 0},
+	{0x0002, "ACC_PRIVATE", "private", 2},
+	{0x0004, "ACC_PROTECTED", "protected", 1},
+	{0x0008, "ACC_STATIC", "static", 3},
+	{0x0010, "ACC_FINAL", "final", 4},
+	{0x0040, "ACC_VOLATILE", "volatile", 6},
+	{0x0080, "ACC_TRANSIENT", "transient", 5},
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 7},
+	{0x4000, "ACC_ENUM", "enum", 8},
+}
+
+func (f FieldFlags) Value() uint16     { return uint16(f) }
+func (f FieldFlags) String() string    { return fieldTable.source(uint16(f)) }
+func (f FieldFlags) Javap() string     { return fieldTable.javap(uint16(f)) }
+func (f FieldFlags) Names() []string   { return fieldTable.names(uint16(f)) }
+func (f FieldFlags) Undefined() uint16 { return uint16(f) &^ fieldTable.mask() }
+
+func (f FieldFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
+
+// 方法的访问标志, 参见JVMS 4.6表4.6-A
+type MethodFlags uint16
+
+const (
+	METHOD_PUBLIC       MethodFlags = 0x0001
+	METHOD_PRIVATE      MethodFlags = 0x0002
+	METHOD_PROTECTED    MethodFlags = 0x0004
+	METHOD_STATIC       MethodFlags = 0x0008
+	METHOD_FINAL        MethodFlags = 0x0010
+	METHOD_SYNCHRONIZED MethodFlags = 0x0020
+	METHOD_BRIDGE       MethodFlags = 0x0040
+	METHOD_VARARGS      MethodFlags = 0x0080
+	METHOD_NATIVE       MethodFlags = 0x0100
+	METHOD_ABSTRACT     MethodFlags = 0x0400
+	METHOD_STRICT       MethodFlags = 0x0800
+	METHOD_SYNTHETIC    MethodFlags = 0x1000
+)
+
+var methodTable = table{
+	{0x0001, "ACC_PUBLIC", "public", 0},
+	{0x0002, "ACC_PRIVATE", "private", 2},
+	{0x0004, "ACC_PROTECTED", "protected", 1},
+	{0x0008, "ACC_STATIC", "static", 4},
+	{0x0010, "ACC_FINAL", "final", 5},
+	{0x0020, "ACC_SYNCHRONIZED", "synchronized", 6},
+	{0x0040, "ACC_BRIDGE", "bridge", 9},
+	{0x0080, "ACC_VARARGS", "varargs", 10},
+	{0x0100, "ACC_NATIVE", "native", 7},
+	{0x0400, "ACC_ABSTRACT", "abstract", 3},
+	{0x0800, "ACC_STRICT", "strictfp", 8},
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 11},
+}
+
+func (f MethodFlags) Value() uint16     { return uint16(f) }
+func (f MethodFlags) String() string    { return methodTable.source(uint16(f)) }
+func (f MethodFlags) Javap() string     { return methodTable.javap(uint16(f)) }
+func (f MethodFlags) Names() []string   { return methodTable.names(uint16(f)) }
+func (f MethodFlags) Undefined() uint16 { return uint16(f) &^ methodTable.mask() }
+
+func (f MethodFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
+
+// InnerClasses属性中内部类的访问标志, 参见JVMS 4.7.6表4.7.6-A
+type InnerClassFlags uint16
+
+const (
+	INNER_PUBLIC     InnerClassFlags = 0x0001
+	INNER_PRIVATE    InnerClassFlags = 0x0002
+	INNER_PROTECTED  InnerClassFlags = 0x0004
+	INNER_STATIC     InnerClassFlags = 0x0008
+	INNER_FINAL      InnerClassFlags = 0x0010
+	INNER_INTERFACE  InnerClassFlags = 0x0200
+	INNER_ABSTRACT   InnerClassFlags = 0x0400
+	INNER_SYNTHETIC  InnerClassFlags = 0x1000
+	INNER_ANNOTATION InnerClassFlags = 0x2000
+	INNER_ENUM       InnerClassFlags = 0x4000
+)
+
+var innerClassTable = table{
+	{0x0001, "ACC_PUBLIC", "public", 0},
+	{0x0002, "ACC_PRIVATE", "private", 2},
+	{0x0004, "ACC_PROTECTED", "protected", 1},
+	{0x0008, "ACC_STATIC", "static", 4},
+	{0x0010, "ACC_FINAL", "final", 5},
+	{0x0200, "ACC_INTERFACE", "", 0},
+	{0x0400, "ACC_ABSTRACT", "abstract", 3},
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 6},
+	{0x2000, "ACC_ANNOTATION", "", 0},
+	{0x4000, "ACC_ENUM", "", 0},
+}
+
+func (f InnerClassFlags) Value() uint16     { return uint16(f) }
+func (f InnerClassFlags) Javap() string     { return innerClassTable.javap(uint16(f)) }
+func (f InnerClassFlags) Names() []string   { return innerClassTable.names(uint16(f)) }
+func (f InnerClassFlags) Undefined() uint16 { return uint16(f) &^ innerClassTable.mask() }
+func (f InnerClassFlags) Kind() string      { return kind(uint16(f)) }
+
+func (f InnerClassFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
+
+// 成员接口隐含abstract和static, 不输出
+func (f InnerClassFlags) String() string {
+	if f&INNER_INTERFACE != 0 {
+		f &^= INNER_ABSTRACT | INNER_STATIC
+	}
+	return innerClassTable.source(uint16(f))
+}
+
+// MethodParameters属性中参数的访问标志, 参见JVMS 4.7.24
+type ParameterFlags uint16
+
+const (
+	PARAMETER_FINAL     ParameterFlags = 0x0010
+	PARAMETER_SYNTHETIC ParameterFlags = 0x1000
+	PARAMETER_MANDATED  ParameterFlags = 0x8000
+)
+
+var parameterTable = table{
+	{0x0010, "ACC_FINAL", "final", 0},
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 1},
+	{0x8000, "ACC_MANDATED", "mandated", 2},
+}
+
+func (f ParameterFlags) Value() uint16     { return uint16(f) }
+func (f ParameterFlags) String() string    { return parameterTable.source(uint16(f)) }
+func (f ParameterFlags) Javap() string     { return parameterTable.javap(uint16(f)) }
+func (f ParameterFlags) Names() []string   { return parameterTable.names(uint16(f)) }
+func (f ParameterFlags) Undefined() uint16 { return uint16(f) &^ parameterTable.mask() }
+
+func (f ParameterFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
+
+// Module属性中模块的标志, 参见JVMS 4.7.25
+type ModuleFlags uint16
+
+const (
+	MODULE_OPEN      ModuleFlags = 0x0020
+	MODULE_SYNTHETIC ModuleFlags = 0x1000
+	MODULE_MANDATED  ModuleFlags = 0x8000
+)
+
+var moduleTable = table{
+	{0x0020, "ACC_OPEN", "open", 0},
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 1},
+	{0x8000, "ACC_MANDATED", "mandated", 2},
+}
+
+func (f ModuleFlags) Value() uint16     { return uint16(f) }
+func (f ModuleFlags) String() string    { return moduleTable.source(uint16(f)) }
+func (f ModuleFlags) Javap() string     { return moduleTable.javap(uint16(f)) }
+func (f ModuleFlags) Names() []string   { return moduleTable.names(uint16(f)) }
+func (f ModuleFlags) Undefined() uint16 { return uint16(f) &^ moduleTable.mask() }
+
+func (f ModuleFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
+
+// requires的标志
+type RequiresFlags uint16
+
+const (
+	REQUIRES_TRANSITIVE   RequiresFlags = 0x0020
+	REQUIRES_STATIC_PHASE RequiresFlags = 0x0040
+	REQUIRES_SYNTHETIC    RequiresFlags = 0x1000
+	REQUIRES_MANDATED     RequiresFlags = 0x8000
+)
+
+var requiresTable = table{
+	{0x0020, "ACC_TRANSITIVE", "transitive", 0},
+	{0x0040, "ACC_STATIC_PHASE", "static", 1},
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 2},
+	{0x8000, "ACC_MANDATED", "mandated", 3},
+}
+
+func (f RequiresFlags) Value() uint16     { return uint16(f) }
+func (f RequiresFlags) String() string    { return requiresTable.source(uint16(f)) }
+func (f RequiresFlags) Javap() string     { return requiresTable.javap(uint16(f)) }
+func (f RequiresFlags) Names() []string   { return requiresTable.names(uint16(f)) }
+func (f RequiresFlags) Undefined() uint16 { return uint16(f) &^ requiresTable.mask() }
+
+func (f RequiresFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
+
+// exports和opens的标志
+type ExportsFlags uint16
+
+const (
+	EXPORTS_SYNTHETIC ExportsFlags = 0x1000
+	EXPORTS_MANDATED  ExportsFlags = 0x8000
+)
+
+var exportsTable = table{
+	{0x1000, "ACC_SYNTHETIC", "synthetic", 0},
+	{0x8000, "ACC_MANDATED", "mandated", 1},
+}
+
+func (f ExportsFlags) Value() uint16     { return uint16(f) }
+func (f ExportsFlags) String() string    { return exportsTable.source(uint16(f)) }
+func (f ExportsFlags) Javap() string     { return exportsTable.javap(uint16(f)) }
+func (f ExportsFlags) Names() []string   { return exportsTable.names(uint16(f)) }
+func (f ExportsFlags) Undefined() uint16 { return uint16(f) &^ exportsTable.mask() }
+
+func (f ExportsFlags) MarshalJSON() ([]byte, error) { return marshal(f) }
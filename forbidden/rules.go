@@ -0,0 +1,123 @@
+package forbidden
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// 规则的种类
+const (
+	KIND_CLASS  = "class"
+	KIND_FIELD  = "field"
+	KIND_METHOD = "method"
+)
+
+// 禁止使用的类、字段或者方法, Pattern是规则文件中的写法
+type Rule struct {
+	Kind    string
+	Pattern string
+	Message string
+	Line    int
+	pattern *regexp.Regexp
+}
+
+// 例如method:java/lang/System.exit
+func (r *Rule) ID() string {
+	return r.Kind + ":" + r.Pattern
+}
+
+// 匹配的类中不报告匹配的引用, Reference为空时不报告任何引用
+type Suppression struct {
+	Class     string
+	Reference string
+	class     *regexp.Regexp
+	reference *regexp.Regexp
+}
+
+type Rules struct {
+	Rules        []*Rule
+	Suppressions []*Suppression
+	Annotations  []string //带有这些注解的类和方法不报告
+	annotations  []*regexp.Regexp
+}
+
+// 通配符: **匹配任意字符, *匹配除/以外的字符, ?匹配除/以外的一个字符, 其他字符按原样匹配
+func glob(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// 方法没有写描述符时匹配所有重载, 描述符以)结尾时匹配任意返回类型; 字段没有写描述符时匹配任意类型
+func memberPattern(kind, pattern string) string {
+	switch {
+	case kind == KIND_METHOD && !strings.Contains(pattern, "("):
+		return pattern + "(**"
+	case kind == KIND_METHOD && strings.HasSuffix(pattern, ")"):
+		return pattern + "**"
+	case kind == KIND_FIELD && !strings.Contains(pattern, ":"):
+		return pattern + ":**"
+	}
+	return pattern
+}
+
+// 规则文件每行一条, #开始的行是注释, 类名都是内部形式:
+//
+//	method java/lang/System.exit(I)V 消息
+//	method java/lang/String.getBytes() 使用默认字符集
+//	class sun/misc/Unsafe
+//	field java/lang/System.out
+//	suppress com/acme/cli/** java/lang/System.exit
+//	annotation com/acme/SuppressForbidden
+func ParseRules(in io.Reader) (*Rules, error) {
+	rules := &Rules{}
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing pattern", line)
+		}
+		switch kind := fields[0]; kind {
+		case KIND_CLASS, KIND_FIELD, KIND_METHOD:
+			message := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text[len(kind):]), fields[1]))
+			rules.Rules = append(rules.Rules, &Rule{Kind: kind, Pattern: fields[1], Message: message, Line: line,
+				pattern: glob(memberPattern(kind, fields[1]))})
+		case "suppress":
+			s := &Suppression{Class: fields[1], class: glob(fields[1])}
+			if len(fields) > 2 {
+				s.Reference, s.reference = fields[2], glob(fields[2]+"**")
+			}
+			rules.Suppressions = append(rules.Suppressions, s)
+		case "annotation":
+			rules.Annotations = append(rules.Annotations, fields[1])
+			rules.annotations = append(rules.annotations, glob(fields[1]))
+		default:
+			return nil, fmt.Errorf("line %d: unknown rule %s", line, kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
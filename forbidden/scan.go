@@ -0,0 +1,171 @@
+package forbidden
+
+import (
+	"strings"
+
+	"class-file-parser/bytecode"
+)
+
+// 一处禁止的引用, Method为空时引用来自类的声明; Pc为-1时不在字节码中, Line为0时没有行号.
+// SourcePath是可能的源文件路径, 例如com/acme/Main.java
+type Finding struct {
+	Rule       string `json:"rule"`
+	Kind       string `json:"kind"`
+	Message    string `json:"message,omitempty"`
+	Class      string `json:"class"`
+	Method     string `json:"method,omitempty"`
+	Reference  string `json:"reference"`
+	Pc         int    `json:"pc"`
+	Line       int    `json:"line,omitempty"`
+	SourceFile string `json:"sourceFile,omitempty"`
+	SourcePath string `json:"sourcePath"`
+}
+
+type scanner struct {
+	rules      *Rules
+	f          *bytecode.ClassFile
+	sourceFile string
+	sourcePath string
+	findings   []Finding
+}
+
+// 检查类的声明和字节码中的引用: 父类、接口、成员的描述符, 指令引用的类、字段和方法, 以及方法引用的MethodHandle
+func (rs *Rules) Scan(f *bytecode.ClassFile) []Finding {
+	s := &scanner{rules: rs, f: f, sourceFile: f.SourceFile(), sourcePath: f.SourcePath(), findings: make([]Finding, 0)}
+	if rs.annotated(f.Attributes, f.ConstantPool) || rs.suppressed(f.ClassName(), "") {
+		return s.findings
+	}
+	pool := f.ConstantPool
+	for _, name := range append([]string{f.SuperClassName()}, f.InterfaceNames()...) {
+		s.class(name, "", -1, 0)
+	}
+	for i := range f.Fields {
+		for _, name := range bytecode.DescriptorClasses(f.Fields[i].Descriptor(pool)) {
+			s.class(name, "", -1, 0)
+		}
+	}
+	for i := range f.Methods {
+		m := &f.Methods[i]
+		method := m.Name(pool) + m.Descriptor(pool)
+		if rs.annotated(m.Attributes, pool) {
+			continue
+		}
+		for _, name := range bytecode.DescriptorClasses(m.Descriptor(pool)) {
+			s.class(name, method, -1, 0)
+		}
+		s.code(method, m)
+	}
+	return s.findings
+}
+
+func (rs *Rules) annotated(attrs []bytecode.AttributeInfo, pool []bytecode.ConstantPoolInfo) bool {
+	for _, annotation := range bytecode.AnnotationsOf(attrs, pool) {
+		for _, pattern := range rs.annotations {
+			if pattern.MatchString(annotation.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (rs *Rules) suppressed(class, reference string) bool {
+	for _, s := range rs.Suppressions {
+		if s.class.MatchString(class) && (s.reference == nil || reference != "" && s.reference.MatchString(reference)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scanner) report(kind, reference, method string, pc, line int) {
+	if s.rules.suppressed(s.f.ClassName(), reference) {
+		return
+	}
+	for _, rule := range s.rules.Rules {
+		if rule.Kind == kind && rule.pattern.MatchString(reference) {
+			s.findings = append(s.findings, Finding{Rule: rule.ID(), Kind: kind, Message: rule.Message, Class: s.f.ClassName(),
+				Method: method, Reference: reference, Pc: pc, Line: line, SourceFile: s.sourceFile, SourcePath: s.sourcePath})
+		}
+	}
+}
+
+// 数组类检查其中的元素类型
+func (s *scanner) class(name, method string, pc, line int) {
+	if trimmed := strings.TrimLeft(name, "["); len(trimmed) < len(name) {
+		if !strings.HasPrefix(trimmed, "L") || !strings.HasSuffix(trimmed, ";") {
+			return
+		}
+		name = trimmed[1 : len(trimmed)-1]
+	}
+	if name != "" {
+		s.report(KIND_CLASS, name, method, pc, line)
+	}
+}
+
+// 字段和方法的引用同时检查所属的类
+func (s *scanner) member(index uint16, method string, pc, line int) {
+	pool := s.f.ConstantPool
+	var classIndex, natIndex uint16
+	kind, separator := KIND_METHOD, ""
+	switch c := bytecode.ConstantAt(pool, index).(type) {
+	case *bytecode.ConstantFieldref:
+		classIndex, natIndex, kind, separator = c.ClassIndex, c.NameAndTypeIndex, KIND_FIELD, ":"
+	case *bytecode.ConstantMethodref:
+		classIndex, natIndex = c.ClassIndex, c.NameAndTypeIndex
+	case *bytecode.ConstantInterfaceMethodref:
+		classIndex, natIndex = c.ClassIndex, c.NameAndTypeIndex
+	case *bytecode.ConstantMethodHandle:
+		s.member(c.ReferenceIndex, method, pc, line)
+		return
+	default:
+		return
+	}
+	owner := bytecode.ClassNameAt(pool, classIndex)
+	nat, ok := bytecode.ConstantAt(pool, natIndex).(*bytecode.ConstantNameAndType)
+	if owner == "" || !ok {
+		return
+	}
+	s.class(owner, method, pc, line)
+	s.report(kind, owner+"."+bytecode.Utf8At(pool, nat.NameIndex)+separator+bytecode.Utf8At(pool, nat.DescriptorIndex), method, pc, line)
+}
+
+func (s *scanner) code(method string, m *bytecode.MethodInfo) {
+	code := m.Code()
+	if code == nil {
+		return
+	}
+	instructions, err := code.Instructions()
+	if err != nil {
+		return
+	}
+	pool := s.f.ConstantPool
+	lines := m.LineRanges()
+	for i := range instructions {
+		ins := &instructions[i]
+		if !ins.HasConstantPoolIndex() {
+			continue
+		}
+		line := bytecode.LineAt(lines, ins.Pc)
+		switch item := bytecode.ConstantAt(pool, ins.Index).(type) {
+		case *bytecode.ConstantClass:
+			s.class(bytecode.Utf8At(pool, item.NameIndex), method, ins.Pc, line)
+		case *bytecode.ConstantInvokeDynamic:
+			// 方法引用例如System::exit是引导方法的MethodHandle参数
+			for _, arg := range s.bootstrapArguments(item.BootstrapMethodAttrIndex) {
+				s.member(arg, method, ins.Pc, line)
+			}
+		default:
+			s.member(ins.Index, method, ins.Pc, line)
+		}
+	}
+}
+
+func (s *scanner) bootstrapArguments(index uint16) []uint16 {
+	for _, attr := range s.f.Attributes {
+		if methods, ok := attr.(*bytecode.BootstrapMethods); ok && int(index) < len(methods.Methods) {
+			return methods.Methods[index].Arguments
+		}
+	}
+	return nil
+}
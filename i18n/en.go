@@ -0,0 +1,167 @@
+package i18n
+
+var en = map[string]string{
+	"version.jdk":           "JDK Version %s, %d.%d",
+	"version.jdk_lts":       "JDK Version %s (LTS), %d.%d",
+	"version.jdk_preview":   "JDK Version %s (preview), %d.%d",
+	"version.jdk_1_0":       "1.0.2 or 1.1",
+	"version.unknown":       "Unknown JDK Version",
+	"class.constant_count":  "constant number: %d",
+	"class.field_count":     "fields count: %d",
+	"class.method_count":    "methods count: %d",
+	"attribute_count":       "attributes count: %d",
+	"code.max_stack":        "max stack: %d, max locals: %d",
+	"line_number":           "start pc: %d, line number: %d",
+	"local_variable":        "start pc: %d, length: %d, name index: %d, descriptor index: %d, index: %d",
+	"local_variable_type":   "start pc: %d, length: %d, name index: %d, signature index: %d, index: %d",
+	"annotation.count":      "%d annotations",
+	"annotation.parameter":  "parameter %d:",
+	"record.component_name": "name: ",
+
+	"type_target.class_type_parameter":           "type parameter %d of class",
+	"type_target.method_type_parameter":          "type parameter %d of method",
+	"type_target.superclass":                     "superclass",
+	"type_target.interface":                      "interface %d",
+	"type_target.class_bound":                    "bound %d of class type parameter %d",
+	"type_target.method_bound":                   "bound %d of method type parameter %d",
+	"type_target.field":                          "field or record component",
+	"type_target.return":                         "method return type or constructed type",
+	"type_target.receiver":                       "method receiver",
+	"type_target.parameter":                      "method parameter %d",
+	"type_target.throws":                         "throws clause %d",
+	"type_target.local_variable":                 "local variable %s",
+	"type_target.resource_variable":              "resource variable %s",
+	"type_target.catch":                          "catch clause of exception table entry %d",
+	"type_target.instanceof":                     "instanceof at pc %d",
+	"type_target.new":                            "new at pc %d",
+	"type_target.constructor_reference":          "constructor reference at pc %d",
+	"type_target.method_reference":               "method reference at pc %d",
+	"type_target.cast":                           "type %d of cast at pc %d",
+	"type_target.constructor_invocation":         "type argument %d of constructor invocation at pc %d",
+	"type_target.method_invocation":              "type argument %d of method invocation at pc %d",
+	"type_target.constructor_reference_argument": "type argument %d of constructor reference at pc %d",
+	"type_target.method_reference_argument":      "type argument %d of method reference at pc %d",
+	"type_target.unknown":                        "unknown target type 0x%02x",
+	"type_target.variable_range":                 "(slot %d, pc %d-%d)",
+	"type_path.array":                            "array element",
+	"type_path.nested":                           "nested type",
+	"type_path.wildcard":                         "wildcard bound",
+	"type_path.type_argument":                    "type argument %d",
+	"type_path.join":                             "%s of %s",
+
+	"usage.main":         "usage: class-file-parser <command> [flags] <input>...",
+	"usage.inputs":       "An input is a class file, a jar, a directory, or - for stdin.",
+	"usage.commands":     "commands:",
+	"usage.command_help": "Run class-file-parser <command> -h to show the flags of a command.",
+	"usage.command":      "usage: class-file-parser %s [flags] %s",
+	"usage.flags":        "flags:",
+
+	"cmd.dump":      "print the full structure of class files",
+	"cmd.disasm":    "disassemble the bytecode of methods",
+	"cmd.constants": "list the constant pool",
+	"cmd.members":   "list fields and methods",
+	"cmd.verify":    "check the constant pool and the class file format, exit with 1 on errors",
+	"cmd.deps":      "list the dependencies of classes, packages or jars, like jdeps",
+	"cmd.diff":      "compare the classes of two inputs, exit with 1 if they differ",
+	"cmd.compat":    "check that the new version is binary compatible with the old one, exit code 1 on incompatible changes",
+	"cmd.repro":     "check that two builds of a jar are the same apart from entry order, timestamps, constant pool order, SourceFile paths and lambda numbering, exit code 1 on real differences",
+	"cmd.versions":  "report the class file versions of each jar and features that need a newer runtime than declared, exit code 1 on problems",
+	"cmd.search":    "search the constant pool, exit with 1 if nothing matches",
+	"cmd.stats":     "count classes, constants, methods and bytecode",
+	"cmd.schema":    "print the JSON Schema, or validate JSON output against it",
+	"cmd.stub":      "generate compilable Java source stubs",
+	"cmd.forbidden": "report uses of forbidden classes, fields and methods listed in a rules file, exit code 1 on findings",
+	"cmd.sniff":     "build an API signature of a JDK, or check that classes only use APIs in the signature, exit code 1 on missing references",
+	"cmd.index":     "build an annotation index, or query which classes and members carry an annotation",
+	"cmd.retrace":   "deobfuscate stack traces with a ProGuard or R8 mapping.txt, reading stdin when no file is given",
+	"cmd.remap":     "rename classes, fields and methods with a ProGuard, Tiny or SRG mapping and write the class files",
+
+	"flag.format":           "output format: text or json",
+	"flag.format_sarif":     "output format: text, json or sarif",
+	"flag.filter":           "only process classes whose name matches, e.g. java/util/* or com.example.**",
+	"flag.file":             "input file, may be repeated",
+	"flag.j":                "number of goroutines parsing in parallel",
+	"flag.summary":          "print files, classes, failures, bytes and time to stderr",
+	"flag.lang":             "language of the text output: en or zh, defaults to LANG",
+	"flag.warnings":         "also print warnings and infos",
+	"flag.regexp":           "the pattern is a regular expression",
+	"flag.tag":              "only search constants of these comma separated types, e.g. Class,Methodref",
+	"flag.d":                "write the stubs as .java files into this directory",
+	"flag.index_output":     "write the index to this file",
+	"flag.index_input":      "read the index from this file",
+	"flag.cp":               "classpath of jars and directories, separated by the path list separator",
+	"flag.meta":             "also match annotations whose declaration carries the annotation, transitively",
+	"flag.level":            "dependency level: class, package or archive (jar, directory or JDK module)",
+	"flag.exclude_jdk":      "leave out dependencies on the JDK",
+	"flag.internals":        "only list references to JDK internal APIs, exit with 1 if there are any",
+	"flag.dot":              "print the dependencies as a Graphviz DOT graph",
+	"flag.signature_output": "write the signature file to this file",
+	"flag.signature":        "read the signature file",
+	"flag.release":          "read the API of this release from ct.sym, e.g. 8 or 11",
+	"flag.rules":            "the rules file of forbidden APIs",
+	"flag.max_release":      "fail on classes that need a newer release than this, e.g. 8 or 11",
+	"flag.mapping":          "the ProGuard or R8 mapping.txt",
+	"flag.retrace_cp":       "obfuscated jars and directories, used for ambiguous lines and SMAP line translation",
+	"flag.remap_mapping":    "the ProGuard mapping.txt, Tiny v1/v2, SRG, XSRG or TSRG mapping",
+	"flag.from":             "namespace of the input classes, defaults to the first one of the mapping",
+	"flag.to":               "namespace to rename to, defaults to the last one of the mapping",
+	"flag.reverse":          "swap -from and -to, e.g. apply a ProGuard mapping backwards",
+	"flag.remap_cp":         "jars and directories of super classes, used to rename inherited members",
+	"flag.remap_d":          "write the renamed class files into this directory",
+
+	"error.unknown_command": "unknown command %s",
+	"error.unknown_format":  "unknown format %s",
+	"error.no_input":        "no input",
+	"error.encode_json":     "encode json error %s",
+	"error.read_json":       "read json file error %s",
+	"error.missing_pattern": "missing pattern or input",
+	"error.invalid_pattern": "invalid pattern %s",
+	"error.diff_inputs":     "diff needs exactly two inputs",
+	"error.compat_inputs":   "compat needs exactly two inputs: the old and the new version",
+	"error.repro_inputs":    "repro needs exactly two jars",
+	"error.stub_outer":      "%s: outer class %s not found, skip the member class",
+	"error.write_file":      "write file error %s",
+	"error.read_index":      "read index error %s",
+	"error.unknown_level":   "unknown level %s",
+	"error.missing_rules":   "missing -rules",
+	"error.read_rules":      "read rules error %s",
+	"error.read_signature":  "read signature error %s",
+	"error.unknown_release": "unknown release %s",
+	"error.missing_mapping": "missing -mapping or -cp",
+	"error.read_mapping":    "read mapping error %s",
+	"error.missing_remap":   "missing -mapping or -d",
+
+	"summary":                 "files: %d, classes: %d, failures: %d, bytes: %d, time: %s",
+	"dump.size":               "%s: %d bytes",
+	"disasm.stack":            "stack=%d, locals=%d",
+	"disasm.exception_table":  "Exception table:",
+	"disasm.exception_header": "from    to  target type",
+
+	"deps.internal":    "JDK internal API (%s)",
+	"deps.replacement": "use %s instead",
+
+	"versions.archive": "%s: %d classes, highest version: %s",
+	"versions.preview": "%s: uses preview features",
+	"versions.feature": "%s: %s at %s requires class file version %s, but the class file is %s",
+	"versions.release": "%s: requires class file version %s, newer than release %s",
+
+	"forbidden.finding": "%s %s is forbidden by %s",
+
+	"sniff.built":   "%s: %d classes",
+	"sniff.missing": "%s: %s %s is not in the signature (%s)",
+
+	"index.built":     "%s: %d classes, %d annotation types",
+	"index.not_found": "%s: not found",
+
+	"repro.archive": "archive: %s",
+	"repro.summary": "%d entries: %d identical, %d equal after normalization, %d different",
+
+	"stats.classes":      "classes: %d",
+	"stats.bytes":        "bytes: %d",
+	"stats.constants":    "constants: %d",
+	"stats.fields":       "fields: %d",
+	"stats.methods":      "methods: %d",
+	"stats.code_bytes":   "code bytes: %d",
+	"stats.instructions": "instructions: %d",
+	"stats.versions":     "versions:",
+}
@@ -0,0 +1,78 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const DEFAULT_LANGUAGE = "en"
+
+// 每种语言一个消息目录, 键是消息的ID, 值是fmt格式字符串
+var catalogs = map[string]map[string]string{
+	"en": en,
+	"zh": zh,
+}
+
+var current = DEFAULT_LANGUAGE
+
+// 将zh_CN.UTF-8、zh-Hans、en_US等形式规范化为目录的名称
+func normalize(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+func Languages() []string {
+	result := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		result = append(result, lang)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// 设置输出的语言, 需要在开始输出之前调用
+func SetLanguage(lang string) error {
+	name := normalize(lang)
+	if _, ok := catalogs[name]; !ok {
+		return fmt.Errorf("unsupported language %s, available: %s", lang, strings.Join(Languages(), ", "))
+	}
+	current = name
+	return nil
+}
+
+func Language() string {
+	return current
+}
+
+// 按照LC_ALL、LC_MESSAGES、LANG的优先级选择语言, 不支持的语言使用英语
+func FromEnv() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			if _, ok := catalogs[normalize(value)]; ok {
+				return normalize(value)
+			}
+			return DEFAULT_LANGUAGE
+		}
+	}
+	return DEFAULT_LANGUAGE
+}
+
+// 查找当前语言的消息并格式化, 缺失时依次使用英语和消息ID
+func T(id string, args ...interface{}) string {
+	format, ok := catalogs[current][id]
+	if !ok {
+		format, ok = en[id]
+	}
+	if !ok {
+		format = id
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
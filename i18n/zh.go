@@ -0,0 +1,167 @@
+package i18n
+
+var zh = map[string]string{
+	"version.jdk":           "JDK版本 %s, %d.%d",
+	"version.jdk_lts":       "JDK版本 %s (LTS), %d.%d",
+	"version.jdk_preview":   "JDK版本 %s (预览), %d.%d",
+	"version.jdk_1_0":       "1.0.2或1.1",
+	"version.unknown":       "未知的JDK版本",
+	"class.constant_count":  "常量个数: %d",
+	"class.field_count":     "字段个数: %d",
+	"class.method_count":    "方法个数: %d",
+	"attribute_count":       "属性个数: %d",
+	"code.max_stack":        "操作数栈最大深度: %d, 局部变量表大小: %d",
+	"line_number":           "起始pc: %d, 行号: %d",
+	"local_variable":        "起始pc: %d, 长度: %d, 名称索引: %d, 描述符索引: %d, 槽位: %d",
+	"local_variable_type":   "起始pc: %d, 长度: %d, 名称索引: %d, 签名索引: %d, 槽位: %d",
+	"annotation.count":      "%d个注解",
+	"annotation.parameter":  "参数%d:",
+	"record.component_name": "名称: ",
+
+	"type_target.class_type_parameter":           "类的类型参数%d",
+	"type_target.method_type_parameter":          "方法的类型参数%d",
+	"type_target.superclass":                     "父类",
+	"type_target.interface":                      "接口%d",
+	"type_target.class_bound":                    "类的类型参数%[2]d的边界%[1]d",
+	"type_target.method_bound":                   "方法的类型参数%[2]d的边界%[1]d",
+	"type_target.field":                          "字段或者记录组件",
+	"type_target.return":                         "方法返回值或者新建对象的类型",
+	"type_target.receiver":                       "方法的接收者",
+	"type_target.parameter":                      "方法参数%d",
+	"type_target.throws":                         "throws子句%d",
+	"type_target.local_variable":                 "局部变量%s",
+	"type_target.resource_variable":              "资源变量%s",
+	"type_target.catch":                          "异常表第%d项的catch子句",
+	"type_target.instanceof":                     "pc %d处的instanceof",
+	"type_target.new":                            "pc %d处的new",
+	"type_target.constructor_reference":          "pc %d处的构造方法引用",
+	"type_target.method_reference":               "pc %d处的方法引用",
+	"type_target.cast":                           "pc %[2]d处类型转换的类型%[1]d",
+	"type_target.constructor_invocation":         "pc %[2]d处构造方法调用的类型参数%[1]d",
+	"type_target.method_invocation":              "pc %[2]d处方法调用的类型参数%[1]d",
+	"type_target.constructor_reference_argument": "pc %[2]d处构造方法引用的类型参数%[1]d",
+	"type_target.method_reference_argument":      "pc %[2]d处方法引用的类型参数%[1]d",
+	"type_target.unknown":                        "未知的target类型0x%02x",
+	"type_target.variable_range":                 "(槽位%d, pc %d-%d)",
+	"type_path.array":                            "数组元素",
+	"type_path.nested":                           "嵌套类型",
+	"type_path.wildcard":                         "通配符边界",
+	"type_path.type_argument":                    "类型参数%d",
+	"type_path.join":                             "%[2]s的%[1]s",
+
+	"usage.main":         "用法: class-file-parser <命令> [参数] <输入>...",
+	"usage.inputs":       "输入可以是类文件、jar、目录, -表示标准输入",
+	"usage.commands":     "命令:",
+	"usage.command_help": "使用 class-file-parser <命令> -h 查看命令的参数",
+	"usage.command":      "用法: class-file-parser %s [参数] %s",
+	"usage.flags":        "参数:",
+
+	"cmd.dump":      "输出类文件的完整结构",
+	"cmd.disasm":    "反汇编方法的字节码",
+	"cmd.constants": "列出常量池",
+	"cmd.members":   "列出字段和方法",
+	"cmd.verify":    "检查常量池和类文件格式, 存在错误时退出码为1",
+	"cmd.deps":      "列出类、包或者jar之间的依赖, 类似jdeps",
+	"cmd.diff":      "比较两组输入中的类, 存在差异时退出码为1",
+	"cmd.compat":    "检查新版本与旧版本是否二进制兼容, 存在不兼容的变化时退出码为1",
+	"cmd.repro":     "检查两次构建的jar除条目顺序、时间戳、常量池顺序、SourceFile的目录和lambda编号之外是否相同, 存在真正的差异时退出码为1",
+	"cmd.versions":  "统计每个jar的类文件版本, 以及需要比声明的版本更新的运行时的特性, 存在问题时退出码为1",
+	"cmd.search":    "在常量池中查找, 没有匹配时退出码为1",
+	"cmd.stats":     "统计类、常量、方法和字节码的数量",
+	"cmd.schema":    "输出JSON Schema, 或者校验JSON输出",
+	"cmd.stub":      "生成可以编译的Java源码骨架",
+	"cmd.forbidden": "按规则文件检查禁止使用的类、字段和方法, 存在时退出码为1",
+	"cmd.sniff":     "生成JDK的API签名, 或者检查类使用的API是否都在签名中, 存在签名中没有的引用时退出码为1",
+	"cmd.index":     "建立注解索引, 或者查询哪些类和成员使用了注解",
+	"cmd.retrace":   "按ProGuard或者R8的mapping.txt还原混淆的异常栈, 没有指定文件时从stdin读取",
+	"cmd.remap":     "按ProGuard、Tiny或者SRG映射给类、字段和方法改名, 写出类文件",
+
+	"flag.format":           "输出格式: text或json",
+	"flag.format_sarif":     "输出格式: text、json或sarif",
+	"flag.filter":           "只处理类名匹配的类, 例如java/util/*或者com.example.**",
+	"flag.file":             "输入文件, 可以重复使用",
+	"flag.j":                "并发解析的goroutine数量",
+	"flag.summary":          "在stderr输出文件数、类数、失败数、字节数和耗时",
+	"flag.lang":             "文本输出的语言: en或zh, 默认根据LANG选择",
+	"flag.warnings":         "同时输出warning和info级别的问题",
+	"flag.regexp":           "pattern是正则表达式",
+	"flag.tag":              "只查找指定类型的常量, 多个类型用逗号分隔, 例如Class,Methodref",
+	"flag.d":                "将骨架作为.java文件写入该目录",
+	"flag.index_output":     "索引写入的文件",
+	"flag.index_input":      "读取索引的文件",
+	"flag.cp":               "类路径, 由jar和目录组成, 使用系统的路径分隔符分隔",
+	"flag.meta":             "同时匹配声明上带有该注解的注解, 可以传递",
+	"flag.level":            "依赖的粒度: class、package或者archive (jar、目录或者JDK模块)",
+	"flag.exclude_jdk":      "不输出对JDK的依赖",
+	"flag.internals":        "只列出对JDK内部API的引用, 存在时退出码为1",
+	"flag.dot":              "以Graphviz DOT格式输出依赖图",
+	"flag.signature_output": "签名文件的输出路径",
+	"flag.signature":        "读取的签名文件",
+	"flag.release":          "从ct.sym中读取这个版本的API, 例如8或者11",
+	"flag.rules":            "禁止使用的API的规则文件",
+	"flag.max_release":      "类需要的版本超过此版本时失败, 例如8或者11",
+	"flag.mapping":          "ProGuard或者R8的mapping.txt",
+	"flag.retrace_cp":       "混淆后的jar和目录, 用于筛选有歧义的行号和按SMAP换算行号",
+	"flag.remap_mapping":    "ProGuard的mapping.txt、Tiny v1/v2、SRG、XSRG或者TSRG映射",
+	"flag.from":             "输入的类所在的命名空间, 默认是映射中的第一个",
+	"flag.to":               "改名后的命名空间, 默认是映射中的最后一个",
+	"flag.reverse":          "交换-from和-to, 例如反向应用ProGuard的映射",
+	"flag.remap_cp":         "父类所在的jar和目录, 用于给继承的成员改名",
+	"flag.remap_d":          "将改名后的类文件写入该目录",
+
+	"error.unknown_command": "未知的命令 %s",
+	"error.unknown_format":  "未知的输出格式 %s",
+	"error.no_input":        "没有输入",
+	"error.encode_json":     "JSON编码错误 %s",
+	"error.read_json":       "读取JSON文件错误 %s",
+	"error.missing_pattern": "缺少pattern或者输入",
+	"error.invalid_pattern": "pattern不合法 %s",
+	"error.diff_inputs":     "diff需要两个输入",
+	"error.compat_inputs":   "compat需要两个输入: 旧版本和新版本",
+	"error.repro_inputs":    "repro需要两个jar",
+	"error.stub_outer":      "%s: 找不到外部类%s, 跳过该成员类",
+	"error.write_file":      "写文件错误 %s",
+	"error.read_index":      "读取索引错误 %s",
+	"error.unknown_level":   "未知的粒度 %s",
+	"error.missing_rules":   "缺少-rules参数",
+	"error.read_rules":      "读取规则文件出错 %s",
+	"error.read_signature":  "读取签名文件出错 %s",
+	"error.unknown_release": "未知的JDK版本 %s",
+	"error.missing_mapping": "缺少-mapping或者-cp参数",
+	"error.read_mapping":    "读取映射文件出错 %s",
+	"error.missing_remap":   "缺少-mapping或者-d参数",
+
+	"summary":                 "文件: %d, 类: %d, 失败: %d, 字节: %d, 耗时: %s",
+	"dump.size":               "%s: %d字节",
+	"disasm.stack":            "栈深度=%d, 局部变量=%d",
+	"disasm.exception_table":  "异常表:",
+	"disasm.exception_header": "from    to  target type",
+
+	"deps.internal":    "JDK内部API (%s)",
+	"deps.replacement": "建议使用%s",
+
+	"versions.archive": "%s: %d个类, 最高版本: %s",
+	"versions.preview": "%s: 使用了预览特性",
+	"versions.feature": "%s: %s (%s) 需要类文件版本%s, 但类文件的版本为%s",
+	"versions.release": "%s: 需要类文件版本%s, 超过了JDK %s",
+
+	"forbidden.finding": "%s %s 被规则%s禁止",
+
+	"sniff.built":   "%s: %d个类",
+	"sniff.missing": "%s: 签名中没有%s %s (%s)",
+
+	"index.built":     "%s: %d个类, %d种注解",
+	"index.not_found": "%s: 没有找到",
+
+	"repro.archive": "jar: %s",
+	"repro.summary": "%d个条目: %d个相同, %d个规范化后相同, %d个不同",
+
+	"stats.classes":      "类: %d",
+	"stats.bytes":        "字节: %d",
+	"stats.constants":    "常量: %d",
+	"stats.fields":       "字段: %d",
+	"stats.methods":      "方法: %d",
+	"stats.code_bytes":   "字节码字节数: %d",
+	"stats.instructions": "指令: %d",
+	"stats.versions":     "版本:",
+}
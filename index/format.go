@@ -0,0 +1,204 @@
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"class-file-parser/bytecode"
+)
+
+// 索引文件的格式, 整数都是uvarint:
+//
+//	magic "CFPIDX" version u1
+//	strings count {length bytes}     字符串表, 0号为空字符串, 后面都使用字符串表中的序号
+//	classes count {string}
+//	annotations count {type targets count {kind u1 retention u1 class name descriptor position}}
+const (
+	MAGIC   = "CFPIDX"
+	VERSION = 1
+)
+
+// 单个计数的上限, 避免损坏的文件导致分配过多的内存
+const MAX_COUNT = 1 << 24
+
+type stringTable struct {
+	strings []string
+	indexes map[string]uint64
+}
+
+func (t *stringTable) add(s string) uint64 {
+	if index, ok := t.indexes[s]; ok {
+		return index
+	}
+	index := uint64(len(t.strings))
+	t.strings = append(t.strings, s)
+	t.indexes[s] = index
+	return index
+}
+
+type writer struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *writer) uvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	w.w.Write(w.buf[:n])
+}
+
+// 注解类型按名称排序, 相同的索引写出相同的文件
+func (x *Index) Write(out io.Writer) error {
+	table := &stringTable{strings: []string{""}, indexes: map[string]uint64{"": 0}}
+	annotations := x.Annotations()
+	for _, class := range x.classes {
+		table.add(class)
+	}
+	for _, name := range annotations {
+		table.add(name)
+		for _, target := range x.targets[name] {
+			table.add(target.Class)
+			table.add(target.Name)
+			table.add(target.Descriptor)
+		}
+	}
+
+	w := &writer{w: bufio.NewWriter(out)}
+	w.w.WriteString(MAGIC)
+	w.w.WriteByte(VERSION)
+	w.uvarint(uint64(len(table.strings)))
+	for _, s := range table.strings {
+		w.uvarint(uint64(len(s)))
+		w.w.WriteString(s)
+	}
+	w.uvarint(uint64(len(x.classes)))
+	for _, class := range x.classes {
+		w.uvarint(table.indexes[class])
+	}
+	w.uvarint(uint64(len(annotations)))
+	for _, name := range annotations {
+		targets := x.targets[name]
+		w.uvarint(table.indexes[name])
+		w.uvarint(uint64(len(targets)))
+		for _, target := range targets {
+			w.w.WriteByte(byte(target.Kind))
+			w.w.WriteByte(byte(target.Retention))
+			w.uvarint(table.indexes[target.Class])
+			w.uvarint(table.indexes[target.Name])
+			w.uvarint(table.indexes[target.Descriptor])
+			w.uvarint(uint64(target.Position))
+		}
+	}
+	return w.w.Flush()
+}
+
+type reader struct {
+	r       *bufio.Reader
+	strings []string
+	err     error
+}
+
+// 出错后后续的读取都返回零值, 只保留第一个错误
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		r.err = unexpectedEOF(err)
+	}
+	return v
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	b, err := r.r.ReadByte()
+	if err != nil {
+		r.err = unexpectedEOF(err)
+	}
+	return b
+}
+
+func (r *reader) count() int {
+	n := r.uvarint()
+	if n > MAX_COUNT && r.err == nil {
+		r.err = fmt.Errorf("count %d exceeds %d", n, MAX_COUNT)
+	}
+	if r.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) string() string {
+	i := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if i >= uint64(len(r.strings)) {
+		r.err = fmt.Errorf("string index %d out of range %d", i, len(r.strings))
+		return ""
+	}
+	return r.strings[i]
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func Read(in io.Reader) (*Index, error) {
+	r := &reader{r: bufio.NewReader(in)}
+	header := make([]byte, len(MAGIC)+1)
+	if _, err := io.ReadFull(r.r, header); err != nil || string(header[:len(MAGIC)]) != MAGIC {
+		return nil, fmt.Errorf("not an index file")
+	}
+	if header[len(MAGIC)] != VERSION {
+		return nil, fmt.Errorf("unsupported index version %d", header[len(MAGIC)])
+	}
+
+	r.strings = make([]string, r.count())
+	for i := range r.strings {
+		data := make([]byte, r.count())
+		if r.err == nil {
+			_, r.err = io.ReadFull(r.r, data)
+			r.err = unexpectedEOF(r.err)
+		}
+		r.strings[i] = string(data)
+	}
+	x := New()
+	classes := r.count()
+	for i := 0; i < classes && r.err == nil; i++ {
+		class := r.string()
+		x.indexed[class] = true
+		x.classes = append(x.classes, class)
+	}
+	annotations := r.count()
+	for i := 0; i < annotations && r.err == nil; i++ {
+		name := r.string()
+		count := r.count()
+		targets := make([]Target, 0, count)
+		for j := 0; j < count && r.err == nil; j++ {
+			targets = append(targets, Target{
+				Annotation: name,
+				Kind:       Kind(r.byte()),
+				Retention:  bytecode.Retention(r.byte()),
+				Class:      r.string(),
+				Name:       r.string(),
+				Descriptor: r.string(),
+				Position:   int(r.uvarint()),
+			})
+		}
+		x.targets[name] = targets
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return x, nil
+}
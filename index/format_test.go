@@ -0,0 +1,109 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"class-file-parser/bytecode"
+)
+
+// 索引bytecode/testdata中的类, 其中有类、字段、方法、参数上的注解和类型注解
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join("..", "bytecode", "testdata", "*.class"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("no classes in testdata: %v", err)
+	}
+	x := New()
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := bytecode.ParseClassFile(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		x.Add(f)
+	}
+	if len(x.Annotations()) == 0 {
+		t.Fatal("no annotations in testdata")
+	}
+	return x
+}
+
+func indexBytes(t *testing.T, x *Index) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := x.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteRead(t *testing.T) {
+	for _, x := range []*Index{New(), testIndex(t)} {
+		data := indexBytes(t, x)
+		y, err := Read(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(x.Classes(), y.Classes()) || !reflect.DeepEqual(x.Annotations(), y.Annotations()) {
+			t.Errorf("classes or annotations differ: %v %v", y.Classes(), y.Annotations())
+		}
+		for _, name := range x.Annotations() {
+			if !reflect.DeepEqual(x.AnnotatedWith(name), y.AnnotatedWith(name)) {
+				t.Errorf("%s: got %v, want %v", name, y.AnnotatedWith(name), x.AnnotatedWith(name))
+			}
+		}
+		if !bytes.Equal(indexBytes(t, y), data) {
+			t.Error("index written after reading differs")
+		}
+	}
+}
+
+// 截断在任何位置都要返回错误, 不能panic
+func TestReadTruncated(t *testing.T) {
+	data := indexBytes(t, testIndex(t))
+	for n := 0; n < len(data); n++ {
+		_, err := Read(bytes.NewReader(data[:n]))
+		if err == nil {
+			t.Fatalf("no error for %d of %d bytes", n, len(data))
+		}
+		if n > len(MAGIC) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%d bytes: %v", n, err)
+		}
+	}
+}
+
+func TestReadCorrupt(t *testing.T) {
+	header := MAGIC + string([]byte{VERSION})
+	uvarint := func(values ...uint64) string {
+		result := ""
+		for _, v := range values {
+			buf := make([]byte, binary.MaxVarintLen64)
+			result += string(buf[:binary.PutUvarint(buf, v)])
+		}
+		return result
+	}
+	tests := map[string]string{
+		"magic":        "CFPIDY" + string([]byte{VERSION}),
+		"version":      MAGIC + string([]byte{VERSION + 1}),
+		"count":        header + uvarint(MAX_COUNT+1),
+		"string size":  header + uvarint(1, MAX_COUNT+1),
+		"string index": header + uvarint(1, 0, 1, 1, 0),
+		"target class": header + uvarint(2, 0, 1) + "A" + uvarint(0, 1, 1, 1, 0, 0, 9),
+	}
+	for name, input := range tests {
+		if _, err := Read(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expect an error", name)
+		}
+	}
+}
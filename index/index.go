@@ -0,0 +1,198 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+
+	"class-file-parser/bytecode"
+)
+
+// 注解所在的位置
+type Kind uint8
+
+const (
+	KIND_CLASS Kind = iota
+	KIND_FIELD
+	KIND_METHOD
+	KIND_PARAMETER
+	KIND_TYPE
+)
+
+var kindNames = []string{"class", "field", "method", "parameter", "type"}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", k)
+}
+
+// 被注解的目标, 类名和注解类型都是内部形式; 类上的注解Name和Descriptor为空,
+// 参数注解的Position是参数在描述符中的序号, 类型注解的Position是target_type
+type Target struct {
+	Annotation string
+	Kind       Kind
+	Class      string
+	Name       string
+	Descriptor string
+	Position   int
+	Retention  bytecode.Retention
+}
+
+// 例如com/acme/Box.of(J[Ljava/lang/String;)V#1, 字段为com/acme/Box.NAME:Ljava/lang/String;
+func (t Target) String() string {
+	result := t.Class
+	switch {
+	case t.Name == "":
+	case len(t.Descriptor) > 0 && t.Descriptor[0] == '(':
+		result += "." + t.Name + t.Descriptor
+	default:
+		result += "." + t.Name + ":" + t.Descriptor
+	}
+	switch t.Kind {
+	case KIND_PARAMETER:
+		result += fmt.Sprintf("#%d", t.Position)
+	case KIND_TYPE:
+		result += fmt.Sprintf(" [0x%02x]", t.Position)
+	}
+	return result
+}
+
+// 注解类型到被注解目标的索引, 同名的类只索引第一次出现的, 与类路径的查找顺序一致
+type Index struct {
+	classes []string
+	indexed map[string]bool
+	targets map[string][]Target
+}
+
+func New() *Index {
+	return &Index{indexed: make(map[string]bool), targets: make(map[string][]Target)}
+}
+
+// 索引类、字段、方法、参数上的注解以及类型注解, 类已经索引过时返回false
+func (x *Index) Add(f *bytecode.ClassFile) bool {
+	name := f.ClassName()
+	if x.indexed[name] {
+		return false
+	}
+	x.indexed[name] = true
+	x.classes = append(x.classes, name)
+	pool := f.ConstantPool
+	class := Target{Kind: KIND_CLASS, Class: name}
+	x.annotations(pool, f.Attributes, class)
+	for i := range f.Fields {
+		field := &f.Fields[i]
+		x.annotations(pool, field.Attributes, Target{Kind: KIND_FIELD, Class: name,
+			Name: field.Name(pool), Descriptor: field.Descriptor(pool)})
+	}
+	for i := range f.Methods {
+		method := &f.Methods[i]
+		target := Target{Kind: KIND_METHOD, Class: name, Name: method.Name(pool), Descriptor: method.Descriptor(pool)}
+		x.annotations(pool, method.Attributes, target)
+		x.parameterAnnotations(pool, method.Attributes, target)
+		if code := method.Code(); code != nil {
+			x.annotations(pool, code.Attributes, target)
+		}
+	}
+	return true
+}
+
+func (x *Index) add(annotation bytecode.AnnotationInfo, target Target) {
+	target.Annotation = annotation.Type
+	target.Retention = annotation.Retention
+	x.targets[annotation.Type] = append(x.targets[annotation.Type], target)
+}
+
+// Code属性中的类型注解属于所在的方法
+func (x *Index) annotations(pool []bytecode.ConstantPoolInfo, attrs []bytecode.AttributeInfo, target Target) {
+	for _, annotation := range bytecode.AnnotationsOf(attrs, pool) {
+		x.add(annotation, target)
+	}
+	for _, attr := range attrs {
+		var annotations *bytecode.RuntimeVisibleTypeAnnotations
+		switch a := attr.(type) {
+		case *bytecode.RuntimeVisibleTypeAnnotations:
+			annotations = a
+		case *bytecode.RuntimeInvisibleTypeAnnotations:
+			annotations = &a.RuntimeVisibleTypeAnnotations
+		default:
+			continue
+		}
+		typeTarget := target
+		typeTarget.Kind = KIND_TYPE
+		for i := range annotations.Annotations {
+			annotation := &annotations.Annotations[i]
+			typeTarget.Position = int(annotation.TargetType)
+			x.add(annotation.Resolve(pool, annotations.Retention()), typeTarget)
+		}
+	}
+}
+
+// 参数注解的个数可能少于描述符中的参数, 按末尾对齐
+func (x *Index) parameterAnnotations(pool []bytecode.ConstantPoolInfo, attrs []bytecode.AttributeInfo, method Target) {
+	count := -1
+	if params, _, err := bytecode.ParseMethodDescriptor(method.Descriptor); err == nil {
+		count = len(params)
+	}
+	for _, attr := range attrs {
+		var parameters [][]bytecode.AnnotationInfo
+		switch a := attr.(type) {
+		case *bytecode.RuntimeVisibleParameterAnnotations:
+			parameters = a.Resolve(pool)
+		case *bytecode.RuntimeInvisibleParameterAnnotations:
+			parameters = a.Resolve(pool)
+		}
+		offset := 0
+		if count >= len(parameters) {
+			offset = count - len(parameters)
+		}
+		target := method
+		target.Kind = KIND_PARAMETER
+		for i, annotations := range parameters {
+			target.Position = offset + i
+			for _, annotation := range annotations {
+				x.add(annotation, target)
+			}
+		}
+	}
+}
+
+// 已索引的类, 按添加的顺序
+func (x *Index) Classes() []string {
+	return x.classes
+}
+
+// 出现过的注解类型, 按名称排序
+func (x *Index) Annotations() []string {
+	result := make([]string, 0, len(x.targets))
+	for name := range x.targets {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// 直接使用了annotation的目标, annotation是内部形式的类名, 例如javax/inject/Singleton
+func (x *Index) AnnotatedWith(annotation string) []Target {
+	return x.targets[annotation]
+}
+
+// 使用了带有元注解meta的注解的目标, 元注解可以传递, 例如@Singleton上有@Scope, 自定义的注解上又有@Singleton.
+// 注解类型本身也需要被索引, 否则无法知道它带有哪些元注解
+func (x *Index) MetaAnnotatedWith(meta string) []Target {
+	visited := map[string]bool{meta: true}
+	queue := []string{meta}
+	result := make([]Target, 0)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, target := range x.targets[name] {
+			if target.Kind == KIND_CLASS && !visited[target.Class] {
+				visited[target.Class] = true
+				queue = append(queue, target.Class)
+				result = append(result, x.targets[target.Class]...)
+			}
+		}
+	}
+	return result
+}
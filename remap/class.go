@@ -0,0 +1,410 @@
+package remap
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"class-file-parser/bytecode"
+)
+
+const LAMBDA_METAFACTORY = "java/lang/invoke/LambdaMetafactory"
+
+// 改写类文件中的类名、成员名、描述符和签名. 常量池中原有的常量不修改, 因为同一个Utf8可能同时是
+// 类名、成员名和字符串的值; 新的名称追加到常量池, 最后删除不再使用的常量. 字节码本身不需要改写.
+// 不认识的属性原样保留, 其中可能有常量池索引, 所以这时只删除追加后又不用的常量, 原有常量的索引不变.
+// 常量池超过65535项时返回错误, 这时类文件已经改了一部分, 不能再写出
+func (r *Remapper) Remap(f *bytecode.ClassFile) error {
+	c := &classRemapper{r: r, f: f, pool: append([]bytecode.ConstantPoolInfo(nil), f.ConstantPool...),
+		utf8s: make(map[string]uint16), nats: make(map[[2]uint16]uint16)}
+	for i, item := range f.ConstantPool {
+		switch v := item.(type) {
+		case *bytecode.ConstantUtf8:
+			if _, ok := c.utf8s[string(v.Value)]; !ok {
+				c.utf8s[string(v.Value)] = uint16(i)
+			}
+		case *bytecode.ConstantNameAndType:
+			if _, ok := c.nats[[2]uint16{v.NameIndex, v.DescriptorIndex}]; !ok {
+				c.nats[[2]uint16{v.NameIndex, v.DescriptorIndex}] = uint16(i)
+			}
+		}
+	}
+	owner := f.ClassName()
+	c.constants()
+	for i := range f.Fields {
+		field := &f.Fields[i]
+		name, desc := c.str(field.NameIndex), c.str(field.DescriptorIndex)
+		c.rename(&field.NameIndex, r.FieldName(owner, name, desc))
+		c.rename(&field.DescriptorIndex, r.Descriptor(desc))
+		c.attributes(field.Attributes, nil)
+	}
+	for i := range f.Methods {
+		method := &f.Methods[i]
+		name, desc := c.str(method.NameIndex), c.str(method.DescriptorIndex)
+		static := method.AccessFlags&bytecode.METHOD_ACC_STATIC != 0
+		if static || method.AccessFlags&bytecode.METHOD_ACC_PRIVATE != 0 {
+			c.rename(&method.NameIndex, r.declaredMethodName(owner, name, desc))
+		} else {
+			c.rename(&method.NameIndex, r.MethodName(owner, name, desc))
+		}
+		c.rename(&method.DescriptorIndex, r.Descriptor(desc))
+		c.attributes(method.Attributes, &methodContext{params: r.params[owner+"."+name+desc], slots: parameterSlots(desc, static)})
+	}
+	c.attributes(f.Attributes, nil)
+	if c.err != nil {
+		return c.err
+	}
+	var err error
+	if f.HasUnknownAttributes() {
+		_, err = f.RemoveUnusedConstantsFrom(len(c.pool))
+	} else {
+		_, err = f.RemoveUnusedConstants()
+	}
+	return err
+}
+
+type classRemapper struct {
+	r *Remapper
+	f *bytecode.ClassFile
+	// 改写前的常量池, 名称都从这里读取
+	pool  []bytecode.ConstantPoolInfo
+	utf8s map[string]uint16
+	nats  map[[2]uint16]uint16
+	// 追加常量时常量池溢出
+	err error
+}
+
+// 方法的参数名称, 键为参数在局部变量表中的位置
+type methodContext struct {
+	params map[int]string
+	slots  []int
+}
+
+func parameterSlots(desc string, static bool) []int {
+	params, _, err := bytecode.ParseMethodDescriptor(desc)
+	if err != nil {
+		return nil
+	}
+	slots := make([]int, 0, len(params))
+	slot := 1
+	if static {
+		slot = 0
+	}
+	for _, p := range params {
+		slots = append(slots, slot)
+		if p == "J" || p == "D" {
+			slot += 2
+		} else {
+			slot++
+		}
+	}
+	return slots
+}
+
+// 索引非法时返回nil
+func (c *classRemapper) constant(index uint16) bytecode.ConstantPoolInfo {
+	if int(index) < len(c.pool) {
+		return c.pool[index]
+	}
+	return nil
+}
+
+func (c *classRemapper) str(index uint16) string {
+	if utf8, ok := c.constant(index).(*bytecode.ConstantUtf8); ok {
+		return string(utf8.Value)
+	}
+	return ""
+}
+
+func (c *classRemapper) className(index uint16) string {
+	if class, ok := c.constant(index).(*bytecode.ConstantClass); ok {
+		return c.str(class.NameIndex)
+	}
+	return ""
+}
+
+func (c *classRemapper) nameAndTypeOf(index uint16) (string, string) {
+	if nat, ok := c.constant(index).(*bytecode.ConstantNameAndType); ok {
+		return c.str(nat.NameIndex), c.str(nat.DescriptorIndex)
+	}
+	return "", ""
+}
+
+// 常量池满了之后不再追加, 返回0
+func (c *classRemapper) add(item bytecode.ConstantPoolInfo) uint16 {
+	if len(c.f.ConstantPool) >= math.MaxUint16 {
+		if c.err == nil {
+			c.err = fmt.Errorf("constant pool exceeds %d entries", math.MaxUint16)
+		}
+		return 0
+	}
+	c.f.ConstantPool = append(c.f.ConstantPool, item)
+	c.f.ConstantPoolCount = uint16(len(c.f.ConstantPool))
+	return uint16(len(c.f.ConstantPool) - 1)
+}
+
+func (c *classRemapper) utf8(value string) uint16 {
+	if index, ok := c.utf8s[value]; ok {
+		return index
+	}
+	index := c.add(&bytecode.ConstantUtf8{Tag: bytecode.CONSTANT_Utf8, Length: uint16(len(value)), Value: []byte(value)})
+	c.utf8s[value] = index
+	return index
+}
+
+func (c *classRemapper) nameAndType(name, desc string) uint16 {
+	key := [2]uint16{c.utf8(name), c.utf8(desc)}
+	if index, ok := c.nats[key]; ok {
+		return index
+	}
+	index := c.add(&bytecode.ConstantNameAndType{Tag: bytecode.CONSTANT_NameAndType, NameIndex: key[0], DescriptorIndex: key[1]})
+	c.nats[key] = index
+	return index
+}
+
+// 名称有变化时指向新的Utf8
+func (c *classRemapper) rename(index *uint16, value string) {
+	if c.str(*index) != value {
+		*index = c.utf8(value)
+	}
+}
+
+// 改写的常量换成新的对象, 原来的对象仍在c.pool中
+func (c *classRemapper) constants() {
+	r := c.r
+	for i, item := range c.pool {
+		switch v := item.(type) {
+		case *bytecode.ConstantClass:
+			if name := c.str(v.NameIndex); r.classConstant(name) != name {
+				c.f.ConstantPool[i] = &bytecode.ConstantClass{Tag: v.Tag, NameIndex: c.utf8(r.classConstant(name))}
+			}
+		case *bytecode.ConstantFieldref:
+			name, desc := c.nameAndTypeOf(v.NameAndTypeIndex)
+			if index, ok := c.member(name, desc, r.FieldName(c.className(v.ClassIndex), name, desc)); ok {
+				c.f.ConstantPool[i] = &bytecode.ConstantFieldref{Tag: v.Tag, ClassIndex: v.ClassIndex, NameAndTypeIndex: index}
+			}
+		case *bytecode.ConstantMethodref:
+			name, desc := c.nameAndTypeOf(v.NameAndTypeIndex)
+			if index, ok := c.member(name, desc, r.MethodName(c.className(v.ClassIndex), name, desc)); ok {
+				c.f.ConstantPool[i] = &bytecode.ConstantMethodref{Tag: v.Tag, ClassIndex: v.ClassIndex, NameAndTypeIndex: index}
+			}
+		case *bytecode.ConstantInterfaceMethodref:
+			name, desc := c.nameAndTypeOf(v.NameAndTypeIndex)
+			if index, ok := c.member(name, desc, r.MethodName(c.className(v.ClassIndex), name, desc)); ok {
+				c.f.ConstantPool[i] = &bytecode.ConstantInterfaceMethodref{Tag: v.Tag, ClassIndex: v.ClassIndex, NameAndTypeIndex: index}
+			}
+		case *bytecode.ConstantMethodType:
+			if desc := c.str(v.DescriptorIndex); r.Descriptor(desc) != desc {
+				c.f.ConstantPool[i] = &bytecode.ConstantMethodType{Tag: v.Tag, DescriptorIndex: c.utf8(r.Descriptor(desc))}
+			}
+		case *bytecode.ConstantInvokeDynamic:
+			name, desc := c.nameAndTypeOf(v.NameAndTypeIndex)
+			if index, ok := c.member(name, desc, c.lambdaName(v.BootstrapMethodAttrIndex, name, desc)); ok {
+				c.f.ConstantPool[i] = &bytecode.ConstantInvokeDynamic{Tag: v.Tag, BootstrapMethodAttrIndex: v.BootstrapMethodAttrIndex, NameAndTypeIndex: index}
+			}
+		case *bytecode.ConstantDynamic:
+			name, desc := c.nameAndTypeOf(v.NameAndTypeIndex)
+			if index, ok := c.member(name, desc, name); ok {
+				c.f.ConstantPool[i] = &bytecode.ConstantDynamic{Tag: v.Tag, BootstrapMethodAttrIndex: v.BootstrapMethodAttrIndex, NameAndTypeIndex: index}
+			}
+		}
+	}
+}
+
+// 名称或者描述符有变化时返回新的NameAndType
+func (c *classRemapper) member(name, desc, newName string) (uint16, bool) {
+	newDesc := c.r.Descriptor(desc)
+	if name == newName && desc == newDesc {
+		return 0, false
+	}
+	return c.nameAndType(newName, newDesc), true
+}
+
+// LambdaMetafactory生成的对象实现函数式接口, invokedynamic的名称是接口中方法的名称, 需要跟随接口改名.
+// 接口是描述符的返回类型, 第一个引导参数是方法擦除后的描述符
+func (c *classRemapper) lambdaName(bootstrap uint16, name, desc string) string {
+	var methods *bytecode.BootstrapMethods
+	for _, attr := range c.f.Attributes {
+		if a, ok := attr.(*bytecode.BootstrapMethods); ok {
+			methods = a
+		}
+	}
+	if methods == nil || int(bootstrap) >= len(methods.Methods) {
+		return name
+	}
+	method := methods.Methods[bootstrap]
+	handle, ok := c.constant(method.BootstrapMethodRef).(*bytecode.ConstantMethodHandle)
+	if !ok || len(method.Arguments) == 0 {
+		return name
+	}
+	ref, ok := c.constant(handle.ReferenceIndex).(*bytecode.ConstantMethodref)
+	if !ok || c.className(ref.ClassIndex) != LAMBDA_METAFACTORY {
+		return name
+	}
+	if factory, _ := c.nameAndTypeOf(ref.NameAndTypeIndex); factory != "metafactory" && factory != "altMetafactory" {
+		return name
+	}
+	samType, ok := c.constant(method.Arguments[0]).(*bytecode.ConstantMethodType)
+	_, ret, err := bytecode.ParseMethodDescriptor(desc)
+	if !ok || err != nil || !strings.HasPrefix(ret, "L") {
+		return name
+	}
+	return c.r.MethodName(ret[1:len(ret)-1], name, c.str(samType.DescriptorIndex))
+}
+
+func (c *classRemapper) attributes(attrs []bytecode.AttributeInfo, method *methodContext) {
+	r := c.r
+	for _, attr := range attrs {
+		switch a := attr.(type) {
+		case *bytecode.Code:
+			c.attributes(a.Attributes, method)
+		case *bytecode.Signature:
+			c.rename(&a.SignatureIndex, r.Signature(c.str(a.SignatureIndex)))
+		case *bytecode.LocalVariableTable:
+			for i := range a.LocalVariable {
+				v := &a.LocalVariable[i]
+				c.rename(&v.DescriptorIndex, r.Descriptor(c.str(v.DescriptorIndex)))
+				c.parameterName(method, v.StartPc, v.Index, &v.NameIndex)
+			}
+		case *bytecode.LocalVariableTypeTable:
+			for i := range a.LocalVariableType {
+				v := &a.LocalVariableType[i]
+				c.rename(&v.SignatureIndex, r.Signature(c.str(v.SignatureIndex)))
+				c.parameterName(method, v.StartPc, v.Index, &v.NameIndex)
+			}
+		case *bytecode.InnerClasses:
+			for i := range a.Classes {
+				c.innerClass(&a.Classes[i])
+			}
+		case *bytecode.EnclosingMethod:
+			if a.MethodIndex != 0 {
+				name, desc := c.nameAndTypeOf(a.MethodIndex)
+				if index, ok := c.member(name, desc, r.MethodName(c.className(a.ClassIndex), name, desc)); ok {
+					a.MethodIndex = index
+				}
+			}
+		case *bytecode.Record:
+			owner := c.f.ClassName()
+			for i := range a.RecordComponentInfo {
+				component := &a.RecordComponentInfo[i]
+				name, desc := c.str(component.NameIndex), c.str(component.DescriptorIndex)
+				c.rename(&component.NameIndex, r.FieldName(owner, name, desc))
+				c.rename(&component.DescriptorIndex, r.Descriptor(desc))
+				c.attributes(component.Attributes, nil)
+			}
+		case *bytecode.MethodParameters:
+			if method == nil || len(a.Parameters) != len(method.slots) {
+				continue
+			}
+			for i := range a.Parameters {
+				if name, ok := method.params[method.slots[i]]; ok {
+					c.rename(&a.Parameters[i].NameIndex, name)
+				}
+			}
+		case *bytecode.RuntimeVisibleAnnotations:
+			c.annotations(a.Annotations)
+		case *bytecode.RuntimeInvisibleAnnotations:
+			c.annotations(a.Annotations)
+		case *bytecode.RuntimeVisibleParameterAnnotations:
+			for i := range a.ParameterAnnotations {
+				c.annotations(a.ParameterAnnotations[i].Annotations)
+			}
+		case *bytecode.RuntimeInvisibleParameterAnnotations:
+			for i := range a.ParameterAnnotations {
+				c.annotations(a.ParameterAnnotations[i].Annotations)
+			}
+		case *bytecode.RuntimeVisibleTypeAnnotations:
+			c.typeAnnotations(a.Annotations)
+		case *bytecode.RuntimeInvisibleTypeAnnotations:
+			c.typeAnnotations(a.Annotations)
+		case *bytecode.AnnotationDefault:
+			c.elementValue(&a.DefaultValue)
+		}
+	}
+}
+
+// 局部变量表中从方法开头就有的参数按映射改名
+func (c *classRemapper) parameterName(method *methodContext, startPc, slot uint16, index *uint16) {
+	if method == nil || startPc != 0 {
+		return
+	}
+	for _, s := range method.slots {
+		if s == int(slot) {
+			if name, ok := method.params[s]; ok {
+				c.rename(index, name)
+			}
+		}
+	}
+}
+
+// 映射中明确改名的内部类才改简单名称, 跟随外部类改名的不变. 局部类的名称是外部类$序号加简单名称
+func (c *classRemapper) innerClass(info *bytecode.InnerClassInfo) {
+	if info.InnerNameIndex == 0 {
+		return
+	}
+	inner := c.className(info.InnerClassIndex)
+	mapped, explicit := c.r.classes[inner]
+	if !explicit {
+		return
+	}
+	outer := ""
+	if info.OuterClassIndex != 0 {
+		outer = c.r.ClassName(c.className(info.OuterClassIndex))
+	}
+	name := simpleName(mapped, outer)
+	if outer == "" {
+		name = strings.TrimLeft(name, "0123456789")
+	}
+	if name != "" {
+		c.rename(&info.InnerNameIndex, name)
+	}
+}
+
+func (c *classRemapper) annotations(annotations []bytecode.Annotation) {
+	for i := range annotations {
+		c.annotation(&annotations[i])
+	}
+}
+
+func (c *classRemapper) annotation(a *bytecode.Annotation) {
+	desc := c.str(a.TypeIndex)
+	c.rename(&a.TypeIndex, c.r.Descriptor(desc))
+	c.elementValuePairs(desc, a.ValuePairs)
+}
+
+func (c *classRemapper) typeAnnotations(annotations []bytecode.TypeAnnotation) {
+	for i := range annotations {
+		a := &annotations[i]
+		desc := c.str(a.TypeIndex)
+		c.rename(&a.TypeIndex, c.r.Descriptor(desc))
+		c.elementValuePairs(desc, a.ValuePairs)
+	}
+}
+
+// 元素名是注解接口中的方法名
+func (c *classRemapper) elementValuePairs(desc string, pairs []bytecode.ElementValuePairs) {
+	annotation := strings.TrimSuffix(strings.TrimPrefix(desc, "L"), ";")
+	for i := range pairs {
+		pair := &pairs[i]
+		c.rename(&pair.ElementNameIndex, c.r.elementName(annotation, c.str(pair.ElementNameIndex)))
+		c.elementValue(&pair.ElementValue)
+	}
+}
+
+// 枚举常量是枚举类中的字段, 类字面量是返回值描述符
+func (c *classRemapper) elementValue(e *bytecode.ElementValue) {
+	switch e.Tag {
+	case 'e':
+		desc, name := c.str(e.TypeNameIndex), c.str(e.ConstNameIndex)
+		c.rename(&e.ConstNameIndex, c.r.FieldName(strings.TrimSuffix(strings.TrimPrefix(desc, "L"), ";"), name, desc))
+		c.rename(&e.TypeNameIndex, c.r.Descriptor(desc))
+	case 'c':
+		c.rename(&e.ClassInfoIndex, c.r.Descriptor(c.str(e.ClassInfoIndex)))
+	case '@':
+		c.annotation(&e.AnnotationValue)
+	case '[':
+		for i := range e.Values {
+			c.elementValue(&e.Values[i])
+		}
+	}
+}
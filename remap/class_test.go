@@ -0,0 +1,141 @@
+package remap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"class-file-parser/bytecode"
+)
+
+func loadClass(t *testing.T, name string) *bytecode.ClassFile {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "bytecode", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := bytecode.ParseClassFile(data)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return f
+}
+
+func newRemapper(t *testing.T, mapping string) *Remapper {
+	t.Helper()
+	m, err := Parse(strings.NewReader(mapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(m, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// 常量池已经满了, 新的类名放不下, 不能让索引回绕
+func TestRemapConstantPoolOverflow(t *testing.T) {
+	f := loadClass(t, "Box.class")
+	for i := 0; len(f.ConstantPool) < 65535; i++ {
+		value := fmt.Sprintf("pad%d", i)
+		f.ConstantPool = append(f.ConstantPool, &bytecode.ConstantUtf8{Tag: bytecode.CONSTANT_Utf8, Length: uint16(len(value)), Value: []byte(value)})
+	}
+	f.ConstantPoolCount = uint16(len(f.ConstantPool))
+	r := newRemapper(t, "v1\tofficial\tnamed\nCLASS\tcom/acme/Box\tcom/acme/Crate\n")
+	r.AddClass(f)
+	err := r.Remap(f)
+	if err == nil || !strings.Contains(err.Error(), "65535") {
+		t.Fatalf("expect the constant pool to overflow, got %v", err)
+	}
+}
+
+// 改名之后写出、重新解析, 类文件仍然合法, 名称都已改写
+func TestRemapRoundTrip(t *testing.T) {
+	f := loadClass(t, "Box.class")
+	r := newRemapper(t, "v1\tofficial\tnamed\n"+
+		"CLASS\tcom/acme/Box\tcom/acme/Crate\n"+
+		"CLASS\tcom/acme/Base\tcom/acme/Root\n"+
+		"FIELD\tcom/acme/Box\tLjava/util/List;\titems\telements\n"+
+		"METHOD\tcom/acme/Box\t(Ljava/util/function/Function;)Ljava/lang/Object;\tmap\ttransform\n")
+	r.AddClass(f)
+	if err := r.Remap(f); err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := bytecode.ParseClassFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, finding := range g.Verify() {
+		if finding.Severity == bytecode.SEVERITY_ERROR {
+			t.Error(finding.String())
+		}
+	}
+	pool := g.ConstantPool
+	if g.ClassName() != "com/acme/Crate" || g.SuperClassName() != "com/acme/Root" {
+		t.Errorf("class is %s extends %s", g.ClassName(), g.SuperClassName())
+	}
+	names := make(map[string]bool)
+	for i := range g.Fields {
+		names[g.Fields[i].Name(pool)] = true
+	}
+	for i := range g.Methods {
+		names[g.Methods[i].Name(pool)] = true
+	}
+	for _, attr := range g.Attributes {
+		switch a := attr.(type) {
+		case *bytecode.Signature:
+			names[bytecode.Utf8At(pool, a.SignatureIndex)] = true
+		case *bytecode.InnerClasses:
+			for _, c := range a.Classes {
+				names[bytecode.ClassNameAt(pool, c.InnerClassIndex)] = true
+			}
+		}
+	}
+	for _, name := range []string{"elements", "transform", "com/acme/Crate$Entry",
+		"<T::Ljava/lang/Comparable<TT;>;>Lcom/acme/Root;Ljava/lang/Iterable<TT;>;"} {
+		if !names[name] {
+			t.Errorf("%s is missing", name)
+		}
+	}
+	for i := range pool {
+		if value := bytecode.Utf8At(pool, uint16(i)); value == "com/acme/Box" || value == "com/acme/Base" {
+			t.Errorf("constant #%d %s is still in the pool", i, value)
+		}
+	}
+}
+
+// 不认识的属性原样保留, 原有常量的索引不变
+func TestRemapKeepsUnknownAttributes(t *testing.T) {
+	f := loadClass(t, "Lambdas.class")
+	r := newRemapper(t, "v1\tofficial\tnamed\nCLASS\tcom/acme/Lambdas\tcom/acme/Closures\n")
+	r.AddClass(f)
+	if err := r.Remap(f); err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := bytecode.ParseClassFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, finding := range g.Verify() {
+		if finding.Severity == bytecode.SEVERITY_ERROR {
+			t.Error(finding.String())
+		}
+	}
+	if g.ClassName() != "com/acme/Closures" {
+		t.Errorf("class is %s", g.ClassName())
+	}
+	if !g.HasUnknownAttributes() {
+		t.Error("ScalaSig attribute is lost")
+	}
+}
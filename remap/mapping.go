@@ -0,0 +1,430 @@
+package remap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"class-file-parser/retrace"
+)
+
+// 映射文件的格式, 读取时按内容自动识别
+const (
+	FORMAT_PROGUARD = "proguard"
+	FORMAT_TINY_V1  = "tiny"
+	FORMAT_TINY_V2  = "tiny2"
+	FORMAT_SRG      = "srg"
+	FORMAT_TSRG     = "tsrg"
+)
+
+// 没有命名空间名称的格式使用的名称. ProGuard的mapping.txt是原始名称到混淆后的名称, SRG是混淆后的名称到SRG名称
+var defaultNamespaces = map[string][]string{
+	FORMAT_PROGUARD: {"original", "obfuscated"},
+	FORMAT_SRG:      {"obf", "srg"},
+	FORMAT_TSRG:     {"obf", "srg"},
+}
+
+// 各个命名空间中的名称, 类名都是内部形式, 例如com/acme/Main$Inner.
+// 成员的描述符使用第一个命名空间中的类名, SRG和TSRG的字段没有描述符
+type Mapping struct {
+	Format     string
+	Namespaces []string
+	Classes    []*Class
+}
+
+type Class struct {
+	Names   []string
+	Fields  []Member
+	Methods []Member
+}
+
+type Member struct {
+	Names      []string
+	Descriptor string
+	Parameters []Parameter
+}
+
+// Tiny v2中方法参数的名称, Index是局部变量表中的位置
+type Parameter struct {
+	Index int
+	Names []string
+}
+
+// 读取映射文件并识别格式, 缺少的名称与第一个命名空间相同
+func Parse(r io.Reader) (*Mapping, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lines := make([]string, 0)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var m *Mapping
+	var err error
+	switch format := detect(lines); format {
+	case FORMAT_PROGUARD:
+		m, err = parseProGuard(lines)
+	case FORMAT_TINY_V1:
+		m, err = parseTinyV1(lines)
+	case FORMAT_TINY_V2:
+		m, err = parseTinyV2(lines)
+	case FORMAT_SRG:
+		m, err = parseSRG(lines)
+	case FORMAT_TSRG:
+		m, err = parseTSRG(lines)
+	default:
+		return nil, fmt.Errorf("unknown mapping format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.fill()
+	return m, nil
+}
+
+// 按第一个有内容的行判断, ProGuard的注释行跳过
+func detect(lines []string) string {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(line, "v1\t"):
+			return FORMAT_TINY_V1
+		case strings.HasPrefix(line, "tiny\t2\t"):
+			return FORMAT_TINY_V2
+		case strings.HasPrefix(line, "PK: "), strings.HasPrefix(line, "CL: "), strings.HasPrefix(line, "FD: "), strings.HasPrefix(line, "MD: "):
+			return FORMAT_SRG
+		case strings.Contains(line, " -> ") && strings.HasSuffix(trimmed, ":"):
+			return FORMAT_PROGUARD
+		case len(strings.Fields(line)) == 2 && !strings.HasPrefix(line, "\t"):
+			return FORMAT_TSRG
+		}
+		return ""
+	}
+	return ""
+}
+
+func (m *Mapping) namespace(name string) int {
+	for i, ns := range m.Namespaces {
+		if ns == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// 空的名称取第一个命名空间中的名称
+func (m *Mapping) fill() {
+	fill := func(names []string) []string {
+		for len(names) < len(m.Namespaces) {
+			names = append(names, "")
+		}
+		for i := range names {
+			if names[i] == "" {
+				names[i] = names[0]
+			}
+		}
+		return names
+	}
+	for _, c := range m.Classes {
+		c.Names = fill(c.Names)
+		for i := range c.Fields {
+			c.Fields[i].Names = fill(c.Fields[i].Names)
+		}
+		for i := range c.Methods {
+			c.Methods[i].Names = fill(c.Methods[i].Names)
+		}
+	}
+}
+
+// 只使用类和成员的名称, 行号范围忽略; 内联进来的方法和调用处的外层方法只有最后一项是方法本身
+func parseProGuard(lines []string) (*Mapping, error) {
+	pg, err := retrace.ParseMapping(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	m := &Mapping{Format: FORMAT_PROGUARD, Namespaces: defaultNamespaces[FORMAT_PROGUARD], Classes: make([]*Class, 0)}
+	for _, pc := range pg.Classes {
+		c := &Class{Names: []string{internalName(pc.Original), internalName(pc.Obfuscated)}}
+		for _, f := range pc.Fields {
+			c.Fields = append(c.Fields, Member{Names: []string{f.Original, f.Obfuscated}, Descriptor: javaDescriptor(f.Type)})
+		}
+		seen := make(map[string]bool)
+		for i, pm := range pc.Methods {
+			if i+1 < len(pc.Methods) {
+				next := pc.Methods[i+1]
+				if next.Obfuscated == pm.Obfuscated && (pm.ObfuscatedStart > 0 || pm.ObfuscatedEnd > 0) &&
+					next.ObfuscatedStart == pm.ObfuscatedStart && next.ObfuscatedEnd == pm.ObfuscatedEnd &&
+					next.OriginalStart > 0 && (next.OriginalEnd == 0 || next.OriginalEnd == next.OriginalStart) {
+					continue
+				}
+			}
+			if strings.Contains(pm.Original, ".") {
+				continue
+			}
+			descriptor := "("
+			for _, p := range pm.Parameters {
+				descriptor += javaDescriptor(p)
+			}
+			descriptor += ")" + javaDescriptor(pm.ReturnType)
+			if key := pm.Original + descriptor; !seen[key] {
+				seen[key] = true
+				c.Methods = append(c.Methods, Member{Names: []string{pm.Original, pm.Obfuscated}, Descriptor: descriptor})
+			}
+		}
+		m.Classes = append(m.Classes, c)
+	}
+	return m, nil
+}
+
+func internalName(name string) string {
+	return strings.ReplaceAll(name, ".", "/")
+}
+
+var primitiveDescriptors = map[string]string{
+	"boolean": "Z", "byte": "B", "char": "C", "short": "S", "int": "I", "long": "J", "float": "F", "double": "D", "void": "V",
+}
+
+// Java形式的类型转换为描述符, 例如com.acme.Foo[]得到[Lcom/acme/Foo;
+func javaDescriptor(t string) string {
+	t = strings.TrimSpace(t)
+	prefix := ""
+	for strings.HasSuffix(t, "[]") {
+		prefix += "["
+		t = strings.TrimSuffix(t, "[]")
+	}
+	if d, ok := primitiveDescriptors[t]; ok {
+		return prefix + d
+	}
+	return prefix + "L" + internalName(t) + ";"
+}
+
+// v1	ns0	ns1...; CLASS	名称...; FIELD和METHOD是所属的类、描述符和名称, 类名和描述符都是第一个命名空间中的
+func parseTinyV1(lines []string) (*Mapping, error) {
+	header := strings.Split(lines[firstLine(lines)], "\t")
+	if len(header) < 3 {
+		return nil, fmt.Errorf("line %d: invalid tiny header", firstLine(lines)+1)
+	}
+	m := &Mapping{Format: FORMAT_TINY_V1, Namespaces: header[1:], Classes: make([]*Class, 0)}
+	classes := make(map[string]*Class)
+	class := func(name string) *Class {
+		c := classes[name]
+		if c == nil {
+			c = &Class{Names: []string{name}}
+			classes[name] = c
+			m.Classes = append(m.Classes, c)
+		}
+		return c
+	}
+	for i := firstLine(lines) + 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		switch fields[0] {
+		case "CLASS":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: invalid class mapping", i+1)
+			}
+			class(fields[1]).Names = fields[1:]
+		case "FIELD", "METHOD":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: invalid %s mapping", i+1, strings.ToLower(fields[0]))
+			}
+			c := class(fields[1])
+			member := Member{Names: fields[3:], Descriptor: fields[2]}
+			if fields[0] == "FIELD" {
+				c.Fields = append(c.Fields, member)
+			} else {
+				c.Methods = append(c.Methods, member)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown entry %q", i+1, fields[0])
+		}
+	}
+	return m, nil
+}
+
+func firstLine(lines []string) int {
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return i
+		}
+	}
+	return 0
+}
+
+// tiny	2	0	ns0	ns1...; 缩进表示层次: c是类, 类中的f和m是字段和方法, 方法中的p是参数.
+// 同一层次的c、v以及未知的项忽略, 有escaped-names属性时名称中的转义字符需要还原
+func parseTinyV2(lines []string) (*Mapping, error) {
+	start := firstLine(lines)
+	header := strings.Split(lines[start], "\t")
+	if len(header) < 5 || header[2] != "0" {
+		return nil, fmt.Errorf("line %d: invalid tiny v2 header", start+1)
+	}
+	m := &Mapping{Format: FORMAT_TINY_V2, Namespaces: header[3:], Classes: make([]*Class, 0)}
+	escaped := false
+	var class *Class
+	var method *Member
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, "\t"))
+		fields := strings.Split(line[depth:], "\t")
+		if escaped {
+			for n := range fields {
+				fields[n] = unescapeTiny(fields[n])
+			}
+		}
+		invalid := func() error {
+			return fmt.Errorf("line %d: invalid %q entry", i+1, fields[0])
+		}
+		switch {
+		case depth == 1 && class == nil:
+			// 文件头之后的属性
+			if fields[0] == "escaped-names" {
+				escaped = true
+			}
+		case depth == 0 && fields[0] == "c":
+			if len(fields) < 2 {
+				return nil, invalid()
+			}
+			class, method = &Class{Names: fields[1:]}, nil
+			m.Classes = append(m.Classes, class)
+		case depth == 1 && (fields[0] == "f" || fields[0] == "m"):
+			if class == nil || len(fields) < 3 {
+				return nil, invalid()
+			}
+			member := Member{Names: fields[2:], Descriptor: fields[1]}
+			if fields[0] == "f" {
+				class.Fields, method = append(class.Fields, member), nil
+			} else {
+				class.Methods = append(class.Methods, member)
+				method = &class.Methods[len(class.Methods)-1]
+			}
+		case depth == 2 && fields[0] == "p" && method != nil:
+			if len(fields) < 3 {
+				return nil, invalid()
+			}
+			index, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, invalid()
+			}
+			method.Parameters = append(method.Parameters, Parameter{Index: index, Names: fields[2:]})
+		}
+	}
+	return m, nil
+}
+
+func unescapeTiny(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r", `\t`, "\t", `\0`, "\x00").Replace(s)
+}
+
+// CL: a com/acme/Main; FD: a/b com/acme/Main/count; MD: a/b (I)V com/acme/Main/run (I)V. PK行忽略
+func parseSRG(lines []string) (*Mapping, error) {
+	m := &Mapping{Format: FORMAT_SRG, Namespaces: defaultNamespaces[FORMAT_SRG], Classes: make([]*Class, 0)}
+	classes := make(map[string]*Class)
+	class := func(name string) *Class {
+		c := classes[name]
+		if c == nil {
+			c = &Class{Names: []string{name, ""}}
+			classes[name] = c
+			m.Classes = append(m.Classes, c)
+		}
+		return c
+	}
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		invalid := func() error {
+			return fmt.Errorf("line %d: invalid %s entry", i+1, fields[0])
+		}
+		switch fields[0] {
+		case "PK:":
+		case "CL:":
+			if len(fields) != 3 {
+				return nil, invalid()
+			}
+			class(fields[1]).Names[1] = fields[2]
+		case "FD:":
+			// XSRG的字段带有描述符: FD: a/b I com/acme/Main/count I
+			source, target, descriptor := "", "", ""
+			switch len(fields) {
+			case 3:
+				source, target = fields[1], fields[2]
+			case 5:
+				source, descriptor, target = fields[1], fields[2], fields[3]
+			default:
+				return nil, invalid()
+			}
+			owner, name := splitMember(source)
+			_, newName := splitMember(target)
+			c := class(owner)
+			c.Fields = append(c.Fields, Member{Names: []string{name, newName}, Descriptor: descriptor})
+		case "MD:":
+			if len(fields) != 5 {
+				return nil, invalid()
+			}
+			owner, name := splitMember(fields[1])
+			_, newName := splitMember(fields[3])
+			c := class(owner)
+			c.Methods = append(c.Methods, Member{Names: []string{name, newName}, Descriptor: fields[2]})
+		default:
+			return nil, fmt.Errorf("line %d: unknown entry %q", i+1, fields[0])
+		}
+	}
+	return m, nil
+}
+
+// com/acme/Main/run分为类名和成员名
+func splitMember(s string) (string, string) {
+	i := strings.LastIndex(s, "/")
+	if i < 0 {
+		return "", s
+	}
+	return s[:i], s[i+1:]
+}
+
+// 类一行: a com/acme/Main; 成员缩进: 字段a count, 方法a (I)V run
+func parseTSRG(lines []string) (*Mapping, error) {
+	m := &Mapping{Format: FORMAT_TSRG, Namespaces: defaultNamespaces[FORMAT_TSRG], Classes: make([]*Class, 0)}
+	var class *Class
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, " ") {
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: invalid class mapping", i+1)
+			}
+			class = &Class{Names: fields}
+			m.Classes = append(m.Classes, class)
+			continue
+		}
+		switch {
+		case class == nil:
+			return nil, fmt.Errorf("line %d: member mapping outside of a class", i+1)
+		case len(fields) == 2:
+			class.Fields = append(class.Fields, Member{Names: fields})
+		case len(fields) == 3 && strings.HasPrefix(fields[1], "("):
+			class.Methods = append(class.Methods, Member{Names: []string{fields[0], fields[2]}, Descriptor: fields[1]})
+		default:
+			return nil, fmt.Errorf("line %d: invalid member mapping", i+1)
+		}
+	}
+	return m, nil
+}
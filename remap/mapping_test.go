@@ -0,0 +1,139 @@
+package remap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		format     string
+		namespaces []string
+		classes    []*Class
+	}{
+		{
+			name: "proguard",
+			input: "# compiler: R8\n" +
+				"com.acme.Base -> a:\n" +
+				"    int count -> a\n" +
+				"    1:2:void run():10:11 -> a\n" +
+				"    java.lang.String name(int,com.acme.Base[]) -> b\n" +
+				"    3:3:void inlined():20:20 -> c\n" +
+				"    3:3:void outer():5 -> c\n",
+			format:     FORMAT_PROGUARD,
+			namespaces: []string{"original", "obfuscated"},
+			classes: []*Class{{
+				Names:  []string{"com/acme/Base", "a"},
+				Fields: []Member{{Names: []string{"count", "a"}, Descriptor: "I"}},
+				Methods: []Member{
+					{Names: []string{"run", "a"}, Descriptor: "()V"},
+					{Names: []string{"name", "b"}, Descriptor: "(I[Lcom/acme/Base;)Ljava/lang/String;"},
+					{Names: []string{"outer", "c"}, Descriptor: "()V"},
+				},
+			}},
+		},
+		{
+			name: "tiny v1",
+			input: "v1\tofficial\tintermediary\tnamed\n" +
+				"CLASS\ta\tclass_1\tcom/acme/Base\n" +
+				"FIELD\ta\tI\ta\tfield_1\tcount\n" +
+				"METHOD\ta\t()V\ta\tmethod_1\t\n",
+			format:     FORMAT_TINY_V1,
+			namespaces: []string{"official", "intermediary", "named"},
+			classes: []*Class{{
+				Names:   []string{"a", "class_1", "com/acme/Base"},
+				Fields:  []Member{{Names: []string{"a", "field_1", "count"}, Descriptor: "I"}},
+				Methods: []Member{{Names: []string{"a", "method_1", "a"}, Descriptor: "()V"}},
+			}},
+		},
+		{
+			name: "tiny v2",
+			input: "tiny\t2\t0\tofficial\tnamed\n" +
+				"\tescaped-names\n" +
+				"c\ta\tcom/acme/Base\n" +
+				"\tf\tI\ta\tcount\n" +
+				"\tm\t(La;)V\ta\tset\n" +
+				"\t\tp\t1\t\tvalue\n" +
+				"c\tb\tcom/acme/Back\\\\slash\n",
+			format:     FORMAT_TINY_V2,
+			namespaces: []string{"official", "named"},
+			classes: []*Class{
+				{
+					Names:  []string{"a", "com/acme/Base"},
+					Fields: []Member{{Names: []string{"a", "count"}, Descriptor: "I"}},
+					Methods: []Member{{Names: []string{"a", "set"}, Descriptor: "(La;)V",
+						Parameters: []Parameter{{Index: 1, Names: []string{"", "value"}}}}},
+				},
+				{Names: []string{"b", `com/acme/Back\slash`}},
+			},
+		},
+		{
+			name: "srg",
+			input: "PK: ./ com/acme\n" +
+				"CL: a com/acme/Base\n" +
+				"FD: a/a com/acme/Base/count\n" +
+				"FD: a/b I com/acme/Base/size I\n" +
+				"MD: a/c ()V com/acme/Base/run ()V\n",
+			format:     FORMAT_SRG,
+			namespaces: []string{"obf", "srg"},
+			classes: []*Class{{
+				Names:   []string{"a", "com/acme/Base"},
+				Fields:  []Member{{Names: []string{"a", "count"}}, {Names: []string{"b", "size"}, Descriptor: "I"}},
+				Methods: []Member{{Names: []string{"c", "run"}, Descriptor: "()V"}},
+			}},
+		},
+		{
+			name:       "tsrg",
+			input:      "a com/acme/Base\n\ta count\n\tc ()V run\nb com/acme/Child\n",
+			format:     FORMAT_TSRG,
+			namespaces: []string{"obf", "srg"},
+			classes: []*Class{
+				{
+					Names:   []string{"a", "com/acme/Base"},
+					Fields:  []Member{{Names: []string{"a", "count"}}},
+					Methods: []Member{{Names: []string{"c", "run"}, Descriptor: "()V"}},
+				},
+				{Names: []string{"b", "com/acme/Child"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		m, err := Parse(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if m.Format != test.format {
+			t.Errorf("%s: format is %s", test.name, m.Format)
+		}
+		if !reflect.DeepEqual(m.Namespaces, test.namespaces) {
+			t.Errorf("%s: namespaces are %v", test.name, m.Namespaces)
+		}
+		if len(m.Classes) != len(test.classes) {
+			t.Errorf("%s: %d classes, expect %d", test.name, len(m.Classes), len(test.classes))
+			continue
+		}
+		for i, c := range m.Classes {
+			if !reflect.DeepEqual(c, test.classes[i]) {
+				t.Errorf("%s: class %d is %+v, expect %+v", test.name, i, *c, *test.classes[i])
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"hello world foo\n",
+		"v1\tofficial\tnamed\nMETHOD\ta\t()V\n",
+		"tiny\t2\t1\tofficial\tnamed\n",
+		"MD: a/a ()V com/acme/Base/run\n",
+		"a com/acme/Base\n\ta b c d\n",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("expect an error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package remap
+
+import (
+	"fmt"
+	"strings"
+
+	"class-file-parser/bytecode"
+)
+
+// 按映射把一个命名空间中的名称改为另一个命名空间中的名称. 表中的类名和描述符都是源命名空间中的
+type Remapper struct {
+	classes map[string]string
+	// 键为owner.name:desc, 没有描述符的字段为owner.name
+	fields map[string]string
+	// 键为owner.name(desc)
+	methods map[string]string
+	// 同名的方法改为相同的名称时才有, 用于不知道描述符的注解元素
+	methodNames map[string]string
+	params      map[string]map[int]string
+	// 源命名空间中的类, 用于查找继承的成员
+	hierarchy map[string]*classInfo
+}
+
+// 类改名之后仍要查找继承关系, 所以只保存改名前的父类、接口和成员
+type classInfo struct {
+	super      string
+	interfaces []string
+	// 键为name:desc
+	fields map[string]bool
+	// 键为name+desc, 值为访问标志
+	methods map[string]uint16
+}
+
+// from和to为空时分别是第一个和最后一个命名空间
+func New(m *Mapping, from, to string) (*Remapper, error) {
+	source, target := 0, len(m.Namespaces)-1
+	if from != "" {
+		if source = m.namespace(from); source < 0 {
+			return nil, fmt.Errorf("unknown namespace %q, expect one of %s", from, strings.Join(m.Namespaces, ", "))
+		}
+	}
+	if to != "" {
+		if target = m.namespace(to); target < 0 {
+			return nil, fmt.Errorf("unknown namespace %q, expect one of %s", to, strings.Join(m.Namespaces, ", "))
+		}
+	}
+	// 映射中的描述符先换算到源命名空间
+	first := &Remapper{classes: make(map[string]string)}
+	r := &Remapper{classes: make(map[string]string), fields: make(map[string]string), methods: make(map[string]string),
+		methodNames: make(map[string]string), params: make(map[string]map[int]string), hierarchy: make(map[string]*classInfo)}
+	for _, c := range m.Classes {
+		first.classes[c.Names[0]] = c.Names[source]
+		if c.Names[source] != c.Names[target] {
+			r.classes[c.Names[source]] = c.Names[target]
+		}
+	}
+	for _, c := range m.Classes {
+		owner := c.Names[source]
+		for _, f := range c.Fields {
+			if f.Names[source] == f.Names[target] {
+				continue
+			}
+			key := owner + "." + f.Names[source]
+			r.fields[key] = f.Names[target]
+			if f.Descriptor != "" {
+				r.fields[key+":"+first.Descriptor(f.Descriptor)] = f.Names[target]
+			}
+		}
+		for _, method := range c.Methods {
+			key := owner + "." + method.Names[source]
+			descriptor := first.Descriptor(method.Descriptor)
+			if method.Names[source] != method.Names[target] {
+				r.methods[key+descriptor] = method.Names[target]
+			}
+			if name, ok := r.methodNames[key]; !ok {
+				r.methodNames[key] = method.Names[target]
+			} else if name != method.Names[target] {
+				r.methodNames[key] = ""
+			}
+			for _, p := range method.Parameters {
+				if target < len(p.Names) && p.Names[target] != "" {
+					if r.params[key+descriptor] == nil {
+						r.params[key+descriptor] = make(map[int]string)
+					}
+					r.params[key+descriptor][p.Index] = p.Names[target]
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+// 添加源命名空间中的类, 查找继承的成员时使用. 要改名的类和它们的父类、接口都应该添加
+func (r *Remapper) AddClass(f *bytecode.ClassFile) {
+	if _, ok := r.hierarchy[f.ClassName()]; ok {
+		return
+	}
+	info := &classInfo{super: f.SuperClassName(), interfaces: f.InterfaceNames(), fields: make(map[string]bool), methods: make(map[string]uint16)}
+	for i := range f.Fields {
+		info.fields[f.Fields[i].Name(f.ConstantPool)+":"+f.Fields[i].Descriptor(f.ConstantPool)] = true
+	}
+	for i := range f.Methods {
+		info.methods[f.Methods[i].Name(f.ConstantPool)+f.Methods[i].Descriptor(f.ConstantPool)] = f.Methods[i].AccessFlags
+	}
+	r.hierarchy[f.ClassName()] = info
+}
+
+// 映射中没有的内部类跟随外部类改名, 例如a$1在a改为com/acme/Main时改为com/acme/Main$1
+func (r *Remapper) ClassName(name string) string {
+	if mapped, ok := r.classes[name]; ok {
+		return mapped
+	}
+	for i := strings.LastIndex(name, "$"); i > 0; i = strings.LastIndex(name[:i], "$") {
+		if mapped, ok := r.classes[name[:i]]; ok {
+			return mapped + name[i:]
+		}
+	}
+	return name
+}
+
+// Class常量中的名称, 数组是描述符
+func (r *Remapper) classConstant(name string) string {
+	if strings.HasPrefix(name, "[") {
+		return r.Descriptor(name)
+	}
+	return r.ClassName(name)
+}
+
+// 字段或者方法描述符中的类名改名, 格式错误时不变
+func (r *Remapper) Descriptor(desc string) string {
+	return r.Signature(desc)
+}
+
+// 泛型签名中的类名改名, 内部类按外部类改名后的名称取简单名称. 格式错误时不变
+func (r *Remapper) Signature(signature string) string {
+	p := &signatureParser{s: signature, class: r.ClassName}
+	if err := p.signature(); err != nil {
+		return signature
+	}
+	return p.out.String()
+}
+
+// 字段按JVM的解析顺序查找: 类本身、接口、父类, 找到声明字段的类为止
+func (r *Remapper) FieldName(owner, name, desc string) string {
+	seen := make(map[string]bool)
+	var find func(class string) (string, bool)
+	find = func(class string) (string, bool) {
+		if class == "" || seen[class] {
+			return "", false
+		}
+		seen[class] = true
+		if mapped, ok := r.fields[class+"."+name+":"+desc]; ok {
+			return mapped, true
+		}
+		if mapped, ok := r.fields[class+"."+name]; ok {
+			return mapped, true
+		}
+		info := r.hierarchy[class]
+		if info == nil {
+			return "", false
+		}
+		if info.fields[name+":"+desc] {
+			return name, true
+		}
+		for _, iface := range info.interfaces {
+			if mapped, ok := find(iface); ok {
+				return mapped, true
+			}
+		}
+		return find(info.super)
+	}
+	if mapped, ok := find(owner); ok {
+		return mapped
+	}
+	return name
+}
+
+// 方法先在类本身查找, 然后沿父类和接口查找被覆盖的方法, 这样覆盖的方法和被覆盖的方法改为同一个名称.
+// 父类中的private方法不会被继承, 跳过; 构造方法和类初始化方法不改名
+func (r *Remapper) MethodName(owner, name, desc string) string {
+	if name == "<init>" || name == "<clinit>" {
+		return name
+	}
+	if mapped, ok := r.inheritedMethod(owner, name, desc, true, make(map[string]bool)); ok {
+		return mapped
+	}
+	return name
+}
+
+func (r *Remapper) inheritedMethod(class, name, desc string, self bool, seen map[string]bool) (string, bool) {
+	if class == "" || seen[class] {
+		return "", false
+	}
+	seen[class] = true
+	info := r.hierarchy[class]
+	private := false
+	if info != nil && !self {
+		private = info.methods[name+desc]&bytecode.METHOD_ACC_PRIVATE != 0
+	}
+	if mapped, ok := r.methods[class+"."+name+desc]; ok && !private {
+		return mapped, true
+	}
+	if info == nil {
+		return "", false
+	}
+	if mapped, ok := r.inheritedMethod(info.super, name, desc, false, seen); ok {
+		return mapped, true
+	}
+	for _, iface := range info.interfaces {
+		if mapped, ok := r.inheritedMethod(iface, name, desc, false, seen); ok {
+			return mapped, true
+		}
+	}
+	return "", false
+}
+
+// 只在类本身查找, 用于private和static方法的声明
+func (r *Remapper) declaredMethodName(owner, name, desc string) string {
+	if mapped, ok := r.methods[owner+"."+name+desc]; ok {
+		return mapped
+	}
+	return name
+}
+
+// 注解元素只有名称, 同名的方法都改为同一个名称时才改名
+func (r *Remapper) elementName(annotation, name string) string {
+	if mapped := r.methodNames[annotation+"."+name]; mapped != "" {
+		return mapped
+	}
+	return name
+}
+
+// 内部类的简单名称, 改名后的类名以改名后的外部类加$开头时取其余的部分, 否则取最后一个$或者/之后的部分
+func simpleName(inner, outer string) string {
+	if outer != "" && strings.HasPrefix(inner, outer+"$") {
+		return inner[len(outer)+1:]
+	}
+	return inner[strings.LastIndexAny(inner, "$/")+1:]
+}
@@ -0,0 +1,44 @@
+package remap
+
+import (
+	"testing"
+
+	"class-file-parser/bytecode"
+)
+
+// a <- b <- c, c实现j, j继承i. a中的hide是private的, 不会被b继承
+func TestMethodName(t *testing.T) {
+	r := newRemapper(t, "v1\tofficial\tnamed\n"+
+		"METHOD\ta\t()V\trun\texecute\n"+
+		"METHOD\ta\t()V\thide\tsecret\n"+
+		"METHOD\ti\t()V\tcall\tinvoke\n"+
+		"METHOD\ta\t()V\t<init>\tcreate\n")
+	r.hierarchy["a"] = &classInfo{super: "java/lang/Object",
+		methods: map[string]uint16{"run()V": bytecode.METHOD_ACC_PUBLIC, "hide()V": bytecode.METHOD_ACC_PRIVATE}}
+	r.hierarchy["b"] = &classInfo{super: "a", methods: map[string]uint16{}}
+	r.hierarchy["c"] = &classInfo{super: "b", interfaces: []string{"j"}, methods: map[string]uint16{}}
+	r.hierarchy["j"] = &classInfo{super: "java/lang/Object", interfaces: []string{"i"}, methods: map[string]uint16{}}
+	r.hierarchy["i"] = &classInfo{super: "java/lang/Object", methods: map[string]uint16{"call()V": bytecode.METHOD_ACC_ABSTRACT}}
+	tests := []struct {
+		owner, name, want string
+	}{
+		{"a", "run", "execute"},
+		{"b", "run", "execute"},
+		{"c", "run", "execute"},
+		{"a", "hide", "secret"},
+		{"b", "hide", "hide"},
+		{"c", "hide", "hide"},
+		{"c", "call", "invoke"},
+		{"j", "call", "invoke"},
+		{"a", "<init>", "<init>"},
+		{"x", "run", "run"},
+	}
+	for _, test := range tests {
+		if got := r.MethodName(test.owner, test.name, "()V"); got != test.want {
+			t.Errorf("%s.%s: got %s, want %s", test.owner, test.name, got, test.want)
+		}
+	}
+	if got := r.declaredMethodName("a", "hide", "()V"); got != "secret" {
+		t.Errorf("declared private method: got %s", got)
+	}
+}
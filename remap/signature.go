@@ -0,0 +1,190 @@
+package remap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 按JVMS 4.7.9.1的文法改写签名, 描述符是不带泛型的签名, 可以用同一套规则处理
+type signatureParser struct {
+	s     string
+	pos   int
+	out   strings.Builder
+	class func(string) string
+}
+
+func (p *signatureParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("signature %q at %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *signatureParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *signatureParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expect %q", c)
+	}
+	p.out.WriteByte(c)
+	p.pos++
+	return nil
+}
+
+// 类签名是类型参数加上父类和接口, 方法签名有参数列表, 字段签名只有一个类型
+func (p *signatureParser) signature() error {
+	if p.peek() == '<' {
+		if err := p.typeParameters(); err != nil {
+			return err
+		}
+	}
+	if p.peek() == '(' {
+		return p.method()
+	}
+	if p.pos >= len(p.s) {
+		return p.errorf("missing type")
+	}
+	for p.pos < len(p.s) {
+		if err := p.javaType(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *signatureParser) method() error {
+	p.expect('(')
+	for p.peek() != ')' {
+		if p.pos >= len(p.s) {
+			return p.errorf("missing )")
+		}
+		if err := p.javaType(); err != nil {
+			return err
+		}
+	}
+	p.expect(')')
+	if err := p.javaType(); err != nil {
+		return err
+	}
+	for p.peek() == '^' {
+		p.expect('^')
+		if err := p.javaType(); err != nil {
+			return err
+		}
+	}
+	if p.pos < len(p.s) {
+		return p.errorf("unexpected %q", p.peek())
+	}
+	return nil
+}
+
+// <T:Ljava/lang/Object;U::Ljava/lang/Comparable<TU;>;>, 类的边界可以为空
+func (p *signatureParser) typeParameters() error {
+	p.expect('<')
+	for p.peek() != '>' {
+		if err := p.identifier(":"); err != nil {
+			return err
+		}
+		p.expect(':')
+		if c := p.peek(); c != ':' && c != '>' {
+			if err := p.javaType(); err != nil {
+				return err
+			}
+		}
+		for p.peek() == ':' {
+			p.expect(':')
+			if err := p.javaType(); err != nil {
+				return err
+			}
+		}
+	}
+	return p.expect('>')
+}
+
+func (p *signatureParser) identifier(stops string) error {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(stops, rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start || p.pos >= len(p.s) {
+		return p.errorf("invalid identifier")
+	}
+	p.out.WriteString(p.s[start:p.pos])
+	return nil
+}
+
+func (p *signatureParser) javaType() error {
+	switch c := p.peek(); c {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 'V':
+		return p.expect(c)
+	case '[':
+		p.expect('[')
+		return p.javaType()
+	case 'T':
+		p.expect('T')
+		if err := p.identifier(";"); err != nil {
+			return err
+		}
+		return p.expect(';')
+	case 'L':
+		return p.classType()
+	}
+	return p.errorf("unexpected %q", p.peek())
+}
+
+// Lcom/acme/Outer<TT;>.Inner<TU;>;, 内部类的名称是Outer$Inner
+func (p *signatureParser) classType() error {
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune("<.;", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start || p.pos >= len(p.s) {
+		return p.errorf("invalid class name")
+	}
+	name := p.s[start:p.pos]
+	mapped := p.class(name)
+	p.out.WriteString("L" + mapped)
+	for {
+		if p.peek() == '<' {
+			if err := p.typeArguments(); err != nil {
+				return err
+			}
+		}
+		if p.peek() != '.' {
+			break
+		}
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.s) && !strings.ContainsRune("<.;", rune(p.s[p.pos])) {
+			p.pos++
+		}
+		if p.pos == start || p.pos >= len(p.s) {
+			return p.errorf("invalid inner class name")
+		}
+		name += "$" + p.s[start:p.pos]
+		inner := p.class(name)
+		p.out.WriteString("." + simpleName(inner, mapped))
+		mapped = inner
+	}
+	return p.expect(';')
+}
+
+func (p *signatureParser) typeArguments() error {
+	p.expect('<')
+	for p.peek() != '>' {
+		switch p.peek() {
+		case '*':
+			p.expect('*')
+			continue
+		case '+', '-':
+			p.expect(p.peek())
+		}
+		if err := p.javaType(); err != nil {
+			return err
+		}
+	}
+	return p.expect('>')
+}
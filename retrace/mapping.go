@@ -0,0 +1,220 @@
+package retrace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// R8写在类和成员之后的元数据, 格式是# {"id":...}
+const (
+	METADATA_SOURCE_FILE        = "sourceFile"
+	METADATA_SYNTHESIZED        = "com.android.tools.r8.synthesized"
+	METADATA_RESIDUAL_SIGNATURE = "com.android.tools.r8.residualsignature"
+)
+
+// mapping.txt中的一个类, 类名都是Java的形式, 例如com.acme.Main$Inner
+type Class struct {
+	Original   string
+	Obfuscated string
+	SourceFile string
+	Fields     []Field
+	Methods    []Method
+}
+
+type Field struct {
+	Type       string
+	Original   string
+	Obfuscated string
+}
+
+// 方法的一行映射, 例如4:10:void run(int):20:26 -> a. 没有的行号为0, Original可以是内联进来的其他类的方法,
+// 这时带有类名. 混淆后的行号范围相同的连续几行是内联的栈, 最里层的在前
+type Method struct {
+	ObfuscatedStart   int
+	ObfuscatedEnd     int
+	ReturnType        string
+	Original          string
+	Parameters        []string
+	OriginalStart     int
+	OriginalEnd       int
+	Obfuscated        string
+	Synthesized       bool
+	ResidualSignature string
+}
+
+// 方法所在的类和方法名, Original不带类名时是owner
+func (m *Method) Owner(owner string) (string, string) {
+	if i := strings.LastIndex(m.Original, "."); i >= 0 {
+		return m.Original[:i], m.Original[i+1:]
+	}
+	return owner, m.Original
+}
+
+// 混淆后的第line行对应的原始行号, line为0或者没有行号时返回0
+func (m *Method) OriginalLine(line int) int {
+	switch {
+	case line <= 0:
+		return 0
+	case m.OriginalStart == 0:
+		// 没有原始的行号时与混淆后的相同
+		return line
+	case m.OriginalEnd == 0 || m.OriginalEnd-m.OriginalStart != m.ObfuscatedEnd-m.ObfuscatedStart:
+		// 调用处的行号或者整段映射到一行
+		return m.OriginalStart
+	}
+	return m.OriginalStart + line - m.ObfuscatedStart
+}
+
+func (m *Method) hasRange() bool {
+	return m.ObfuscatedStart > 0 || m.ObfuscatedEnd > 0
+}
+
+func (m *Method) contains(line int) bool {
+	return m.hasRange() && m.ObfuscatedStart <= line && line <= m.ObfuscatedEnd
+}
+
+type Mapping struct {
+	Classes    []*Class
+	obfuscated map[string]*Class
+	original   map[string]*Class
+}
+
+var (
+	classPattern  = regexp.MustCompile(`^(\S+)\s+->\s+(\S+):$`)
+	fieldPattern  = regexp.MustCompile(`^(\S+)\s+(\S+)\s+->\s+(\S+)$`)
+	methodPattern = regexp.MustCompile(`^(?:(\d+):(\d+):)?(\S+)\s+([^\s(]+)\(([^)]*)\)(?::(\d+)(?::(\d+))?)?\s+->\s+(\S+)$`)
+)
+
+// 解析ProGuard或者R8的mapping.txt
+func ParseMapping(r io.Reader) (*Mapping, error) {
+	m := &Mapping{Classes: make([]*Class, 0), obfuscated: make(map[string]*Class), original: make(map[string]*Class)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var class *Class
+	var method *Method
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := scanner.Text()
+		line := strings.TrimSpace(text)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			if class != nil {
+				metadata(class, method, strings.TrimSpace(line[1:]))
+			}
+		case text[0] != ' ' && text[0] != '\t':
+			match := classPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: invalid class mapping %q", number, line)
+			}
+			class, method = &Class{Original: match[1], Obfuscated: match[2]}, nil
+			m.Classes = append(m.Classes, class)
+			m.obfuscated[class.Obfuscated] = class
+			m.original[class.Original] = class
+		case class == nil:
+			return nil, fmt.Errorf("line %d: member mapping outside of a class", number)
+		case strings.Contains(line, "("):
+			match := methodPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: invalid method mapping %q", number, line)
+			}
+			parameters := make([]string, 0)
+			if match[5] != "" {
+				parameters = strings.Split(match[5], ",")
+			}
+			class.Methods = append(class.Methods, Method{ObfuscatedStart: atoi(match[1]), ObfuscatedEnd: atoi(match[2]),
+				ReturnType: match[3], Original: match[4], Parameters: parameters,
+				OriginalStart: atoi(match[6]), OriginalEnd: atoi(match[7]), Obfuscated: match[8]})
+			method = &class.Methods[len(class.Methods)-1]
+		default:
+			match := fieldPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: invalid field mapping %q", number, line)
+			}
+			class.Fields = append(class.Fields, Field{Type: match[1], Original: match[2], Obfuscated: match[3]})
+			method = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// 不认识的元数据和普通的注释忽略
+func metadata(class *Class, method *Method, text string) {
+	var value struct {
+		ID        string `json:"id"`
+		FileName  string `json:"fileName"`
+		Signature string `json:"signature"`
+	}
+	if !strings.HasPrefix(text, "{") || json.Unmarshal([]byte(text), &value) != nil {
+		return
+	}
+	switch {
+	case value.ID == METADATA_SOURCE_FILE && method == nil:
+		class.SourceFile = value.FileName
+	case value.ID == METADATA_SYNTHESIZED && method != nil:
+		method.Synthesized = true
+	case value.ID == METADATA_RESIDUAL_SIGNATURE && method != nil:
+		method.ResidualSignature = value.Signature
+	}
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// 混淆后的类名对应的类, 没有时返回nil
+func (m *Mapping) Class(obfuscated string) *Class {
+	return m.obfuscated[obfuscated]
+}
+
+// 混淆后的类名还原为原始的类名, 不在映射中时不变
+func (m *Mapping) OriginalName(obfuscated string) string {
+	if c := m.obfuscated[obfuscated]; c != nil {
+		return c.Original
+	}
+	return obfuscated
+}
+
+// 原始的类名对应的混淆后的类名, 不在映射中时不变
+func (m *Mapping) ObfuscatedName(original string) string {
+	if c := m.original[original]; c != nil {
+		return c.Obfuscated
+	}
+	return original
+}
+
+// 原始类型的方法签名对应的混淆后的描述符, 例如(com.acme.Foo,int)和void得到(La;I)V
+func (m *Mapping) ObfuscatedDescriptor(parameters []string, returnType string) string {
+	result := "("
+	for _, p := range parameters {
+		result += m.typeDescriptor(p)
+	}
+	return result + ")" + m.typeDescriptor(returnType)
+}
+
+var primitiveDescriptors = map[string]string{
+	"boolean": "Z", "byte": "B", "char": "C", "short": "S", "int": "I", "long": "J", "float": "F", "double": "D", "void": "V",
+}
+
+func (m *Mapping) typeDescriptor(t string) string {
+	t = strings.TrimSpace(t)
+	prefix := ""
+	for strings.HasSuffix(t, "[]") {
+		prefix += "["
+		t = strings.TrimSuffix(t, "[]")
+	}
+	if d, ok := primitiveDescriptors[t]; ok {
+		return prefix + d
+	}
+	return prefix + "L" + strings.ReplaceAll(m.ObfuscatedName(t), ".", "/") + ";"
+}
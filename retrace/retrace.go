@@ -0,0 +1,238 @@
+package retrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"class-file-parser/bytecode"
+)
+
+// 栈帧中不是文件名的位置
+const (
+	SOURCE_FILE    = "SourceFile"
+	UNKNOWN_SOURCE = "Unknown Source"
+	NATIVE_METHOD  = "Native Method"
+)
+
+// 栈中的一帧, 类名是Java的形式; Line为0时没有行号
+type Frame struct {
+	Class      string `json:"class"`
+	Method     string `json:"method"`
+	SourceFile string `json:"sourceFile,omitempty"`
+	Line       int    `json:"line,omitempty"`
+}
+
+// 例如com.acme.Main.run(Main.java:12)
+func (f Frame) String() string {
+	location := f.SourceFile
+	if location == "" {
+		location = UNKNOWN_SOURCE
+	}
+	if f.Line > 0 {
+		location += ":" + strconv.Itoa(f.Line)
+	}
+	return f.Class + "." + f.Method + "(" + location + ")"
+}
+
+type Retracer struct {
+	mapping *Mapping
+	classes map[string]*bytecode.ClassFile
+}
+
+// mapping为nil时只按SMAP换算行号
+func New(mapping *Mapping) *Retracer {
+	if mapping == nil {
+		mapping = &Mapping{Classes: make([]*Class, 0), obfuscated: make(map[string]*Class), original: make(map[string]*Class)}
+	}
+	return &Retracer{mapping: mapping, classes: make(map[string]*bytecode.ClassFile)}
+}
+
+// 添加混淆后的类文件, 映射有歧义时用其中的方法和LineNumberTable筛选, 没有映射的类按SMAP换算行号
+func (r *Retracer) AddClass(f *bytecode.ClassFile) {
+	if _, ok := r.classes[f.ClassName()]; !ok {
+		r.classes[f.ClassName()] = f
+	}
+}
+
+// 还原一帧, 返回所有可能的结果, 每个结果是内联展开后的栈, 最里层的在前.
+// 行号不在任何范围内或者没有行号时只能确定外层的方法
+func (r *Retracer) Retrace(frame Frame) [][]Frame {
+	f := r.classes[strings.ReplaceAll(frame.Class, ".", "/")]
+	class := r.mapping.Class(frame.Class)
+	if class == nil {
+		return [][]Frame{{r.translateSMAP(frame, f)}}
+	}
+	groups := make([][]Method, 0)
+	last := -1
+	for i, m := range class.Methods {
+		if m.Obfuscated != frame.Method {
+			continue
+		}
+		// 调用内联方法的外层只有调用处的一行, 范围相同但是有原始行号范围的是另一个重载
+		if n := len(groups); n > 0 && last == i-1 && m.hasRange() && groups[n-1][0].ObfuscatedStart == m.ObfuscatedStart &&
+			groups[n-1][0].ObfuscatedEnd == m.ObfuscatedEnd && m.OriginalStart > 0 && (m.OriginalEnd == 0 || m.OriginalEnd == m.OriginalStart) {
+			groups[n-1] = append(groups[n-1], m)
+		} else {
+			groups = append(groups, []Method{m})
+		}
+		last = i
+	}
+	if len(groups) == 0 {
+		return [][]Frame{{{Class: class.Original, Method: frame.Method, SourceFile: r.sourceFile(class.Original, class, frame), Line: frame.Line}}}
+	}
+
+	selected := make([][]Method, 0)
+	if frame.Line > 0 {
+		for _, g := range groups {
+			if g[0].contains(frame.Line) {
+				selected = append(selected, g)
+			}
+		}
+		if len(selected) == 0 {
+			for _, g := range groups {
+				if !g[0].hasRange() {
+					selected = append(selected, g)
+				}
+			}
+		}
+	}
+	if len(selected) == 0 {
+		// 只保留外层的方法, 同一个方法的多个范围合并
+		seen := make(map[string]bool)
+		for _, g := range groups {
+			outer := g[len(g)-1]
+			key := outer.Original + "(" + strings.Join(outer.Parameters, ",") + ")"
+			if !seen[key] {
+				seen[key] = true
+				selected = append(selected, []Method{outer})
+			}
+		}
+	}
+	if len(selected) > 1 && f != nil {
+		filtered := make([][]Method, 0)
+		for _, g := range selected {
+			if r.matches(f, g[len(g)-1], frame) {
+				filtered = append(filtered, g)
+			}
+		}
+		if len(filtered) > 0 {
+			selected = filtered
+		}
+	}
+
+	result := make([][]Frame, 0, len(selected))
+	seen := make(map[string]bool)
+	for _, g := range selected {
+		frames := make([]Frame, 0, len(g))
+		for i, m := range g {
+			// 内联栈中编译器合成的方法不显示
+			if m.Synthesized && i < len(g)-1 {
+				continue
+			}
+			owner, name := m.Owner(class.Original)
+			line := 0
+			if !m.hasRange() || m.contains(frame.Line) {
+				line = m.OriginalLine(frame.Line)
+			}
+			frames = append(frames, Frame{Class: owner, Method: name, SourceFile: r.sourceFile(owner, class, frame), Line: line})
+		}
+		key := ""
+		for _, frame := range frames {
+			key += frame.String() + "\n"
+		}
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, frames)
+		}
+	}
+	return result
+}
+
+// 混淆后的类中存在这个方法, 并且有行号时LineNumberTable中有这一行
+func (r *Retracer) matches(f *bytecode.ClassFile, m Method, frame Frame) bool {
+	descriptor := m.ResidualSignature
+	if descriptor == "" {
+		descriptor = r.mapping.ObfuscatedDescriptor(m.Parameters, m.ReturnType)
+	}
+	pool := f.ConstantPool
+	for i := range f.Methods {
+		method := &f.Methods[i]
+		if method.Name(pool) == frame.Method && method.Descriptor(pool) == descriptor {
+			return frame.Line <= 0 || len(method.PCsForLine(frame.Line)) > 0
+		}
+	}
+	return false
+}
+
+// R8记录的源文件名, 其次是栈中真实的文件名, 都没有时按最外层的类名推测
+func (r *Retracer) sourceFile(owner string, class *Class, frame Frame) string {
+	if frame.SourceFile == NATIVE_METHOD {
+		return frame.SourceFile
+	}
+	if c := r.mapping.original[owner]; c != nil && c.SourceFile != "" {
+		return c.SourceFile
+	}
+	switch frame.SourceFile {
+	case "", SOURCE_FILE, UNKNOWN_SOURCE:
+	default:
+		if owner == class.Original {
+			return frame.SourceFile
+		}
+	}
+	name := owner[strings.LastIndex(owner, ".")+1:]
+	if i := strings.Index(name, "$"); i > 0 {
+		name = name[:i]
+	}
+	return name + ".java"
+}
+
+// 没有混淆的类按默认的stratum换算行号, 例如Kotlin内联函数
+func (r *Retracer) translateSMAP(frame Frame, f *bytecode.ClassFile) Frame {
+	if f == nil || frame.Line <= 0 {
+		return frame
+	}
+	if smap, err := f.SMAP(); err == nil && smap != nil {
+		if position, ok := smap.Translate(frame.Line, ""); ok && position.File.Name != "" {
+			frame.SourceFile, frame.Line = position.File.Name, position.Line
+		}
+	}
+	return frame
+}
+
+var (
+	// 可以有模块或者类加载器的前缀, 例如java.base/和app//
+	framePattern     = regexp.MustCompile(`^(\s*at\s+)((?:[^\s(/]*/)*)([^\s(/]+)\.([^\s.(/]+)\(([^)]*)\)(.*)$`)
+	exceptionPattern = regexp.MustCompile(`^(\s*(?:Exception in thread "[^"]*" |Caused by: |Suppressed: )?)([\w$]+(?:\.[\w$]+)*)(:.*)?$`)
+)
+
+// 还原栈中的一行, 返回输出的行和栈帧的结果, 不是栈帧时frames为nil.
+// 有多个可能的结果时其余的以<OR>开头; 异常的类名也会还原
+func (r *Retracer) Line(line string) ([]string, [][]Frame) {
+	match := framePattern.FindStringSubmatch(line)
+	if match == nil {
+		if match := exceptionPattern.FindStringSubmatch(line); match != nil && r.mapping.Class(match[2]) != nil {
+			return []string{match[1] + r.mapping.OriginalName(match[2]) + match[3]}, nil
+		}
+		return []string{line}, nil
+	}
+	frame := Frame{Class: match[3], Method: match[4], SourceFile: match[5]}
+	if i := strings.LastIndex(match[5], ":"); i >= 0 {
+		if n, err := strconv.Atoi(match[5][i+1:]); err == nil {
+			frame.SourceFile, frame.Line = match[5][:i], n
+		}
+	}
+	results := r.Retrace(frame)
+	indent := match[1][:len(match[1])-len(strings.TrimLeft(match[1], " \t"))]
+	output := make([]string, 0)
+	for i, frames := range results {
+		for j, f := range frames {
+			prefix := match[1]
+			if i > 0 && j == 0 {
+				prefix = indent + "<OR> " + strings.TrimLeft(match[1], " \t")
+			}
+			output = append(output, prefix+match[2]+f.String()+match[6])
+		}
+	}
+	return output, results
+}
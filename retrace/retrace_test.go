@@ -0,0 +1,112 @@
+package retrace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// R8的mapping.txt: a.a的4和5行是内联的栈, 5行最里层是合成的方法; a.b的6-8行是两个重载;
+// a.e的两行范围相同但是不相邻, 不是内联的栈
+const R8_MAPPING = `# {"id":"com.android.tools.r8.mapping","version":"2.0"}
+com.acme.Main -> a:
+# {"id":"sourceFile","fileName":"Main.kt"}
+    int count -> a
+    1:3:void run():10:12 -> a
+    4:4:void com.acme.Util.check(int):40:40 -> a
+    4:4:void run():13 -> a
+    5:5:void helper():70:70 -> a
+    # {"id":"com.android.tools.r8.synthesized"}
+    5:5:void run():14 -> a
+    6:8:void first(int):20:22 -> b
+    6:8:void second(java.lang.String):30:32 -> b
+    void plain() -> c
+    1:2:void alpha():60:61 -> e
+    void other() -> f
+    1:2:void beta():62 -> e
+com.acme.Util -> b:
+`
+
+func newRetracer(t *testing.T) *Retracer {
+	t.Helper()
+	m, err := ParseMapping(strings.NewReader(R8_MAPPING))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(m)
+}
+
+func TestRetrace(t *testing.T) {
+	r := newRetracer(t)
+	main := func(method string, line int) Frame {
+		return Frame{Class: "com.acme.Main", Method: method, SourceFile: "Main.kt", Line: line}
+	}
+	tests := []struct {
+		name  string
+		frame Frame
+		want  [][]Frame
+	}{
+		{"range", Frame{Class: "a", Method: "a", SourceFile: SOURCE_FILE, Line: 2}, [][]Frame{{main("run", 11)}}},
+		{"inline", Frame{Class: "a", Method: "a", SourceFile: SOURCE_FILE, Line: 4},
+			[][]Frame{{{Class: "com.acme.Util", Method: "check", SourceFile: "Util.java", Line: 40}, main("run", 13)}}},
+		{"synthesized", Frame{Class: "a", Method: "a", SourceFile: SOURCE_FILE, Line: 5}, [][]Frame{{main("run", 14)}}},
+		{"outside every range", Frame{Class: "a", Method: "a", SourceFile: SOURCE_FILE, Line: 99}, [][]Frame{{main("run", 0)}}},
+		{"no line", Frame{Class: "a", Method: "a", SourceFile: UNKNOWN_SOURCE}, [][]Frame{{main("run", 0)}}},
+		{"overloads", Frame{Class: "a", Method: "b", SourceFile: SOURCE_FILE, Line: 7},
+			[][]Frame{{main("first", 21)}, {main("second", 31)}}},
+		{"overloads without line", Frame{Class: "a", Method: "b", SourceFile: SOURCE_FILE},
+			[][]Frame{{main("first", 0)}, {main("second", 0)}}},
+		{"no range", Frame{Class: "a", Method: "c", SourceFile: SOURCE_FILE, Line: 9}, [][]Frame{{main("plain", 9)}}},
+		{"same range not adjacent", Frame{Class: "a", Method: "e", SourceFile: SOURCE_FILE, Line: 2},
+			[][]Frame{{main("alpha", 61)}, {main("beta", 62)}}},
+		{"unknown method", Frame{Class: "a", Method: "z", SourceFile: SOURCE_FILE, Line: 3}, [][]Frame{{main("z", 3)}}},
+		{"unknown class", Frame{Class: "x", Method: "y", SourceFile: "X.java", Line: 3},
+			[][]Frame{{{Class: "x", Method: "y", SourceFile: "X.java", Line: 3}}}},
+	}
+	for _, test := range tests {
+		if got := r.Retrace(test.frame); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	r := newRetracer(t)
+	tests := map[string][]string{
+		"Caused by: a: boom": {"Caused by: com.acme.Main: boom"},
+		"\tat a.a(SourceFile:4)": {
+			"\tat com.acme.Util.check(Util.java:40)",
+			"\tat com.acme.Main.run(Main.kt:13)",
+		},
+		"\tat app//a.b(SourceFile:6)": {
+			"\tat app//com.acme.Main.first(Main.kt:20)",
+			"\t<OR> at app//com.acme.Main.second(Main.kt:30)",
+		},
+		"java.lang.IllegalStateException: a": {"java.lang.IllegalStateException: a"},
+	}
+	for line, want := range tests {
+		if got, _ := r.Line(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestOriginalLine(t *testing.T) {
+	tests := []struct {
+		method Method
+		line   int
+		want   int
+	}{
+		{Method{ObfuscatedStart: 1, ObfuscatedEnd: 3, OriginalStart: 10, OriginalEnd: 12}, 2, 11},
+		{Method{ObfuscatedStart: 1, ObfuscatedEnd: 3, OriginalStart: 10, OriginalEnd: 12}, 0, 0},
+		{Method{ObfuscatedStart: 4, ObfuscatedEnd: 4, OriginalStart: 13}, 4, 13},
+		{Method{ObfuscatedStart: 1, ObfuscatedEnd: 5, OriginalStart: 20, OriginalEnd: 20}, 3, 20},
+		{Method{ObfuscatedStart: 1, ObfuscatedEnd: 5}, 3, 3},
+		{Method{}, 7, 7},
+	}
+	for _, test := range tests {
+		if got := test.method.OriginalLine(test.line); got != test.want {
+			t.Errorf("%+v line %d: got %d, want %d", test.method, test.line, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package sarif
+
+import "strings"
+
+// SARIF 2.1.0, 代码审查工具和CI通用的静态分析结果格式, 只使用其中的一部分字段
+const (
+	VERSION = "2.1.0"
+	SCHEMA  = "https://json.schemastore.org/sarif-2.1.0.json"
+	TOOL    = "class-file-parser"
+)
+
+// 结果的级别
+const (
+	LEVEL_ERROR   = "error"
+	LEVEL_WARNING = "warning"
+	LEVEL_NOTE    = "note"
+)
+
+// 源码的路径相对于SRCROOT, 由使用方映射到仓库中的源码目录
+const SRCROOT = "SRCROOT"
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID               string   `json:"id"`
+	ShortDescription *Message `json:"shortDescription,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	RuleIndex int        `json:"ruleIndex"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []LogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type Region struct {
+	StartLine int `json:"startLine"`
+}
+
+type LogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind,omitempty"`
+}
+
+// 只有一个run的日志
+func New() *Log {
+	return &Log{Schema: SCHEMA, Version: VERSION, Runs: []Run{{
+		Tool:    Tool{Driver: Driver{Name: TOOL, Rules: make([]Rule, 0)}},
+		Results: make([]Result, 0),
+	}}}
+}
+
+// 添加一个结果, 规则按第一次出现的顺序记录在driver中
+func (l *Log) Add(ruleID, description, level, message string, locations ...Location) {
+	run := &l.Runs[0]
+	index := -1
+	for i, rule := range run.Tool.Driver.Rules {
+		if rule.ID == ruleID {
+			index = i
+		}
+	}
+	if index < 0 {
+		index = len(run.Tool.Driver.Rules)
+		rule := Rule{ID: ruleID}
+		if description != "" {
+			rule.ShortDescription = &Message{Text: description}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	run.Results = append(run.Results, Result{RuleID: ruleID, RuleIndex: index, Level: level, Message: Message{Text: message}, Locations: locations})
+}
+
+// 类的位置, sourcePath是源文件的路径, 例如com/acme/Main.java, 为空时使用类文件的路径; line为0时没有行号.
+// member是方法的名称和描述符或者字段的name:descriptor, 为空时逻辑位置是类
+func ClassLocation(className, sourcePath string, line int, member string) Location {
+	uri := sourcePath
+	if uri == "" {
+		uri = className + ".class"
+	}
+	physical := &PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: uri, URIBaseID: SRCROOT}}
+	if line > 0 {
+		physical.Region = &Region{StartLine: line}
+	}
+	logical := LogicalLocation{FullyQualifiedName: strings.ReplaceAll(className, "/", "."), Kind: "type"}
+	if member != "" {
+		kind := "member"
+		if strings.Contains(member, "(") {
+			kind = "function"
+		}
+		logical = LogicalLocation{FullyQualifiedName: logical.FullyQualifiedName + "." + member, Kind: kind}
+	}
+	return Location{PhysicalLocation: physical, LogicalLocations: []LogicalLocation{logical}}
+}
@@ -0,0 +1,167 @@
+package sniffer
+
+import (
+	"fmt"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/deps"
+)
+
+// 引用的种类
+const (
+	KIND_CLASS  = "class"
+	KIND_FIELD  = "field"
+	KIND_METHOD = "method"
+)
+
+// 签名中不存在的引用, Location是类文件中第一次使用的位置, 例如method run()V Code pc 3 invokevirtual
+type Problem struct {
+	Class      string `json:"class"`
+	Kind       string `json:"kind"`
+	Owner      string `json:"owner"`
+	Name       string `json:"name,omitempty"`
+	Descriptor string `json:"descriptor,omitempty"`
+	Location   string `json:"location"`
+}
+
+// 例如java/nio/ByteBuffer.flip()Ljava/nio/ByteBuffer;, 字段为java/lang/Foo.BAR:I
+func (p Problem) Reference() string {
+	switch p.Kind {
+	case KIND_FIELD:
+		return p.Owner + "." + p.Name + ":" + p.Descriptor
+	case KIND_METHOD:
+		return p.Owner + "." + p.Name + p.Descriptor
+	}
+	return p.Owner
+}
+
+// 检查类文件中的Class、Fieldref、Methodref和InterfaceMethodref是否存在于签名中
+type Checker struct {
+	signature *Signature
+	classpath map[string]*Class
+}
+
+func NewChecker(s *Signature) *Checker {
+	return &Checker{signature: s, classpath: make(map[string]*Class)}
+}
+
+// 被检查的类和类路径中的类, 对它们的引用不需要在签名中, 同名的类只保留第一个
+func (c *Checker) AddClasspath(f *bytecode.ClassFile) {
+	if c.classpath[f.ClassName()] == nil {
+		c.classpath[f.ClassName()] = classOf(f, false)
+	}
+}
+
+// 与运行时相同, 签名中的类优先
+func (c *Checker) lookup(name string) *Class {
+	if class := c.signature.Class(name); class != nil {
+		return class
+	}
+	return c.classpath[name]
+}
+
+// 找不到的类只在属于签名或者JDK的包时报告, 其他的类缺少的是类路径
+func (c *Checker) checkable(name string) bool {
+	return c.signature.HasPackage(packageOf(name)) || deps.Module(name) != ""
+}
+
+// 按JVMS 5.4.3.2到5.4.3.4在类和所有父类型中查找成员, 遇到未知的类时无法判断, 视为存在
+func (c *Checker) hasMember(owner, name, desc string, method bool) bool {
+	keys := []string{name + ":" + desc}
+	if method {
+		keys = []string{name + desc, name + POLYMORPHIC}
+	}
+	visited := make(map[string]bool)
+	queue := []string{owner}
+	for len(queue) > 0 {
+		class := c.lookup(queue[0])
+		visited[queue[0]] = true
+		queue = queue[1:]
+		if class == nil {
+			return true
+		}
+		for _, key := range keys {
+			if class.members[key] {
+				return true
+			}
+		}
+		for _, super := range append([]string{class.Super}, class.Interfaces...) {
+			if super != "" && !visited[super] {
+				visited[super] = true
+				queue = append(queue, super)
+			}
+		}
+	}
+	return false
+}
+
+// 检查一个类, 每个引用只报告一次; 所属的类不存在时只报告类
+func (c *Checker) Check(f *bytecode.ClassFile) []Problem {
+	pool := f.ConstantPool
+	locations := make(map[uint16]string)
+	f.WalkConstantPoolRefs(func(ref *bytecode.ConstantPoolRef) {
+		if _, ok := locations[*ref.Index]; !ok && ref.From == 0 {
+			locations[*ref.Index] = ref.Location
+		}
+	})
+	location := func(index int) string {
+		if l, ok := locations[uint16(index)]; ok {
+			return l
+		}
+		return fmt.Sprintf("constant #%d", index)
+	}
+
+	problems := make([]Problem, 0)
+	seen := make(map[string]bool)
+	add := func(p Problem) {
+		if !seen[p.Reference()] {
+			seen[p.Reference()] = true
+			p.Class = f.ClassName()
+			problems = append(problems, p)
+		}
+	}
+	missing := func(name string) bool {
+		return c.lookup(name) == nil && c.checkable(name)
+	}
+	// 先检查成员引用, 所属的类不存在时位置是成员第一次使用的地方
+	for i, item := range pool {
+		var classIndex, natIndex uint16
+		kind := KIND_METHOD
+		switch constant := item.(type) {
+		case *bytecode.ConstantFieldref:
+			classIndex, natIndex, kind = constant.ClassIndex, constant.NameAndTypeIndex, KIND_FIELD
+		case *bytecode.ConstantMethodref:
+			classIndex, natIndex = constant.ClassIndex, constant.NameAndTypeIndex
+		case *bytecode.ConstantInterfaceMethodref:
+			classIndex, natIndex = constant.ClassIndex, constant.NameAndTypeIndex
+		default:
+			continue
+		}
+		owner := bytecode.ClassNameAt(pool, classIndex)
+		nat, ok := bytecode.ConstantAt(pool, natIndex).(*bytecode.ConstantNameAndType)
+		if owner == "" || strings.HasPrefix(owner, "[") || !ok {
+			continue
+		}
+		name, desc := bytecode.Utf8At(pool, nat.NameIndex), bytecode.Utf8At(pool, nat.DescriptorIndex)
+		if missing(owner) {
+			add(Problem{Kind: KIND_CLASS, Owner: owner, Location: location(i)})
+		} else if !c.hasMember(owner, name, desc, kind == KIND_METHOD) {
+			add(Problem{Kind: kind, Owner: owner, Name: name, Descriptor: desc, Location: location(i)})
+		}
+	}
+	for i := range pool {
+		raw := bytecode.ClassNameAt(pool, uint16(i))
+		name := strings.TrimLeft(raw, "[")
+		if len(name) < len(raw) {
+			if !strings.HasPrefix(name, "L") || !strings.HasSuffix(name, ";") {
+				continue
+			}
+			name = name[1 : len(name)-1]
+		}
+		if name != "" && missing(name) {
+			add(Problem{Kind: KIND_CLASS, Owner: name, Location: location(i)})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,71 @@
+package sniffer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ct.sym中版本目录名里的字符, 7到9是数字, 10开始是A、B、C...
+func releaseChar(release int) (byte, error) {
+	switch {
+	case release >= 7 && release <= 9:
+		return byte('0' + release), nil
+	case release >= 10 && release < 36:
+		return byte('A' + release - 10), nil
+	}
+	return 0, fmt.Errorf("release %d is not in ct.sym", release)
+}
+
+// 读取JDK 9开始lib/ct.sym中某个版本的类, 这也是javac --release使用的API.
+// 条目为<版本目录>/[模块/]包/类.sig, 版本目录的名称包含所有类内容相同的版本, 例如789ABC/java.base/java/lang/Object.sig
+func ReadCtSym(path string, release int, visit func(name string, data []byte) error) error {
+	char, err := releaseChar(release)
+	if err != nil {
+		return err
+	}
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	found := false
+	for _, entry := range archive.File {
+		slash := strings.IndexByte(entry.Name, '/')
+		if slash < 0 || !strings.HasSuffix(entry.Name, ".sig") || strings.HasSuffix(entry.Name, "/module-info.sig") ||
+			!releaseDir(entry.Name[:slash], char) {
+			continue
+		}
+		found = true
+		data, err := readEntry(entry)
+		if err != nil {
+			return fmt.Errorf("%s: %s", entry.Name, err.Error())
+		}
+		if err := visit(entry.Name, data); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("release %d is not in %s", release, path)
+	}
+	return nil
+}
+
+func releaseDir(dir string, char byte) bool {
+	for i := 0; i < len(dir); i++ {
+		if !(dir[i] >= '0' && dir[i] <= '9' || dir[i] >= 'A' && dir[i] <= 'Z') {
+			return false
+		}
+	}
+	return strings.IndexByte(dir, char) >= 0
+}
+
+func readEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
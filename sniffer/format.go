@@ -0,0 +1,175 @@
+package sniffer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 签名文件的格式, 整数都是uvarint, 与注解索引的格式相同:
+//
+//	magic "CFPSIG" version u1
+//	strings count {length bytes}     字符串表, 0号为空字符串, 后面都使用字符串表中的序号
+//	classes count {name super interfaces count {string} fields count {string} methods count {string}}
+const (
+	MAGIC   = "CFPSIG"
+	VERSION = 1
+)
+
+// 单个计数的上限, 避免损坏的文件导致分配过多的内存
+const MAX_COUNT = 1 << 24
+
+type writer struct {
+	w       *bufio.Writer
+	buf     [binary.MaxVarintLen64]byte
+	indexes map[string]uint64
+}
+
+func (w *writer) uvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	w.w.Write(w.buf[:n])
+}
+
+func (w *writer) strings(values []string) {
+	w.uvarint(uint64(len(values)))
+	for _, s := range values {
+		w.uvarint(w.indexes[s])
+	}
+}
+
+// 类按名称排序, 相同的签名写出相同的文件
+func (s *Signature) Write(out io.Writer) error {
+	names := s.Classes()
+	table := []string{""}
+	w := &writer{w: bufio.NewWriter(out), indexes: map[string]uint64{"": 0}}
+	add := func(values ...string) {
+		for _, value := range values {
+			if _, ok := w.indexes[value]; !ok {
+				w.indexes[value] = uint64(len(table))
+				table = append(table, value)
+			}
+		}
+	}
+	for _, name := range names {
+		c := s.classes[name]
+		add(c.Name, c.Super)
+		add(c.Interfaces...)
+		add(c.Fields...)
+		add(c.Methods...)
+	}
+
+	w.w.WriteString(MAGIC)
+	w.w.WriteByte(VERSION)
+	w.uvarint(uint64(len(table)))
+	for _, value := range table {
+		w.uvarint(uint64(len(value)))
+		w.w.WriteString(value)
+	}
+	w.uvarint(uint64(len(names)))
+	for _, name := range names {
+		c := s.classes[name]
+		w.uvarint(w.indexes[c.Name])
+		w.uvarint(w.indexes[c.Super])
+		w.strings(c.Interfaces)
+		w.strings(c.Fields)
+		w.strings(c.Methods)
+	}
+	return w.w.Flush()
+}
+
+type reader struct {
+	r       *bufio.Reader
+	strings []string
+	err     error
+}
+
+// 出错后后续的读取都返回零值, 只保留第一个错误
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		r.err = unexpectedEOF(err)
+	}
+	return v
+}
+
+func (r *reader) count() int {
+	n := r.uvarint()
+	if n > MAX_COUNT && r.err == nil {
+		r.err = fmt.Errorf("count %d exceeds %d", n, MAX_COUNT)
+	}
+	if r.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) string() string {
+	i := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if i >= uint64(len(r.strings)) {
+		r.err = fmt.Errorf("string index %d out of range %d", i, len(r.strings))
+		return ""
+	}
+	return r.strings[i]
+}
+
+func (r *reader) list() []string {
+	result := make([]string, r.count())
+	for i := range result {
+		result[i] = r.string()
+	}
+	return result
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func Read(in io.Reader) (*Signature, error) {
+	r := &reader{r: bufio.NewReader(in)}
+	header := make([]byte, len(MAGIC)+1)
+	if _, err := io.ReadFull(r.r, header); err != nil || string(header[:len(MAGIC)]) != MAGIC {
+		return nil, fmt.Errorf("not a signature file")
+	}
+	if header[len(MAGIC)] != VERSION {
+		return nil, fmt.Errorf("unsupported signature version %d", header[len(MAGIC)])
+	}
+
+	r.strings = make([]string, r.count())
+	for i := range r.strings {
+		data := make([]byte, r.count())
+		if r.err == nil {
+			_, r.err = io.ReadFull(r.r, data)
+			r.err = unexpectedEOF(r.err)
+		}
+		r.strings[i] = string(data)
+	}
+	s := New()
+	classes := r.count()
+	for i := 0; i < classes && r.err == nil; i++ {
+		c := newClass(r.string(), r.string(), r.list())
+		for _, field := range r.list() {
+			c.members[field] = true
+			c.Fields = append(c.Fields, field)
+		}
+		for _, method := range r.list() {
+			c.members[method] = true
+			c.Methods = append(c.Methods, method)
+		}
+		s.add(c)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return s, nil
+}
@@ -0,0 +1,132 @@
+package sniffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// JDK 9开始lib/modules的jimage格式, 参见jdk.internal.jimage.BasicImageReader.
+// 头部7个u4之后依次是redirect表、location偏移表、location属性和字符串表, 资源的内容紧跟在后面
+const (
+	JIMAGE_MAGIC       = 0xCAFEDADA
+	JIMAGE_HEADER_SIZE = 7 * 4
+)
+
+// location属性的种类
+const (
+	attributeEnd = iota
+	attributeModule
+	attributeParent
+	attributeBase
+	attributeExtension
+	attributeOffset
+	attributeCompressed
+	attributeUncompressed
+	attributeCount
+)
+
+// 按照location的顺序读取jimage中所有模块的类文件, 名称例如/java.base/java/lang/Object.class.
+// jlink --compress压缩过的资源无法读取
+func ReadJImage(path string, visit func(name string, data []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	header := make([]byte, JIMAGE_HEADER_SIZE)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return fmt.Errorf("not a jimage file")
+	}
+	// 文件使用生成它的平台的字节序
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(header) != JIMAGE_MAGIC {
+		order = binary.BigEndian
+		if order.Uint32(header) != JIMAGE_MAGIC {
+			return fmt.Errorf("not a jimage file")
+		}
+	}
+	if major := order.Uint32(header[4:]) >> 16; major != 1 {
+		return fmt.Errorf("unsupported jimage version %d", major)
+	}
+	tableLength := int64(order.Uint32(header[16:]))
+	locationsSize := int64(order.Uint32(header[20:]))
+	stringsSize := int64(order.Uint32(header[24:]))
+	indexSize := JIMAGE_HEADER_SIZE + tableLength*8 + locationsSize + stringsSize
+	if tableLength > MAX_COUNT || locationsSize > 1<<30 || stringsSize > 1<<30 {
+		return fmt.Errorf("jimage index too large")
+	}
+	index := make([]byte, indexSize-JIMAGE_HEADER_SIZE)
+	if _, err := io.ReadFull(file, index); err != nil {
+		return fmt.Errorf("read jimage index error %s", err.Error())
+	}
+	offsets := index[tableLength*4 : tableLength*8]
+	locations := index[tableLength*8 : tableLength*8+locationsSize]
+	stringTable := index[tableLength*8+locationsSize:]
+	str := func(offset uint64) string {
+		if offset >= uint64(len(stringTable)) {
+			return ""
+		}
+		s := stringTable[offset:]
+		if end := bytes.IndexByte(s, 0); end >= 0 {
+			s = s[:end]
+		}
+		return string(s)
+	}
+
+	for i := int64(0); i < tableLength; i++ {
+		attributes, err := location(locations, order.Uint32(offsets[i*4:]))
+		if err != nil {
+			return err
+		}
+		module, extension := str(attributes[attributeModule]), str(attributes[attributeExtension])
+		if module == "" || extension != "class" {
+			continue
+		}
+		name := "/" + module + "/"
+		if parent := str(attributes[attributeParent]); parent != "" {
+			name += parent + "/"
+		}
+		name += str(attributes[attributeBase]) + "." + extension
+		if strings.HasSuffix(name, "/module-info.class") {
+			continue
+		}
+		if attributes[attributeCompressed] != 0 {
+			return fmt.Errorf("%s: compressed resources are not supported", name)
+		}
+		data := make([]byte, attributes[attributeUncompressed])
+		if _, err := file.ReadAt(data, indexSize+int64(attributes[attributeOffset])); err != nil {
+			return fmt.Errorf("%s: %s", name, err.Error())
+		}
+		if err := visit(name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 每个属性的第一个字节高5位是种类, 低3位是值的字节数减1, 值是大端序
+func location(locations []byte, offset uint32) ([attributeCount]uint64, error) {
+	var attributes [attributeCount]uint64
+	for i := int(offset); ; i++ {
+		if i >= len(locations) {
+			return attributes, fmt.Errorf("jimage location %d out of range", offset)
+		}
+		kind, length := locations[i]>>3, int(locations[i]&7)+1
+		if kind == attributeEnd {
+			return attributes, nil
+		}
+		if kind >= attributeCount || i+length >= len(locations) {
+			return attributes, fmt.Errorf("invalid jimage location %d", offset)
+		}
+		value := uint64(0)
+		for j := 0; j < length; j++ {
+			i++
+			value = value<<8 | uint64(locations[i])
+		}
+		attributes[kind] = value
+	}
+}
@@ -0,0 +1,125 @@
+package sniffer
+
+import (
+	"sort"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/flags"
+)
+
+// 签名多态方法的描述符, 调用处的描述符可以是任意的, 参见JVMS 2.9.3
+const POLYMORPHIC = "*"
+
+// 签名文件中的一个类, 类名是内部形式; 字段为name:descriptor, 方法为name+descriptor
+type Class struct {
+	Name       string
+	Super      string
+	Interfaces []string
+	Fields     []string
+	Methods    []string
+	members    map[string]bool
+}
+
+func newClass(name, super string, interfaces []string) *Class {
+	return &Class{Name: name, Super: super, Interfaces: interfaces, members: make(map[string]bool)}
+}
+
+func (c *Class) addField(name, desc string) {
+	key := name + ":" + desc
+	if !c.members[key] {
+		c.members[key] = true
+		c.Fields = append(c.Fields, key)
+	}
+}
+
+func (c *Class) addMethod(name, desc string) {
+	key := name + desc
+	if !c.members[key] {
+		c.members[key] = true
+		c.Methods = append(c.Methods, key)
+	}
+}
+
+// MethodHandle和VarHandle中native并且可变参数的方法
+func polymorphic(class string, method *bytecode.MethodInfo) bool {
+	return (class == "java/lang/invoke/MethodHandle" || class == "java/lang/invoke/VarHandle") &&
+		method.Flags()&flags.METHOD_NATIVE != 0 && method.Flags()&flags.METHOD_VARARGS != 0
+}
+
+// 类的结构, api为true时只包括public的类中public和protected的成员
+func classOf(f *bytecode.ClassFile, api bool) *Class {
+	pool := f.ConstantPool
+	c := newClass(f.ClassName(), f.SuperClassName(), f.InterfaceNames())
+	visible := func(value uint16) bool {
+		return !api || value&(flags.METHOD_PUBLIC.Value()|flags.METHOD_PROTECTED.Value()) != 0
+	}
+	for i := range f.Fields {
+		field := &f.Fields[i]
+		if visible(field.AccessFlags) {
+			c.addField(field.Name(pool), field.Descriptor(pool))
+		}
+	}
+	for i := range f.Methods {
+		method := &f.Methods[i]
+		if !visible(method.AccessFlags) {
+			continue
+		}
+		if polymorphic(c.Name, method) {
+			c.addMethod(method.Name(pool), POLYMORPHIC)
+		} else {
+			c.addMethod(method.Name(pool), method.Descriptor(pool))
+		}
+	}
+	return c
+}
+
+// 一个运行时的API签名, 例如某个版本的JDK, 类似animal-sniffer的签名文件
+type Signature struct {
+	classes  map[string]*Class
+	packages map[string]bool
+}
+
+func New() *Signature {
+	return &Signature{classes: make(map[string]*Class), packages: make(map[string]bool)}
+}
+
+// 添加public的类, 同名的类只保留第一个, 类不属于API或者已经添加过时返回false
+func (s *Signature) Add(f *bytecode.ClassFile) bool {
+	if f.Flags()&flags.CLASS_PUBLIC == 0 || f.AccessFlags&bytecode.ACC_MODULE != 0 || s.classes[f.ClassName()] != nil {
+		return false
+	}
+	s.add(classOf(f, true))
+	return true
+}
+
+func (s *Signature) add(c *Class) {
+	s.classes[c.Name] = c
+	s.packages[packageOf(c.Name)] = true
+}
+
+// 按名称排序
+func (s *Signature) Classes() []string {
+	result := make([]string, 0, len(s.classes))
+	for name := range s.classes {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (s *Signature) Class(name string) *Class {
+	return s.classes[name]
+}
+
+// 签名中是否有这个包中的类, pkg是内部形式
+func (s *Signature) HasPackage(pkg string) bool {
+	return s.packages[pkg]
+}
+
+func packageOf(className string) string {
+	if i := strings.LastIndex(className, "/"); i >= 0 {
+		return className[:i]
+	}
+	return ""
+}
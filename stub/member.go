@@ -0,0 +1,330 @@
+package stub
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/flags"
+)
+
+const METHOD_MODIFIERS = flags.METHOD_PUBLIC | flags.METHOD_PRIVATE | flags.METHOD_PROTECTED | flags.METHOD_STATIC |
+	flags.METHOD_FINAL | flags.METHOD_SYNCHRONIZED | flags.METHOD_NATIVE | flags.METHOD_ABSTRACT | flags.METHOD_STRICT
+
+func (g *generator) method(f *bytecode.ClassFile, method *bytecode.MethodInfo, kind string, inner *innerClass) {
+	pool := f.ConstantPool
+	methodFlags := method.Flags()
+	name := bytecode.Utf8At(pool, method.NameIndex)
+	desc := bytecode.Utf8At(pool, method.DescriptorIndex)
+	if methodFlags&(flags.METHOD_SYNTHETIC|flags.METHOD_BRIDGE) != 0 || name == "<clinit>" {
+		return
+	}
+	// 枚举的构造方法是私有的, 常量使用隐含的无参构造方法; values和valueOf由编译器生成
+	switch {
+	case kind == "enum" && (name == "<init>" || name == "values" && strings.HasPrefix(desc, "()") ||
+		name == "valueOf" && strings.HasPrefix(desc, "(Ljava/lang/String;)")):
+		return
+	case kind == "record" && name == "<init>" && desc == canonicalDescriptor(f):
+		return
+	}
+	erased, err := parseMethodSignature(desc, g.javaName)
+	if err != nil {
+		return
+	}
+
+	// 非静态成员类的构造方法的第一个参数是外部类的实例
+	skip := 0
+	if name == "<init>" && kind == "class" && inner != nil && inner.flags&flags.INNER_STATIC == 0 && len(erased.parameters) > 0 {
+		skip = 1
+	}
+	types := erased.parameters[skip:]
+	typeParameters, result, exceptions := "", erased.result, make([]string, 0)
+	for _, exception := range g.exceptions(pool, method.Attributes) {
+		exceptions = append(exceptions, g.javaName(exception))
+	}
+	// 签名中可能不包含编译器添加的参数, 按末尾对齐
+	if text := signatureOf(pool, method.Attributes); text != "" {
+		if sig, err := parseMethodSignature(text, g.javaName); err == nil && len(sig.parameters) <= len(erased.parameters) {
+			if len(erased.parameters)-len(sig.parameters) > skip {
+				skip = len(erased.parameters) - len(sig.parameters)
+			}
+			types = sig.parameters[len(sig.parameters)-(len(erased.parameters)-skip):]
+			typeParameters, result = sig.typeParameters, sig.result
+			if len(sig.exceptions) > 0 {
+				exceptions = sig.exceptions
+			}
+		}
+	}
+
+	descriptors, _, _ := bytecode.ParseMethodDescriptor(desc)
+	names := parameterNames(pool, method, descriptors)
+	parameterAnnotations := g.parameterAnnotations(pool, method.Attributes, len(descriptors))
+	parameters := make([]string, 0)
+	for i, parameterType := range types {
+		if i == len(types)-1 && methodFlags&flags.METHOD_VARARGS != 0 && strings.HasSuffix(parameterType, "[]") {
+			parameterType = strings.TrimSuffix(parameterType, "[]") + "..."
+		}
+		parameter := parameterType + " " + names[skip+i]
+		if annotations := parameterAnnotations[skip+i]; len(annotations) > 0 {
+			parameter = strings.Join(annotations, " ") + " " + parameter
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	// 接口方法隐含public, 没有方法体的隐含abstract
+	modifiers := methodFlags & METHOD_MODIFIERS
+	declaration := ""
+	switch kind {
+	case "enum":
+		modifiers &^= flags.METHOD_ABSTRACT
+	case "interface", "@interface":
+		if methodFlags&(flags.METHOD_ABSTRACT|flags.METHOD_STATIC|flags.METHOD_PRIVATE) == 0 {
+			declaration = "default "
+		}
+		modifiers &^= flags.METHOD_PUBLIC
+	}
+	declaration = prefix((modifiers &^ flags.METHOD_ABSTRACT).String()) + declaration
+	if kind != "interface" && kind != "@interface" && modifiers&flags.METHOD_ABSTRACT != 0 {
+		declaration = prefix(modifiers.String())
+	}
+	declaration += prefix(typeParameters)
+	if name == "<init>" {
+		declaration += simpleName(f, inner)
+	} else {
+		declaration += result + " " + name
+	}
+	declaration += "(" + strings.Join(parameters, ", ") + ")"
+	if len(exceptions) > 0 {
+		declaration += " throws " + strings.Join(exceptions, ", ")
+	}
+	if value, ok := findAttribute(method.Attributes, "AnnotationDefault").(*bytecode.AnnotationDefault); ok {
+		declaration += " default " + value.Resolve(pool).Java(g.javaName)
+	}
+
+	g.separate()
+	for _, annotation := range g.annotations(pool, method.Attributes) {
+		g.line("%s", annotation)
+	}
+	if modifiers&(flags.METHOD_ABSTRACT|flags.METHOD_NATIVE) != 0 {
+		g.line("%s;", declaration)
+		return
+	}
+	g.line("%s {", declaration)
+	g.indent++
+	if name == "<init>" {
+		if call := g.constructorCall(f, kind); call != "" {
+			g.line("%s;", call)
+		}
+	}
+	g.line("throw new UnsupportedOperationException();")
+	g.indent--
+	g.line("}")
+}
+
+func simpleName(f *bytecode.ClassFile, inner *innerClass) string {
+	if inner != nil {
+		return inner.name
+	}
+	name := f.ClassName()
+	return name[strings.LastIndexByte(name, '/')+1:]
+}
+
+func (g *generator) exceptions(pool []bytecode.ConstantPoolInfo, attrs []bytecode.AttributeInfo) []string {
+	result := make([]string, 0)
+	if exceptions, ok := findAttribute(attrs, "Exceptions").(*bytecode.Exceptions); ok {
+		for _, index := range exceptions.ExceptionIndexTable {
+			result = append(result, bytecode.ClassNameAt(pool, index))
+		}
+	}
+	return result
+}
+
+// 构造方法必须先调用其他构造方法: 记录类的非规范构造方法调用规范构造方法,
+// 父类没有无参构造方法时调用父类的第一个非私有构造方法
+func (g *generator) constructorCall(f *bytecode.ClassFile, kind string) string {
+	switch kind {
+	case "record":
+		params, _, _ := bytecode.ParseMethodDescriptor(canonicalDescriptor(f))
+		return "this(" + g.arguments(params) + ")"
+	case "class":
+		super := g.lookup(f.SuperClassName())
+		if super == nil {
+			return ""
+		}
+		g.addInnerClasses(super)
+		skip := 0
+		if info, ok := g.inner[super.ClassName()]; ok && info.outer != "" && info.name != "" && info.flags&flags.INNER_STATIC == 0 {
+			skip = 1
+		}
+		var candidate []string
+		for i := range super.Methods {
+			method := &super.Methods[i]
+			if method.Name(super.ConstantPool) != "<init>" || method.Flags()&(flags.METHOD_PRIVATE|flags.METHOD_SYNTHETIC) != 0 {
+				continue
+			}
+			params, _, err := bytecode.ParseMethodDescriptor(method.Descriptor(super.ConstantPool))
+			if err != nil || len(params) < skip {
+				continue
+			}
+			if len(params) == skip {
+				return ""
+			}
+			if candidate == nil {
+				candidate = params[skip:]
+			}
+		}
+		if candidate != nil {
+			return "super(" + g.arguments(candidate) + ")"
+		}
+	}
+	return ""
+}
+
+// 每个参数使用带类型转换的默认值, 避免重载时的歧义
+func (g *generator) arguments(descriptors []string) string {
+	args := make([]string, 0)
+	for _, desc := range descriptors {
+		switch desc {
+		case "Z":
+			args = append(args, "false")
+		case "B", "C", "S", "I", "J", "F", "D":
+			args = append(args, "("+g.fieldType(desc)+") 0")
+		default:
+			args = append(args, "("+g.fieldType(desc)+") null")
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// 参数名依次来自MethodParameters和LocalVariableTable, 都没有时使用argN
+func parameterNames(pool []bytecode.ConstantPoolInfo, method *bytecode.MethodInfo, descriptors []string) []string {
+	names := make([]string, len(descriptors))
+	if parameters, ok := findAttribute(method.Attributes, "MethodParameters").(*bytecode.MethodParameters); ok {
+		offset := len(descriptors) - len(parameters.Parameters)
+		for i, parameter := range parameters.Parameters {
+			if offset+i >= 0 && offset+i < len(names) {
+				names[offset+i] = bytecode.Utf8At(pool, parameter.NameIndex)
+			}
+		}
+	}
+	if code := method.Code(); code != nil {
+		if table, ok := findAttribute(code.Attributes, "LocalVariableTable").(*bytecode.LocalVariableTable); ok {
+			slot := uint16(1)
+			if method.Flags()&flags.METHOD_STATIC != 0 {
+				slot = 0
+			}
+			for i, desc := range descriptors {
+				for _, variable := range table.LocalVariable {
+					if names[i] == "" && variable.StartPc == 0 && variable.Index == slot {
+						names[i] = bytecode.Utf8At(pool, variable.NameIndex)
+					}
+				}
+				slot++
+				if desc == "J" || desc == "D" {
+					slot++
+				}
+			}
+		}
+	}
+	used := make(map[string]bool)
+	for i, name := range names {
+		if !isIdentifier(name) || used[name] {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		for used[name] {
+			name += "_"
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+var keywords = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`abstract assert boolean break byte case catch char class const
+		continue default do double else enum extends final finally float for goto if implements import
+		instanceof int interface long native new package private protected public return short static
+		strictfp super switch synchronized this throw throws transient try void volatile while
+		true false null _`) {
+		keywords[keyword] = true
+	}
+}
+
+func isIdentifier(name string) bool {
+	if name == "" || keywords[name] {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && r != '$' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *generator) annotations(pool []bytecode.ConstantPoolInfo, attrs []bytecode.AttributeInfo) []string {
+	result := make([]string, 0)
+	for _, annotation := range bytecode.AnnotationsOf(attrs, pool) {
+		result = append(result, annotation.Java(g.javaName))
+	}
+	return result
+}
+
+// 参数注解的个数可能少于描述符中的参数, 按末尾对齐
+func (g *generator) parameterAnnotations(pool []bytecode.ConstantPoolInfo, attrs []bytecode.AttributeInfo, count int) [][]string {
+	result := make([][]string, count)
+	for _, attr := range attrs {
+		var parameters [][]bytecode.AnnotationInfo
+		switch a := attr.(type) {
+		case *bytecode.RuntimeVisibleParameterAnnotations:
+			parameters = a.Resolve(pool)
+		case *bytecode.RuntimeInvisibleParameterAnnotations:
+			parameters = a.Resolve(pool)
+		}
+		offset := count - len(parameters)
+		for i, annotations := range parameters {
+			if offset+i < 0 || offset+i >= count {
+				continue
+			}
+			for _, annotation := range annotations {
+				result[offset+i] = append(result[offset+i], annotation.Java(g.javaName))
+			}
+		}
+	}
+	return result
+}
+
+// 字段的常量初始值, 字符串的tag为s
+func (g *generator) constant(pool []bytecode.ConstantPoolInfo, index uint16, desc string) string {
+	tag := byte('s')
+	if len(desc) == 1 {
+		tag = desc[0]
+	}
+	return bytecode.ResolveConstantValue(pool, index, tag).Java(g.javaName)
+}
+
+// final字段没有ConstantValue时使用非常量表达式初始化, 避免使用方把默认值内联为常量
+func nonConstant(desc string) string {
+	switch desc {
+	case "Z":
+		return "java.lang.Boolean.FALSE"
+	case "B":
+		return "java.lang.Byte.valueOf((byte) 0)"
+	case "C":
+		return "java.lang.Character.valueOf((char) 0)"
+	case "S":
+		return "java.lang.Short.valueOf((short) 0)"
+	case "I":
+		return "java.lang.Integer.valueOf(0)"
+	case "J":
+		return "java.lang.Long.valueOf(0L)"
+	case "F":
+		return "java.lang.Float.valueOf(0.0f)"
+	case "D":
+		return "java.lang.Double.valueOf(0.0d)"
+	}
+	return "null"
+}
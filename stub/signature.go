@@ -0,0 +1,254 @@
+package stub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 泛型签名和描述符的解析, 参见JVMS 4.7.9.1, 解析的结果直接是Java源码中的写法
+type signature struct {
+	text string
+	pos  int
+	name func(internal string) string //内部名称转换为源码中的名称
+}
+
+type signatureError struct {
+	text string
+	pos  int
+}
+
+func (e signatureError) Error() string {
+	return fmt.Sprintf("invalid signature %q at %d", e.text, e.pos)
+}
+
+func (s *signature) fail() {
+	panic(signatureError{s.text, s.pos})
+}
+
+func (s *signature) peek() byte {
+	if s.pos >= len(s.text) {
+		return 0
+	}
+	return s.text[s.pos]
+}
+
+func (s *signature) expect(c byte) {
+	if s.peek() != c {
+		s.fail()
+	}
+	s.pos++
+}
+
+// 读取到任意一个结束字符为止
+func (s *signature) identifier(stops string) string {
+	start := s.pos
+	for s.pos < len(s.text) && !strings.ContainsRune(stops, rune(s.text[s.pos])) {
+		s.pos++
+	}
+	if s.pos == start || s.pos == len(s.text) {
+		s.fail()
+	}
+	return s.text[start:s.pos]
+}
+
+var baseTypes = map[byte]string{
+	'B': "byte", 'C': "char", 'D': "double", 'F': "float",
+	'I': "int", 'J': "long", 'S': "short", 'Z': "boolean",
+}
+
+func (s *signature) javaType() string {
+	if name, ok := baseTypes[s.peek()]; ok {
+		s.pos++
+		return name
+	}
+	return s.referenceType()
+}
+
+func (s *signature) referenceType() string {
+	switch s.peek() {
+	case 'L':
+		return s.classType()
+	case 'T':
+		s.pos++
+		name := s.identifier(";")
+		s.pos++
+		return name
+	case '[':
+		s.pos++
+		return s.javaType() + "[]"
+	}
+	s.fail()
+	return ""
+}
+
+// 例如Ljava/util/Map<TK;TV;>.Entry; 后面的内部类只有简单名称
+func (s *signature) classType() string {
+	s.expect('L')
+	result := s.name(s.identifier("<.;"))
+	for {
+		if s.peek() == '<' {
+			result += s.typeArguments()
+		}
+		if s.peek() != '.' {
+			break
+		}
+		s.pos++
+		result += "." + s.identifier("<.;")
+	}
+	s.expect(';')
+	return result
+}
+
+func (s *signature) typeArguments() string {
+	s.expect('<')
+	args := make([]string, 0)
+	for s.peek() != '>' {
+		switch s.peek() {
+		case '*':
+			s.pos++
+			args = append(args, "?")
+		case '+':
+			s.pos++
+			args = append(args, "? extends "+s.referenceType())
+		case '-':
+			s.pos++
+			args = append(args, "? super "+s.referenceType())
+		default:
+			args = append(args, s.referenceType())
+		}
+	}
+	s.pos++
+	if len(args) == 0 {
+		s.fail()
+	}
+	return "<" + strings.Join(args, ", ") + ">"
+}
+
+// 类型参数的上界为Object时省略
+func (s *signature) typeParameters() string {
+	if s.peek() != '<' {
+		return ""
+	}
+	s.pos++
+	params := make([]string, 0)
+	for s.peek() != '>' {
+		name := s.identifier(":")
+		bounds := make([]string, 0)
+		for s.peek() == ':' {
+			s.pos++
+			if s.peek() == ':' || s.peek() == '>' {
+				continue
+			}
+			if bound := s.referenceType(); bound != "java.lang.Object" || len(bounds) > 0 {
+				bounds = append(bounds, bound)
+			}
+		}
+		if len(bounds) > 0 {
+			name += " extends " + strings.Join(bounds, " & ")
+		}
+		params = append(params, name)
+	}
+	s.pos++
+	if len(params) == 0 {
+		s.fail()
+	}
+	return "<" + strings.Join(params, ", ") + ">"
+}
+
+func (s *signature) end() {
+	if s.pos != len(s.text) {
+		s.fail()
+	}
+}
+
+func parseSignature(text string, name func(string) string, parse func(s *signature)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(signatureError); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	s := &signature{text: text, name: name}
+	parse(s)
+	s.end()
+	return nil
+}
+
+type classSignature struct {
+	typeParameters string
+	super          string
+	interfaces     []string
+}
+
+func parseClassSignature(text string, name func(string) string) (*classSignature, error) {
+	result := &classSignature{}
+	err := parseSignature(text, name, func(s *signature) {
+		result.typeParameters = s.typeParameters()
+		result.super = s.classType()
+		for s.peek() == 'L' {
+			result.interfaces = append(result.interfaces, s.classType())
+		}
+	})
+	return result, err
+}
+
+type methodSignature struct {
+	typeParameters string
+	parameters     []string
+	result         string
+	exceptions     []string
+}
+
+// 方法描述符也符合方法签名的语法
+func parseMethodSignature(text string, name func(string) string) (*methodSignature, error) {
+	result := &methodSignature{}
+	err := parseSignature(text, name, func(s *signature) {
+		result.typeParameters = s.typeParameters()
+		s.expect('(')
+		for s.peek() != ')' {
+			result.parameters = append(result.parameters, s.javaType())
+		}
+		s.pos++
+		if s.peek() == 'V' {
+			s.pos++
+			result.result = "void"
+		} else {
+			result.result = s.javaType()
+		}
+		for s.peek() == '^' {
+			s.pos++
+			result.exceptions = append(result.exceptions, s.referenceType())
+		}
+	})
+	return result, err
+}
+
+func parseFieldSignature(text string, name func(string) string) (string, error) {
+	result := ""
+	err := parseSignature(text, name, func(s *signature) {
+		result = s.javaType()
+	})
+	return result, err
+}
+
+// 签名中引用到的类, 内部形式的名称; 依次尝试字段签名、方法签名和类签名的语法
+func SignatureClasses(text string) ([]string, error) {
+	classes := make([]string, 0)
+	collect := func(internal string) string {
+		classes = append(classes, internal)
+		return internal
+	}
+	if _, err := parseFieldSignature(text, collect); err == nil {
+		return classes, nil
+	}
+	classes = classes[:0]
+	if _, err := parseMethodSignature(text, collect); err == nil {
+		return classes, nil
+	}
+	classes = classes[:0]
+	_, err := parseClassSignature(text, collect)
+	return classes, err
+}
@@ -0,0 +1,419 @@
+package stub
+
+import (
+	"fmt"
+	"strings"
+
+	"class-file-parser/bytecode"
+	"class-file-parser/flags"
+)
+
+// 按内部名称查找类文件, 用于生成嵌套类型和调用父类的构造方法, 找不到时返回nil
+type Lookup func(name string) *bytecode.ClassFile
+
+type innerClass struct {
+	outer string //局部类和匿名类为空
+	name  string //匿名类为空
+	flags flags.InnerClassFlags
+}
+
+type generator struct {
+	lookup Lookup
+	inner  map[string]innerClass //所有已处理的类的InnerClasses属性
+	out    strings.Builder
+	indent int
+}
+
+// 源文件相对于源码根目录的路径, 例如com/example/Foo.java
+func FileName(f *bytecode.ClassFile) string {
+	return f.ClassName() + ".java"
+}
+
+// 嵌套类型返回true, 成员类还返回外部类的名称, 局部类和匿名类的外部类为空
+func DeclaringClass(f *bytecode.ClassFile) (string, bool) {
+	g := &generator{inner: map[string]innerClass{}}
+	g.addInnerClasses(f)
+	info, ok := g.inner[f.ClassName()]
+	if !ok {
+		return "", false
+	}
+	if info.name == "" {
+		return "", true
+	}
+	return info.outer, true
+}
+
+// 生成可以编译的Java源码骨架, 成员类通过lookup查找, 方法体统一抛出UnsupportedOperationException
+func Generate(f *bytecode.ClassFile, lookup Lookup) (source string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", f.ClassName(), r)
+		}
+	}()
+	if f.Flags()&flags.CLASS_MODULE != 0 {
+		return "", fmt.Errorf("%s: module declaration is not supported", f.ClassName())
+	}
+	if lookup == nil {
+		lookup = func(string) *bytecode.ClassFile { return nil }
+	}
+	g := &generator{lookup: lookup, inner: map[string]innerClass{}}
+	g.addInnerClasses(f)
+	name := f.ClassName()
+	pkg := ""
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		pkg = strings.ReplaceAll(name[:i], "/", ".")
+	}
+	// package-info只有包上的注解
+	if name == "package-info" || strings.HasSuffix(name, "/package-info") {
+		for _, annotation := range g.annotations(f.ConstantPool, f.Attributes) {
+			g.line("%s", annotation)
+		}
+		g.line("package %s;", pkg)
+		return g.out.String(), nil
+	}
+	if pkg != "" {
+		g.line("package %s;", pkg)
+		g.line("")
+	}
+	g.class(f, nil)
+	return g.out.String(), nil
+}
+
+func (g *generator) line(format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	if text != "" {
+		g.out.WriteString(strings.Repeat("    ", g.indent))
+	}
+	g.out.WriteString(text + "\n")
+}
+
+// 成员之间空一行, 类型的第一个成员除外
+func (g *generator) separate() {
+	if !strings.HasSuffix(g.out.String(), "{\n") {
+		g.out.WriteString("\n")
+	}
+}
+
+func (g *generator) addInnerClasses(f *bytecode.ClassFile) {
+	pool := f.ConstantPool
+	for _, attr := range f.Attributes {
+		innerClasses, ok := attr.(*bytecode.InnerClasses)
+		if !ok {
+			continue
+		}
+		for _, c := range innerClasses.Classes {
+			name := bytecode.ClassNameAt(pool, c.InnerClassIndex)
+			if _, ok := g.inner[name]; ok || name == "" {
+				continue
+			}
+			info := innerClass{name: bytecode.Utf8At(pool, c.InnerNameIndex), flags: c.Flags()}
+			if c.OuterClassIndex != 0 {
+				info.outer = bytecode.ClassNameAt(pool, c.OuterClassIndex)
+			}
+			g.inner[name] = info
+		}
+	}
+}
+
+// 内部名称转换为源码中的名称, 成员类使用外部类加简单名称
+func (g *generator) javaName(internal string) string {
+	suffix := ""
+	for depth := 0; depth < 32; depth++ {
+		info, ok := g.inner[internal]
+		if !ok || info.outer == "" || info.name == "" {
+			break
+		}
+		suffix = "." + info.name + suffix
+		internal = info.outer
+	}
+	return strings.ReplaceAll(internal, "/", ".") + suffix
+}
+
+// 描述符转换为源码中的类型, 描述符非法时原样返回
+func (g *generator) fieldType(desc string) string {
+	result, err := parseFieldSignature(desc, g.javaName)
+	if err != nil {
+		return desc
+	}
+	return result
+}
+
+func signatureOf(pool []bytecode.ConstantPoolInfo, attrs []bytecode.AttributeInfo) string {
+	for _, attr := range attrs {
+		if s, ok := attr.(*bytecode.Signature); ok {
+			return bytecode.Utf8At(pool, s.SignatureIndex)
+		}
+	}
+	return ""
+}
+
+func findAttribute(attrs []bytecode.AttributeInfo, name string) bytecode.AttributeInfo {
+	for _, attr := range attrs {
+		if attr != nil && attr.GetName() == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+func declarationKind(f *bytecode.ClassFile) string {
+	classFlags := f.Flags()
+	switch {
+	case classFlags&flags.CLASS_ANNOTATION != 0:
+		return "@interface"
+	case classFlags&flags.CLASS_INTERFACE != 0:
+		return "interface"
+	case classFlags&flags.CLASS_ENUM != 0:
+		return "enum"
+	case f.SuperClassName() == "java/lang/Record" && findAttribute(f.Attributes, "Record") != nil:
+		return "record"
+	}
+	return "class"
+}
+
+// 类的修饰符, 成员类使用InnerClasses中的标志
+func (g *generator) classModifiers(f *bytecode.ClassFile, kind string, inner *innerClass) string {
+	modifiers := ""
+	if inner != nil {
+		innerFlags := inner.flags & (flags.INNER_PUBLIC | flags.INNER_PRIVATE | flags.INNER_PROTECTED |
+			flags.INNER_STATIC | flags.INNER_FINAL | flags.INNER_ABSTRACT | flags.INNER_INTERFACE)
+		switch kind {
+		case "enum":
+			innerFlags &^= flags.INNER_FINAL | flags.INNER_ABSTRACT
+		case "record":
+			innerFlags &^= flags.INNER_FINAL
+		}
+		modifiers = innerFlags.String()
+	} else {
+		classFlags := f.Flags() & (flags.CLASS_PUBLIC | flags.CLASS_FINAL | flags.CLASS_ABSTRACT | flags.CLASS_INTERFACE)
+		switch kind {
+		case "enum":
+			classFlags &^= flags.CLASS_FINAL | flags.CLASS_ABSTRACT
+		case "record":
+			classFlags &^= flags.CLASS_FINAL
+		}
+		modifiers = classFlags.String()
+	}
+	if kind != "class" && kind != "interface" {
+		return modifiers
+	}
+	if findAttribute(f.Attributes, "PermittedSubclasses") != nil {
+		return strings.TrimSpace(modifiers + " sealed")
+	}
+	if f.Flags()&flags.CLASS_FINAL == 0 && g.extendsSealed(f) {
+		return strings.TrimSpace(modifiers + " non-sealed")
+	}
+	return modifiers
+}
+
+// 直接父类型是sealed并且允许当前类时, 当前类必须声明为final、sealed或者non-sealed
+func (g *generator) extendsSealed(f *bytecode.ClassFile) bool {
+	for _, name := range append([]string{f.SuperClassName()}, f.InterfaceNames()...) {
+		super := g.lookup(name)
+		if super == nil {
+			continue
+		}
+		if permitted, ok := findAttribute(super.Attributes, "PermittedSubclasses").(*bytecode.PermittedSubclasses); ok {
+			for _, index := range permitted.Classes {
+				if bytecode.ClassNameAt(super.ConstantPool, index) == f.ClassName() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (g *generator) class(f *bytecode.ClassFile, inner *innerClass) {
+	g.addInnerClasses(f)
+	pool := f.ConstantPool
+	kind := declarationKind(f)
+	name := f.ClassName()
+	if inner != nil {
+		name = inner.name
+	} else if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	super := g.javaName(f.SuperClassName())
+	interfaces := make([]string, 0)
+	for _, i := range f.InterfaceNames() {
+		interfaces = append(interfaces, g.javaName(i))
+	}
+	typeParameters := ""
+	if text := signatureOf(pool, f.Attributes); text != "" {
+		if sig, err := parseClassSignature(text, g.javaName); err == nil {
+			typeParameters, super, interfaces = sig.typeParameters, sig.super, sig.interfaces
+		}
+	}
+
+	header := prefix(g.classModifiers(f, kind, inner)) + kind + " " + name + typeParameters
+	if kind == "record" {
+		header += "(" + strings.Join(g.recordComponents(f), ", ") + ")"
+	}
+	switch kind {
+	case "class":
+		if super != "" && super != "java.lang.Object" {
+			header += " extends " + super
+		}
+		fallthrough
+	case "enum", "record":
+		if len(interfaces) > 0 {
+			header += " implements " + strings.Join(interfaces, ", ")
+		}
+	case "interface":
+		if len(interfaces) > 0 {
+			header += " extends " + strings.Join(interfaces, ", ")
+		}
+	}
+	if permitted, ok := findAttribute(f.Attributes, "PermittedSubclasses").(*bytecode.PermittedSubclasses); ok && kind != "enum" {
+		names := make([]string, 0)
+		for _, index := range permitted.Classes {
+			names = append(names, g.javaName(bytecode.ClassNameAt(pool, index)))
+		}
+		header += " permits " + strings.Join(names, ", ")
+	}
+
+	for _, annotation := range g.annotations(pool, f.Attributes) {
+		g.line("%s", annotation)
+	}
+	g.line("%s {", header)
+	g.indent++
+	if kind == "enum" {
+		g.enumConstants(f)
+	}
+	for i := range f.Fields {
+		g.field(f, &f.Fields[i], kind)
+	}
+	for i := range f.Methods {
+		g.method(f, &f.Methods[i], kind, inner)
+	}
+	g.nestedClasses(f)
+	g.indent--
+	g.line("}")
+}
+
+func prefix(modifiers string) string {
+	if modifiers == "" {
+		return ""
+	}
+	return modifiers + " "
+}
+
+func (g *generator) recordComponents(f *bytecode.ClassFile) []string {
+	pool := f.ConstantPool
+	components := make([]string, 0)
+	record, _ := findAttribute(f.Attributes, "Record").(*bytecode.Record)
+	if record == nil {
+		return components
+	}
+	for _, c := range record.RecordComponentInfo {
+		componentType := g.fieldType(bytecode.Utf8At(pool, c.DescriptorIndex))
+		if text := signatureOf(pool, c.Attributes); text != "" {
+			if sig, err := parseFieldSignature(text, g.javaName); err == nil {
+				componentType = sig
+			}
+		}
+		component := componentType + " " + bytecode.Utf8At(pool, c.NameIndex)
+		if annotations := g.annotations(pool, c.Attributes); len(annotations) > 0 {
+			component = strings.Join(annotations, " ") + " " + component
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// 规范构造方法的描述符, 由所有组件的描述符组成
+func canonicalDescriptor(f *bytecode.ClassFile) string {
+	desc := "("
+	if record, ok := findAttribute(f.Attributes, "Record").(*bytecode.Record); ok {
+		for _, c := range record.RecordComponentInfo {
+			desc += bytecode.Utf8At(f.ConstantPool, c.DescriptorIndex)
+		}
+	}
+	return desc + ")V"
+}
+
+func (g *generator) enumConstants(f *bytecode.ClassFile) {
+	pool := f.ConstantPool
+	constants := make([]string, 0)
+	for i := range f.Fields {
+		field := &f.Fields[i]
+		if field.Flags()&flags.FIELD_ENUM == 0 {
+			continue
+		}
+		constants = append(constants, strings.Join(append(g.annotations(pool, field.Attributes), bytecode.Utf8At(pool, field.NameIndex)), " "))
+	}
+	if len(constants) == 0 {
+		g.line(";")
+	}
+	for i, constant := range constants {
+		if i < len(constants)-1 {
+			g.line("%s,", constant)
+		} else {
+			g.line("%s;", constant)
+		}
+	}
+}
+
+func (g *generator) field(f *bytecode.ClassFile, field *bytecode.FieldInfo, kind string) {
+	pool := f.ConstantPool
+	fieldFlags := field.Flags()
+	if fieldFlags&(flags.FIELD_SYNTHETIC|flags.FIELD_ENUM) != 0 {
+		return
+	}
+	// 记录类的实例字段由组件声明
+	if kind == "record" && fieldFlags&flags.FIELD_STATIC == 0 {
+		return
+	}
+	desc := bytecode.Utf8At(pool, field.DescriptorIndex)
+	fieldType := g.fieldType(desc)
+	if text := signatureOf(pool, field.Attributes); text != "" {
+		if sig, err := parseFieldSignature(text, g.javaName); err == nil {
+			fieldType = sig
+		}
+	}
+	modifiers := fieldFlags & (flags.FIELD_PUBLIC | flags.FIELD_PRIVATE | flags.FIELD_PROTECTED |
+		flags.FIELD_STATIC | flags.FIELD_FINAL | flags.FIELD_VOLATILE | flags.FIELD_TRANSIENT)
+	// 接口的字段隐含public static final
+	if kind == "interface" || kind == "@interface" {
+		modifiers &^= flags.FIELD_PUBLIC | flags.FIELD_STATIC | flags.FIELD_FINAL
+	}
+	declaration := prefix(modifiers.String())
+	declaration += fieldType + " " + bytecode.Utf8At(pool, field.NameIndex)
+	if value, ok := findAttribute(field.Attributes, "ConstantValue").(*bytecode.ConstantValue); ok {
+		declaration += " = " + g.constant(pool, value.ConstantValueIndex, desc)
+	} else if fieldFlags&flags.FIELD_FINAL != 0 || kind == "interface" || kind == "@interface" {
+		declaration += " = " + nonConstant(desc)
+	}
+	g.separate()
+	for _, annotation := range g.annotations(pool, field.Attributes) {
+		g.line("%s", annotation)
+	}
+	g.line("%s;", declaration)
+}
+
+func (g *generator) nestedClasses(f *bytecode.ClassFile) {
+	pool := f.ConstantPool
+	for _, attr := range f.Attributes {
+		innerClasses, ok := attr.(*bytecode.InnerClasses)
+		if !ok {
+			continue
+		}
+		for _, c := range innerClasses.Classes {
+			if c.OuterClassIndex == 0 || c.InnerNameIndex == 0 || c.Flags()&flags.INNER_SYNTHETIC != 0 ||
+				bytecode.ClassNameAt(pool, c.OuterClassIndex) != f.ClassName() {
+				continue
+			}
+			name := bytecode.ClassNameAt(pool, c.InnerClassIndex)
+			g.separate()
+			nested := g.lookup(name)
+			if nested == nil {
+				g.line("// %s: class file not found", name)
+				continue
+			}
+			info := g.inner[name]
+			g.class(nested, &info)
+		}
+	}
+}